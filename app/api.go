@@ -0,0 +1,58 @@
+package app
+
+import "github.com/elnerribeiro/go-ws-db-auth-v2/controllers"
+
+// apiRoutes the route table of the API, with the policy of every route
+func apiRoutes(h *controllers.Handlers) []Route {
+	can := HasPermission
+	return []Route{
+		{Method: "POST", Path: "/api/users", Handler: h.ListUsers, Policy: Admin(can("users:read"))},
+		{Method: "GET", Path: "/api/user/{id:[0-9]+}", Handler: h.GetUserByID, Policy: Authenticated(AnyOf(AllOf(can("users:read"), NotImpersonated()), IsOwner("id")))},
+		{Method: "PUT", Path: "/api/user", Handler: h.Upsert, Policy: Admin(can("users:write"))},
+		{Method: "DELETE", Path: "/api/user/{id:[0-9]+}", Handler: h.Delete, Policy: Admin(can("users:write"))},
+		{Method: "POST", Path: "/api/user/{id:[0-9]+}/unlock", Handler: h.Unlock, Policy: Admin(can("users:write"))},
+		{Method: "POST", Path: "/api/user/{id:[0-9]+}/disable", Handler: h.Disable, Policy: Admin(can("users:write"))},
+		{Method: "POST", Path: "/api/user/{id:[0-9]+}/enable", Handler: h.Enable, Policy: Admin(can("users:write"))},
+		{Method: "POST", Path: "/api/user/{id:[0-9]+}/impersonate", Handler: h.Impersonate, Policy: Admin(can("users:impersonate"))},
+		{Method: "GET", Path: "/api/user/{id:[0-9]+}/roles", Handler: h.GetUserRoles, Policy: Admin(can("roles:read"))},
+		{Method: "PUT", Path: "/api/user/{id:[0-9]+}/roles", Handler: h.SetUserRoles, Policy: Admin(can("roles:write"))},
+		{Method: "GET", Path: "/api/roles", Handler: h.ListRoles, Policy: Admin(can("roles:read"))},
+		{Method: "PUT", Path: "/api/role", Handler: h.UpsertRole, Policy: Admin(can("roles:write"), InDefaultTenant())},
+		{Method: "DELETE", Path: "/api/role/{id:[0-9]+}", Handler: h.DeleteRole, Policy: Admin(can("roles:write"), InDefaultTenant())},
+		{Method: "GET", Path: "/api/permissions", Handler: h.ListPermissions, Policy: Admin(can("roles:read"))},
+		{Method: "PUT", Path: "/api/permission", Handler: h.UpsertPermission, Policy: Admin(can("roles:write"), InDefaultTenant())},
+		{Method: "GET", Path: "/api/tenants", Handler: h.ListTenants, Policy: Admin(can("tenants:read"), InDefaultTenant())},
+		{Method: "PUT", Path: "/api/tenant", Handler: h.UpsertTenant, Policy: Admin(can("tenants:write"), InDefaultTenant())},
+		{Method: "POST", Path: "/api/login", Handler: h.Authenticate, Policy: Public()},
+		{Method: "POST", Path: "/api/login/mfa", Handler: h.LoginMfa, Policy: Public()},
+		{Method: "POST", Path: "/api/register", Handler: h.Register, Policy: Public()},
+		{Method: "POST", Path: "/api/register/activate", Handler: h.Activate, Policy: Public()},
+		{Method: "POST", Path: "/api/password/forgot", Handler: h.ForgotPassword, Policy: Public()},
+		{Method: "POST", Path: "/api/password/reset", Handler: h.ResetPassword, Policy: Public()},
+		{Method: "GET", Path: "/api/me", Handler: h.GetMe, Policy: Authenticated()},
+		{Method: "PATCH", Path: "/api/me", Handler: h.UpdateMe, Policy: Authenticated()},
+		{Method: "POST", Path: "/api/me/password", Handler: h.ChangeMyPassword, Policy: Authenticated(NotImpersonated())},
+		{Method: "POST", Path: "/api/me/email", Handler: h.ChangeMyEmail, Policy: Authenticated(NotImpersonated())},
+		{Method: "POST", Path: "/api/me/email/verify", Handler: h.VerifyMyEmail, Policy: Public()},
+		{Method: "POST", Path: "/api/mfa/totp", Handler: h.EnrollTotp, Policy: Authenticated(NotImpersonated())},
+		{Method: "POST", Path: "/api/mfa/totp/confirm", Handler: h.ConfirmTotp, Policy: Authenticated(NotImpersonated())},
+		{Method: "DELETE", Path: "/api/mfa/totp", Handler: h.DisableTotp, Policy: Authenticated(NotImpersonated())},
+		{Method: "POST", Path: "/api/apikeys", Handler: h.CreateApiKey, Policy: Authenticated(UserSession(), NotImpersonated())},
+		{Method: "GET", Path: "/api/apikeys", Handler: h.ListApiKeys, Policy: Authenticated()},
+		{Method: "DELETE", Path: "/api/apikey/{id:[0-9]+}", Handler: h.RevokeApiKey, Policy: Authenticated()},
+		{Method: "GET", Path: "/api/audit", Handler: h.ListAudit, Policy: Admin(can("audit:read"))},
+		{Method: "GET", Path: "/api/oauth/clients", Handler: h.ListOAuthClients, Policy: Admin(can("clients:read"))},
+		{Method: "PUT", Path: "/api/oauth/client", Handler: h.CreateOAuthClient, Policy: Admin(can("clients:write"))},
+		{Method: "DELETE", Path: "/api/oauth/client/{id:[0-9]+}", Handler: h.DeleteOAuthClient, Policy: Admin(can("clients:write"))},
+		{Method: "POST", Path: "/oauth/token", Handler: h.OAuthToken, Policy: Public()},
+		{Method: "POST", Path: "/oauth/introspect", Handler: h.OAuthIntrospect, Policy: Admin(can("tokens:introspect"))},
+		{Method: "POST", Path: "/api/token/refresh", Handler: h.RefreshToken, Policy: Public()},
+		{Method: "POST", Path: "/api/logout", Handler: h.Logout, Policy: Authenticated()},
+		{Method: "GET", Path: "/.well-known/jwks.json", Handler: h.JWKS, Policy: Public()},
+		{Method: "GET", Path: "/api/validate", Handler: h.Validate, Policy: Authenticated()},
+		{Method: "GET", Path: "/api/insert/{id:[0-9]+}", Handler: h.ListInsert, Policy: Authenticated(can("batches:read"))},
+		{Method: "PUT", Path: "/api/insert/sync/{qty:[0-9]+}", Handler: h.InsertSync, Policy: Authenticated(can("batches:write"))},
+		{Method: "PUT", Path: "/api/insert/async/{qty:[0-9]+}", Handler: h.InsertASync, Policy: Authenticated(can("batches:write"))},
+		{Method: "DELETE", Path: "/api/insert", Handler: h.ClearInserts, Policy: Admin(can("batches:clear"))},
+	}
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/config"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/controllers"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/keys"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/mail"
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+// App the whole application: database pool, services, router and HTTP server, built by New
+type App struct {
+	cfg      *config.Config
+	db       *db.DB
+	services *services.Services
+	server   *http.Server
+}
+
+// New Builds the application in order: log level, database pool, keys, mailer, stores, services, handlers and router.
+// Nothing is served until Start
+func New(cfg *config.Config) (*App, error) {
+	zerolog.SetGlobalLevel(cfg.Log.Level)
+
+	database, err := db.Open(cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+	ring, err := keys.Load(cfg.JWT)
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	stores := repo.NewPostgresStores(database)
+	if cfg.Login.ThrottleStore == "memory" {
+		stores.LoginAttempts = repo.NewMemoryLoginAttemptStore()
+	}
+	svc := services.New(ring, mail.New(cfg.Mail), stores, cfg)
+
+	return &App{
+		cfg:      cfg,
+		db:       database,
+		services: svc,
+		server: &http.Server{
+			Addr:    ":" + strconv.Itoa(cfg.Server.Port),
+			Handler: newHandler(cfg, svc, ring),
+		},
+	}, nil
+}
+
+// newHandler Builds the handlers and the router of the services, with request ids and CORS
+func newHandler(cfg *config.Config, svc *services.Services, ring *keys.KeyRing) http.Handler {
+	handlers := controllers.New(svc, ring)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, JwtAuthentication(svc, ring), apiRoutes(handlers))
+	router.Use(RequestID) //every request gets an id, for logs and the audit log
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger, _ := u.GetLoggerAndContext()
+		logger.Error().Msgf("[NotFoundHandler] Resource not found: %s", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	return CORS(cfg.CORS, router)
+}
+
+// Start Listens on the server port and serves requests in background, failing if the port can't be used
+func (a *App) Start() error {
+	logger, _ := u.GetLoggerAndContext()
+	listener, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Start] Cannot listen on %s: %s", a.server.Addr, err)
+		return err
+	}
+	go func() {
+		if err := a.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error().Err(err).Msgf("[Start] Error while executing server: %s", err)
+		}
+	}()
+	logger.Info().Msgf("[Start] Server started on port %d", a.cfg.Server.Port)
+	return nil
+}
+
+// Stop Stops accepting requests, waits up to server.shutdown.timeout for the running ones, cancels the work running
+// in background and closes the database pool
+func (a *App) Stop() error {
+	logger, _ := u.GetLoggerAndContext()
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Server.ShutdownTimeout)
+	defer cancel()
+	err := a.server.Shutdown(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Stop] Server Shutdown Failed:%+v", err)
+	}
+	a.services.Stop()
+	a.db.Close()
+	logger.Info().Msg("[Stop] Server Exited Properly")
+	return err
+}
@@ -0,0 +1,265 @@
+package app
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/config"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/keys"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/mail"
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// response body of every route: status, message and the data of the route
+type response struct {
+	Status  bool            `json:"status"`
+	Message string          `json:"message"`
+	Account *repo.User      `json:"account"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// testServer serves the API on memory stores, with an admin allowed to manage users, roles, tenants and OAuth clients
+// but not to read the audit log, granted by the auditor role
+func testServer(t *testing.T) (*httptest.Server, repo.Stores, *mail.MemoryMailer) {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingKey := filepath.Join(t.TempDir(), "signing.pem")
+	if err := os.WriteFile(signingKey, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ring, err := keys.Load(config.JWT{SigningKey: signingKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := repo.NewMemoryStores()
+	_, logContext := u.GetLoggerAndContext()
+	tx, _ := stores.Transactor.Begin(logContext)
+	permissions := []string{"users:read", "users:write", "roles:read", "roles:write", "tenants:read", "tenants:write", "clients:read", "clients:write"}
+	for _, name := range append(permissions, "audit:read") {
+		if _, err := stores.RBAC.UpsertPermission(logContext, tx, &repo.Permission{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := stores.RBAC.UpsertRole(logContext, tx, &repo.Role{Name: "admin", Permissions: permissions}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stores.RBAC.UpsertRole(logContext, tx, &repo.Role{Name: "auditor", Permissions: []string{"audit:read"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stores.Users.Upsert(logContext, tx, &repo.User{Email: "admin@example.com", Name: "Admin", Password: "secret", Role: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit(logContext)
+
+	cfg := &config.Config{}
+	mailer := &mail.MemoryMailer{}
+	svc := services.New(ring, mailer, stores, cfg)
+	server := httptest.NewServer(newHandler(cfg, svc, ring))
+	t.Cleanup(func() {
+		server.Close()
+		svc.Stop()
+	})
+	return server, stores, mailer
+}
+
+// call sends body as JSON with the Authorization header, failing the test unless the route answers with status true
+func call(t *testing.T, server *httptest.Server, method string, path string, authorization string, body any) *response {
+	t.Helper()
+	code, resp := send(t, server, method, path, authorization, body)
+	if code != http.StatusOK || !resp.Status {
+		t.Fatalf("%s %s: %d, %s", method, path, code, resp.Message)
+	}
+	return resp
+}
+
+// send sends body as JSON with the Authorization header, returning the HTTP status and the response body
+func send(t *testing.T, server *httptest.Server, method string, path string, authorization string, body any) (int, *response) {
+	t.Helper()
+	content, _ := json.Marshal(body)
+	req, err := http.NewRequest(method, server.URL+path, bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	resp := &response{}
+	if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+		t.Fatalf("%s %s: %d, %s", method, path, res.StatusCode, err)
+	}
+	return res.StatusCode, resp
+}
+
+func TestLoginAndUserCRUD(t *testing.T) {
+	server, stores, _ := testServer(t)
+
+	login := call(t, server, "POST", "/api/login", "", &repo.User{Email: "admin@example.com", Password: "secret"})
+	if login.Account == nil || login.Account.Token == "" || login.Account.RefreshToken == "" {
+		t.Fatalf("login: no tokens in %+v", login.Account)
+	}
+	token := "Bearer " + login.Account.Token
+
+	created := &repo.User{}
+	resp := call(t, server, "PUT", "/api/user", token, &repo.User{Email: "jane@example.com", Name: "Jane", Password: "jane", Role: "user"})
+	if err := json.Unmarshal(resp.Data, created); err != nil || created.ID == 0 {
+		t.Fatalf("create: %s, %s", resp.Data, err)
+	}
+	path := "/api/user/" + strconv.Itoa(created.ID)
+
+	call(t, server, "PUT", "/api/user", token, &repo.User{ID: created.ID, Name: "Jane Doe"})
+	found := &repo.User{}
+	resp = call(t, server, "GET", path, token, nil)
+	if err := json.Unmarshal(resp.Data, found); err != nil || found.Name != "Jane Doe" || found.Email != "jane@example.com" || found.Password != "" {
+		t.Fatalf("get: %s, %v", resp.Data, err)
+	}
+
+	var list []repo.User
+	resp = call(t, server, "POST", "/api/users", token, nil)
+	if err := json.Unmarshal(resp.Data, &list); err != nil || len(list) != 2 {
+		t.Fatalf("list: %s, %v", resp.Data, err)
+	}
+
+	call(t, server, "DELETE", path, token, nil)
+	resp = call(t, server, "POST", "/api/users", token, nil)
+	if err := json.Unmarshal(resp.Data, &list); err != nil || len(list) != 1 {
+		t.Fatalf("list after delete: %s, %v", resp.Data, err)
+	}
+
+	_, logContext := u.GetLoggerAndContext()
+	entries, err := stores.Audit.ListAuditEntries(logContext, &repo.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, v := range entries {
+		actions = append(actions, v.Action)
+	}
+	slices.Sort(actions)
+	if want := []string{"user.create", "user.delete", "user.update"}; !slices.Equal(actions, want) {
+		t.Errorf("audited %v, want %v", actions, want)
+	}
+}
+
+// lastToken the token on the last line of the last email sent
+func lastToken(t *testing.T, mailer *mail.MemoryMailer) string {
+	t.Helper()
+	messages := mailer.Messages()
+	if len(messages) == 0 {
+		t.Fatal("no email sent")
+	}
+	lines := strings.Fields(messages[len(messages)-1].Body)
+	return lines[len(lines)-1]
+}
+
+func TestRegistrationApiKeysTenantsAndClients(t *testing.T) {
+	server, stores, mailer := testServer(t)
+
+	call(t, server, "POST", "/api/register", "", &repo.RegisterRequest{Email: "jane@example.com", Password: "jane-password", Name: "Jane"})
+	call(t, server, "POST", "/api/register/activate", "", &repo.RegisterRequest{Token: lastToken(t, mailer)})
+	login := call(t, server, "POST", "/api/login", "", &repo.User{Email: "jane@example.com", Password: "jane-password"})
+
+	apiKey := &repo.ApiKey{}
+	resp := call(t, server, "POST", "/api/apikeys", "Bearer "+login.Account.Token, &repo.ApiKeyRequest{Name: "ci"})
+	if err := json.Unmarshal(resp.Data, apiKey); err != nil || apiKey.Key == "" {
+		t.Fatalf("create API key: %s, %v", resp.Data, err)
+	}
+	var keys []repo.ApiKey
+	resp = call(t, server, "GET", "/api/apikeys", "ApiKey "+apiKey.Key, nil)
+	if err := json.Unmarshal(resp.Data, &keys); err != nil || len(keys) != 1 || keys[0].ID != apiKey.ID {
+		t.Fatalf("list API keys: %s, %v", resp.Data, err)
+	}
+	if code, resp := send(t, server, "POST", "/api/apikeys", "ApiKey "+apiKey.Key, &repo.ApiKeyRequest{Name: "minted"}); code != http.StatusForbidden {
+		t.Errorf("API key created with an API key: %d, %s", code, resp.Message)
+	}
+	call(t, server, "POST", "/api/logout", "ApiKey "+apiKey.Key, nil)
+	if code, resp := send(t, server, "GET", "/api/apikeys", "ApiKey "+apiKey.Key, nil); code != http.StatusForbidden {
+		t.Errorf("API key used after logout: %d, %s", code, resp.Message)
+	}
+
+	login = call(t, server, "POST", "/api/login", "", &repo.User{Email: "admin@example.com", Password: "secret"})
+	admin := "Bearer " + login.Account.Token
+	call(t, server, "PUT", "/api/tenant", admin, &repo.Tenant{Name: "acme"})
+	var tenants []repo.Tenant
+	resp = call(t, server, "GET", "/api/tenants", admin, nil)
+	if err := json.Unmarshal(resp.Data, &tenants); err != nil || len(tenants) != 2 || tenants[1].Name != "acme" {
+		t.Fatalf("list tenants: %s, %v", resp.Data, err)
+	}
+
+	client := &repo.OAuthClient{}
+	resp = call(t, server, "PUT", "/api/oauth/client", admin, &repo.OAuthClientRequest{Name: "billing", Scopes: []string{"users:read"}})
+	if err := json.Unmarshal(resp.Data, client); err != nil || client.Secret == "" {
+		t.Fatalf("create client: %s, %v", resp.Data, err)
+	}
+	call(t, server, "DELETE", "/api/oauth/client/"+strconv.Itoa(client.ID), admin, nil)
+	var clients []repo.OAuthClient
+	resp = call(t, server, "GET", "/api/oauth/clients", admin, nil)
+	if err := json.Unmarshal(resp.Data, &clients); err != nil || len(clients) != 0 {
+		t.Fatalf("list clients after delete: %s, %v", resp.Data, err)
+	}
+
+	_, logContext := u.GetLoggerAndContext()
+	entries, err := stores.Audit.ListAuditEntries(logContext, &repo.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, v := range entries {
+		actions = append(actions, v.Action)
+		if v.Action == "user.activate" && v.ActorID == 0 {
+			t.Errorf("user.activate audited without actor: %+v", v)
+		}
+	}
+	slices.Sort(actions)
+	want := []string{"apikey.create", "apikey.revoke", "oauth_client.create", "oauth_client.delete", "tenant.create", "user.activate", "user.register"}
+	if !slices.Equal(actions, want) {
+		t.Errorf("audited %v, want %v", actions, want)
+	}
+}
+
+func TestGrantsLimitedToCallerPermissions(t *testing.T) {
+	server, _, _ := testServer(t)
+	login := call(t, server, "POST", "/api/login", "", &repo.User{Email: "admin@example.com", Password: "secret"})
+	admin := "Bearer " + login.Account.Token
+
+	created := &repo.User{}
+	resp := call(t, server, "PUT", "/api/user", admin, &repo.User{Email: "jane@example.com", Name: "Jane", Password: "jane", Role: "user"})
+	if err := json.Unmarshal(resp.Data, created); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/user/" + strconv.Itoa(created.ID) + "/roles"
+	if _, resp := send(t, server, "PUT", path, admin, &repo.UserRoles{Roles: []string{"auditor"}}); resp.Status {
+		t.Errorf("role with a permission the caller lacks granted: %s", resp.Message)
+	}
+	if _, resp := send(t, server, "PUT", "/api/role", admin, &repo.Role{Name: "reader", Permissions: []string{"users:read", "audit:read"}}); resp.Status {
+		t.Errorf("role created with a permission the caller lacks: %s", resp.Message)
+	}
+	call(t, server, "PUT", "/api/role", admin, &repo.Role{Name: "reader", Permissions: []string{"users:read"}})
+	call(t, server, "PUT", path, admin, &repo.UserRoles{Roles: []string{"reader"}})
+}
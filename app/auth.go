@@ -3,84 +3,145 @@ package app
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/elnerribeiro/go-ws-db-auth-v2/keys"
 	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
 	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// JwtAuthentication Auth with JWT
-var JwtAuthentication = func(next http.Handler) http.Handler {
-	logger, logContext := u.GetLoggerAndContext()
-
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		notAuth := []string{"/api/login"} //List of endpoints that doesn't require auth
-		requestPath := r.URL.Path         //current request path
+// JwtAuthentication Auth with JWT verified by the key ring, API keys and revocations are checked on the services
+func JwtAuthentication(svc *services.Services, ring *keys.KeyRing) func(http.Handler) http.Handler {
+	logger, _ := u.GetLoggerAndContext()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logContext := u.GetRequestContext(r) //API key and revocation lookups stop if the client goes away
+			response := make(map[string]interface{})
+			tokenHeader := r.Header.Get("Authorization") //Grab the token from the header
+
+			if tokenHeader == "" { //Token is missing, returns with error code 403 Unauthorized
+				logger.Info().Msg("[JwtAuthentication] 403 Token Not found!")
+				response = u.Message(false, "Missing auth token")
+				w.WriteHeader(http.StatusForbidden)
+				w.Header().Add("Content-Type", "application/json")
+				u.Respond(logContext, w, response)
+				return
+			}
 
-		//check if request does not need authentication, serve the request if it doesn't need it
-		for _, value := range notAuth {
+			splitted := strings.Split(tokenHeader, " ") //The token normally comes in format `Bearer {token-body}`, we check if the retrieved token matched this requirement
+			if len(splitted) != 2 {
+				logger.Info().Msg("[JwtAuthentication] 403 Invalid/Malformed auth token!")
+				response = u.Message(false, "Invalid/Malformed auth token")
+				w.WriteHeader(http.StatusForbidden)
+				w.Header().Add("Content-Type", "application/json")
+				u.Respond(logContext, w, response)
+				return
+			}
 
-			if value == requestPath {
-				next.ServeHTTP(w, r)
+			tokenPart := splitted[1] //Grab the token part, what we are truly interested in
+
+			if strings.EqualFold(splitted[0], "ApiKey") { //Machine clients send `ApiKey {key}` instead of a JWT
+				claims, err := svc.AuthenticateApiKey(logContext, tokenPart)
+				if err != nil {
+					logger.Info().Msgf("[JwtAuthentication] 403 Invalid API key: %s", err)
+					response = u.Message(false, "Invalid, expired or revoked API key")
+					w.WriteHeader(http.StatusForbidden)
+					w.Header().Add("Content-Type", "application/json")
+					u.Respond(logContext, w, response)
+					return
+				}
+				if svc.IsTokenRevoked(logContext, claims) { //Owner revoked since the key was created
+					logger.Info().Msgf("[JwtAuthentication] 403 API key revoked for user %d!", claims.UserID)
+					response = u.Message(false, "Token has been revoked.")
+					w.WriteHeader(http.StatusForbidden)
+					w.Header().Add("Content-Type", "application/json")
+					u.Respond(logContext, w, response)
+					return
+				}
+				logger.Info().Msgf("User %d authenticated with an API key", claims.UserID)
+				r = withCaller(r, claims)
+				ctx := context.WithValue(r.Context(), repo.ContextKey("token"), claims)
+				ctx = context.WithValue(ctx, repo.ContextKey("apikey"), true)
+				next.ServeHTTP(w, r.WithContext(ctx)) //proceed in the middleware chain!
 				return
 			}
-		}
 
-		response := make(map[string]interface{})
-		tokenHeader := r.Header.Get("Authorization") //Grab the token from the header
+			token, err := jwt.ParseWithClaims(tokenPart, &repo.Token{}, ring.Keyfunc, jwt.WithValidMethods(ring.Algorithms()))
 
-		if tokenHeader == "" { //Token is missing, returns with error code 403 Unauthorized
-			logger.Info().Msg("[JwtAuthentication] 403 Token Not found!")
-			response = u.Message(false, "Missing auth token")
-			w.WriteHeader(http.StatusForbidden)
-			w.Header().Add("Content-Type", "application/json")
-			u.Respond(logContext, w, response)
-			return
-		}
+			if err != nil { //Malformed token, returns with http code 403 as usual
+				logger.Info().Msg("[JwtAuthentication] 403 Invalid, expired or malformed token!")
+				response = u.Message(false, "Invalid, expired or malformed token")
+				w.WriteHeader(http.StatusForbidden)
+				w.Header().Add("Content-Type", "application/json")
+				u.Respond(logContext, w, response)
+				return
+			}
 
-		splitted := strings.Split(tokenHeader, " ") //The token normally comes in format `Bearer {token-body}`, we check if the retrieved token matched this requirement
-		if len(splitted) != 2 {
-			logger.Info().Msg("[JwtAuthentication] 403 Invalid/Malformed auth token!")
-			response = u.Message(false, "Invalid/Malformed auth token")
-			w.WriteHeader(http.StatusForbidden)
-			w.Header().Add("Content-Type", "application/json")
-			u.Respond(logContext, w, response)
-			return
-		}
+			if !token.Valid { //Token is invalid, maybe not signed on this server
+				logger.Info().Msg("[JwtAuthentication] 403 Token not valid on this server!")
+				response = u.Message(false, "Token is not valid.")
+				w.WriteHeader(http.StatusForbidden)
+				w.Header().Add("Content-Type", "application/json")
+				u.Respond(logContext, w, response)
+				return
+			}
 
-		tokenPart := splitted[1] //Grab the token part, what we are truly interested in
+			parsedToken := token.Claims.(*repo.Token)
+			if slices.Contains(parsedToken.Audience, repo.MfaAudience) { //MFA challenges only work on /api/login/mfa
+				logger.Info().Msg("[JwtAuthentication] 403 MFA challenge used as access token!")
+				response = u.Message(false, "Token is not valid.")
+				w.WriteHeader(http.StatusForbidden)
+				w.Header().Add("Content-Type", "application/json")
+				u.Respond(logContext, w, response)
+				return
+			}
+			if parsedToken.ClientID != "" && !svc.IsClientActive(logContext, parsedToken.ClientID) { //OAuth client was deleted
+				logger.Info().Msgf("[JwtAuthentication] 403 OAuth client %s not found!", parsedToken.ClientID)
+				response = u.Message(false, "Token has been revoked.")
+				w.WriteHeader(http.StatusForbidden)
+				w.Header().Add("Content-Type", "application/json")
+				u.Respond(logContext, w, response)
+				return
+			}
+			if svc.IsTokenRevoked(logContext, parsedToken) { //Token was revoked by logout, user deletion or role change
+				logger.Info().Msgf("[JwtAuthentication] 403 Token revoked for user %d!", parsedToken.UserID)
+				response = u.Message(false, "Token has been revoked.")
+				w.WriteHeader(http.StatusForbidden)
+				w.Header().Add("Content-Type", "application/json")
+				u.Respond(logContext, w, response)
+				return
+			}
 
-		token, err := jwt.ParseWithClaims(tokenPart, &repo.Token{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte("JWTpassword123@"), nil
+			//Everything went well, proceed with the request and set the caller to the user retrieved from the parsed token
+			logger.Info().Msgf("User %d just logged in", parsedToken.UserID) //Useful for monitoring
+			r = withCaller(r, parsedToken)
+			ctx := context.WithValue(r.Context(), repo.ContextKey("token"), parsedToken)
+			if parsedToken.Act != nil { //Impersonation token, the admin behind it goes on the context and on the logs of every write
+				ctx = context.WithValue(ctx, repo.ContextKey("actor"), parsedToken.Act.UserID)
+				if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+					logger.Warn().Int("impersonator", parsedToken.Act.UserID).Int("user", parsedToken.UserID).Str("jti", parsedToken.ID).
+						Msgf("[JwtAuthentication] Impersonated write: %s %s", r.Method, r.URL.Path)
+				}
+			}
+			r = r.WithContext(ctx)
+			next.ServeHTTP(w, r) //proceed in the middleware chain!
 		})
+	}
+}
 
-		if err != nil { //Malformed token, returns with http code 403 as usual
-			logger.Info().Msg("[JwtAuthentication] 403 Invalid, expired or malformed token!")
-			response = u.Message(false, "Invalid, expired or malformed token")
-			w.WriteHeader(http.StatusForbidden)
-			w.Header().Add("Content-Type", "application/json")
-			u.Respond(logContext, w, response)
-			return
-		}
-
-		if !token.Valid { //Token is invalid, maybe not signed on this server
-			logger.Info().Msg("[JwtAuthentication] 403 Token not valid on this server!")
-			response = u.Message(false, "Token is not valid.")
-			w.WriteHeader(http.StatusForbidden)
-			w.Header().Add("Content-Type", "application/json")
-			u.Respond(logContext, w, response)
-			return
-		}
-
-		//Everything went well, proceed with the request and set the caller to the user retrieved from the parsed token
-		parsedToken := token.Claims.(*repo.Token)
-		logger.Info().Msgf("User %d just logged in", parsedToken.UserID) //Useful for monitoring
-		var ctx = context.WithValue(r.Context(), repo.ContextKey("user"), parsedToken.UserID)
-		ctx = context.WithValue(ctx, repo.ContextKey("role"), parsedToken.Role)
-		r = r.WithContext(ctx)
-		next.ServeHTTP(w, r) //proceed in the middleware chain!
-	})
+// withCaller sets the user, tenant, roles, permissions and OAuth client of the caller on the request context.
+// Tokens issued before multi-tenancy have no tenant, they belong to the default one
+func withCaller(r *http.Request, claims *repo.Token) *http.Request {
+	var ctx = context.WithValue(r.Context(), repo.ContextKey("user"), claims.UserID)
+	ctx = context.WithValue(ctx, repo.ContextKey("tenant"), repo.TenantOrDefault(claims.TenantID))
+	ctx = context.WithValue(ctx, repo.ContextKey("role"), claims.Role)
+	ctx = context.WithValue(ctx, repo.ContextKey("roles"), claims.Roles)
+	ctx = context.WithValue(ctx, repo.ContextKey("permissions"), claims.Permissions)
+	ctx = context.WithValue(ctx, repo.ContextKey("client"), claims.ClientID)
+	return r.WithContext(ctx)
 }
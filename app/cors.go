@@ -0,0 +1,19 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/config"
+	"github.com/rs/cors"
+)
+
+// CORS Wraps the handler answering cross-origin requests of the allowed origins
+func CORS(cfg config.CORS, next http.Handler) http.Handler {
+	c := cors.New(cors.Options{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowCredentials: true,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		Debug:            cfg.Debug,
+	})
+	return c.Handler(next)
+}
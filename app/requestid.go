@@ -0,0 +1,26 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// RequestID keeps the X-Request-ID sent by the caller, or creates one, on the context and on the response
+var RequestID = func(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			buf := make([]byte, 16)
+			_, _ = rand.Read(buf)
+			requestID = hex.EncodeToString(buf)
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), repo.ContextKey("requestId"), requestID)))
+	})
+}
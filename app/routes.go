@@ -0,0 +1,154 @@
+package app
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/gorilla/mux"
+)
+
+// Rule decides if the caller of an authenticated request may use the route
+type Rule func(r *http.Request) bool
+
+// Policy authorization of one route: public, or authenticated and passing every rule
+type Policy struct {
+	Public bool
+	Rules  []Rule
+}
+
+// Route one entry of the route table
+type Route struct {
+	Method  string
+	Path    string
+	Handler http.HandlerFunc
+	Policy  Policy
+}
+
+// Public policy of routes that don't need authentication
+func Public() Policy {
+	return Policy{Public: true}
+}
+
+// Authenticated policy of routes that need a valid token and every given rule
+func Authenticated(rules ...Rule) Policy {
+	return Policy{Rules: rules}
+}
+
+// Admin policy of admin-only routes: authenticated, passing every given rule and not under impersonation
+func Admin(rules ...Rule) Policy {
+	return Policy{Rules: append(rules, NotImpersonated())}
+}
+
+// HasRole the caller has one of the roles
+func HasRole(roles ...string) Rule {
+	return func(r *http.Request) bool {
+		role, _ := r.Context().Value(repo.ContextKey("role")).(string)
+		if slices.Contains(roles, role) {
+			return true
+		}
+		granted, _ := r.Context().Value(repo.ContextKey("roles")).([]string)
+		return slices.ContainsFunc(granted, func(v string) bool { return slices.Contains(roles, v) })
+	}
+}
+
+// HasPermission the caller has every one of the permissions
+func HasPermission(permissions ...string) Rule {
+	return func(r *http.Request) bool {
+		granted, _ := r.Context().Value(repo.ContextKey("permissions")).([]string)
+		for _, permission := range permissions {
+			if !slices.Contains(granted, permission) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// IsOwner the caller is the user identified by the path variable
+func IsOwner(pathVar string) Rule {
+	return func(r *http.Request) bool {
+		userID, ok := r.Context().Value(repo.ContextKey("user")).(int)
+		return ok && strconv.Itoa(userID) == mux.Vars(r)[pathVar]
+	}
+}
+
+// NotImpersonated the caller is not using an impersonation token
+func NotImpersonated() Rule {
+	return func(r *http.Request) bool {
+		_, impersonated := r.Context().Value(repo.ContextKey("actor")).(int)
+		return !impersonated
+	}
+}
+
+// UserSession the caller is an user logged in with a bearer token, not an API key nor an OAuth client
+func UserSession() Rule {
+	return func(r *http.Request) bool {
+		userID, _ := r.Context().Value(repo.ContextKey("user")).(int)
+		apiKey, _ := r.Context().Value(repo.ContextKey("apikey")).(bool)
+		return userID != 0 && !apiKey
+	}
+}
+
+// InDefaultTenant the caller belongs to the default tenant, for routes changing data shared by every tenant
+func InDefaultTenant() Rule {
+	return func(r *http.Request) bool {
+		tenantID, _ := r.Context().Value(repo.ContextKey("tenant")).(int)
+		return tenantID == repo.DefaultTenant
+	}
+}
+
+// AnyOf at least one of the rules passes
+func AnyOf(rules ...Rule) Rule {
+	return func(r *http.Request) bool {
+		for _, rule := range rules {
+			if rule(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AllOf every one of the rules passes, to combine rules inside AnyOf
+func AllOf(rules ...Rule) Rule {
+	return func(r *http.Request) bool {
+		for _, rule := range rules {
+			if !rule(r) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// RegisterRoutes adds every route to the router, routes that are not public go through authenticate
+// and then must pass every rule of their policy
+func RegisterRoutes(router *mux.Router, authenticate func(http.Handler) http.Handler, routes []Route) {
+	for _, route := range routes {
+		var handler http.Handler = route.Handler
+		if !route.Policy.Public {
+			handler = authenticate(authorize(route.Policy, handler))
+		}
+		router.Handle(route.Path, handler).Methods(route.Method)
+	}
+}
+
+// authorize answers 403 when the caller doesn't pass every rule of the policy
+func authorize(policy Policy, next http.Handler) http.Handler {
+	logger, logContext := u.GetLoggerAndContext()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, rule := range policy.Rules {
+			if !rule(r) {
+				logger.Info().Msgf("[authorize] 403 Access denied to %s %s", r.Method, r.URL.Path)
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				u.Respond(logContext, w, u.Message(false, "Unauthorized user"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,299 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/magiconair/properties"
+	"github.com/rs/zerolog"
+)
+
+// DefaultFile properties file read when no -config flag is given, it is optional
+const DefaultFile = "application.properties"
+
+// EnvPrefix prefix of the environment variables overriding properties: db.url is read from GOPOC_DB_URL
+const EnvPrefix = "GOPOC_"
+
+// Config every setting of the application, see Load
+type Config struct {
+	Server   Server
+	Log      Log
+	CORS     CORS
+	DB       DB
+	JWT      JWT
+	Login    Login
+	Mail     Mail
+	Register Register
+}
+
+// Server HTTP server settings
+type Server struct {
+	Port            int
+	ShutdownTimeout time.Duration
+}
+
+// Log logging settings
+type Log struct {
+	Level zerolog.Level
+}
+
+// CORS cross-origin settings
+type CORS struct {
+	AllowedOrigins []string
+	Debug          bool
+}
+
+// DB database connection pool settings
+type DB struct {
+	URL               string
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	ConnectTimeout    time.Duration
+	QueryTimeout      time.Duration
+}
+
+// JWT token signing keys, PEM files. Without SigningKey an ephemeral key is generated
+type JWT struct {
+	SigningKey       string
+	VerificationKeys []string
+}
+
+// Login where failed login counters are kept: postgres or memory
+type Login struct {
+	ThrottleStore string
+}
+
+// Mail how emails are sent: smtp, file or memory
+type Mail struct {
+	Mailer       string
+	From         string
+	OutboxDir    string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// Register self registration settings, an empty AllowedDomains allows any domain
+type Register struct {
+	AllowedDomains []string
+	Role           string
+}
+
+// setting one configuration key, with its default value and help text
+type setting struct {
+	key   string
+	value string
+	usage string
+}
+
+var settings = []setting{
+	{"server.port", "8000", "HTTP port"},
+	{"server.shutdown.timeout", "5s", "time given to running requests on shutdown"},
+	{"log.level", "info", "trace, debug, info, warn or error"},
+	{"cors.allowed.origins", "*", "comma separated list of allowed origins"},
+	{"cors.debug", "false", "log CORS decisions"},
+	{"db.url", "", "Postgres connection URL"},
+	{"db.max.conns", "4", "maximum connections on the pool"},
+	{"db.min.conns", "0", "minimum connections kept open on the pool"},
+	{"db.max.conn.lifetime", "1h", "connections older than this are closed"},
+	{"db.max.conn.idle.time", "30m", "idle connections older than this are closed"},
+	{"db.health.check.period", "1m", "interval between health checks of idle connections"},
+	{"db.connect.timeout", "5s", "timeout to open a connection"},
+	{"db.query.timeout", "30s", "timeout of each query, requests cancelled by the client stop their queries sooner"},
+	{"jwt.signing.key", "", "PEM file of the JWT signing key, empty uses an ephemeral key"},
+	{"jwt.verification.keys", "", "comma separated list of PEM files of older keys still accepted"},
+	{"login.throttle.store", "postgres", "where failed login counters are kept: postgres or memory"},
+	{"mail.mailer", "file", "how emails are sent: smtp, file or memory"},
+	{"mail.from", "no-reply@localhost", "sender of emails"},
+	{"mail.outbox.dir", "outbox", "directory of the file mailer"},
+	{"mail.smtp.host", "localhost", "SMTP host"},
+	{"mail.smtp.port", "587", "SMTP port"},
+	{"mail.smtp.username", "", "SMTP username"},
+	{"mail.smtp.password", "", "SMTP password"},
+	{"register.allowed.domains", "", "comma separated list of email domains allowed on /api/register, empty allows any"},
+	{"register.role", "user", "role of registered users"},
+}
+
+// Load Reads the configuration: defaults, overridden by the properties file, then by environment variables
+// (GOPOC_ plus the key in upper case with dots as underscores) and then by command line flags (-db.url=...).
+// The -config flag names the properties file, DefaultFile when omitted. Every invalid setting is reported on the error.
+func Load(args []string) (*Config, error) {
+	values := map[string]string{}
+	for _, s := range settings {
+		values[s.key] = s.value
+	}
+
+	flags := flag.NewFlagSet("gopoc", flag.ContinueOnError)
+	file := flags.String("config", DefaultFile, "properties file")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		flagValues[s.key] = flags.String(s.key, s.value, s.usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	explicit := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	p, err := properties.LoadFile(*file, properties.UTF8)
+	if err != nil && (explicit["config"] || !errors.Is(err, os.ErrNotExist)) {
+		return nil, fmt.Errorf("config: cannot read %s: %w", *file, err)
+	}
+	if p != nil {
+		for _, s := range settings {
+			if v, ok := p.Get(s.key); ok {
+				values[s.key] = v
+			}
+		}
+	}
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(EnvName(s.key)); ok {
+			values[s.key] = v
+		}
+	}
+	for key := range explicit {
+		if v, ok := flagValues[key]; ok {
+			values[key] = *v
+		}
+	}
+	return parse(values)
+}
+
+// EnvName Name of the environment variable overriding the key
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// parser converts setting values, collecting every error
+type parser struct {
+	values map[string]string
+	errs   []error
+}
+
+func (p *parser) fail(key string, format string, args ...any) {
+	p.errs = append(p.errs, fmt.Errorf("config: %s (%s): %s", key, EnvName(key), fmt.Sprintf(format, args...)))
+}
+
+func (p *parser) string(key string) string {
+	return strings.TrimSpace(p.values[key])
+}
+
+func (p *parser) int(key string, minValue int, maxValue int) int {
+	v, err := strconv.Atoi(p.string(key))
+	if err != nil {
+		p.fail(key, "%q is not a number", p.values[key])
+		return 0
+	}
+	if v < minValue || v > maxValue {
+		p.fail(key, "%d is out of range %d-%d", v, minValue, maxValue)
+	}
+	return v
+}
+
+func (p *parser) bool(key string) bool {
+	v, err := strconv.ParseBool(p.string(key))
+	if err != nil {
+		p.fail(key, "%q is not true or false", p.values[key])
+	}
+	return v
+}
+
+func (p *parser) duration(key string) time.Duration {
+	v, err := time.ParseDuration(p.string(key))
+	if err != nil || v <= 0 {
+		p.fail(key, "%q is not a positive duration like 30s, 5m or 1h", p.values[key])
+	}
+	return v
+}
+
+func (p *parser) list(key string) []string {
+	var list []string
+	for _, v := range strings.Split(p.values[key], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func (p *parser) oneOf(key string, options ...string) string {
+	v := p.string(key)
+	if !slices.Contains(options, v) {
+		p.fail(key, "%q is not one of %s", v, strings.Join(options, ", "))
+	}
+	return v
+}
+
+func parse(values map[string]string) (*Config, error) {
+	p := &parser{values: values}
+	cfg := &Config{}
+
+	cfg.Server.Port = p.int("server.port", 1, 65535)
+	cfg.Server.ShutdownTimeout = p.duration("server.shutdown.timeout")
+
+	level, err := zerolog.ParseLevel(p.string("log.level"))
+	if err != nil || level == zerolog.NoLevel {
+		p.fail("log.level", "%q is not one of trace, debug, info, warn, error", values["log.level"])
+	}
+	cfg.Log.Level = level
+
+	cfg.CORS.AllowedOrigins = p.list("cors.allowed.origins")
+	cfg.CORS.Debug = p.bool("cors.debug")
+
+	cfg.DB.URL = p.string("db.url")
+	if cfg.DB.URL == "" {
+		p.fail("db.url", "is required")
+	}
+	cfg.DB.MaxConns = int32(p.int("db.max.conns", 1, 1000))
+	cfg.DB.MinConns = int32(p.int("db.min.conns", 0, 1000))
+	if cfg.DB.MinConns > cfg.DB.MaxConns {
+		p.fail("db.min.conns", "%d is more than db.max.conns %d", cfg.DB.MinConns, cfg.DB.MaxConns)
+	}
+	cfg.DB.MaxConnLifetime = p.duration("db.max.conn.lifetime")
+	cfg.DB.MaxConnIdleTime = p.duration("db.max.conn.idle.time")
+	cfg.DB.HealthCheckPeriod = p.duration("db.health.check.period")
+	cfg.DB.ConnectTimeout = p.duration("db.connect.timeout")
+	cfg.DB.QueryTimeout = p.duration("db.query.timeout")
+
+	cfg.JWT.SigningKey = p.string("jwt.signing.key")
+	cfg.JWT.VerificationKeys = p.list("jwt.verification.keys")
+
+	cfg.Login.ThrottleStore = p.oneOf("login.throttle.store", "postgres", "memory")
+
+	cfg.Mail.Mailer = p.oneOf("mail.mailer", "smtp", "file", "memory")
+	cfg.Mail.From = p.string("mail.from")
+	cfg.Mail.OutboxDir = p.string("mail.outbox.dir")
+	cfg.Mail.SMTPHost = p.string("mail.smtp.host")
+	cfg.Mail.SMTPPort = p.int("mail.smtp.port", 1, 65535)
+	cfg.Mail.SMTPUsername = p.string("mail.smtp.username")
+	cfg.Mail.SMTPPassword = p.string("mail.smtp.password")
+	if cfg.Mail.Mailer == "smtp" && cfg.Mail.SMTPHost == "" {
+		p.fail("mail.smtp.host", "is required by the smtp mailer")
+	}
+	if cfg.Mail.Mailer == "file" && cfg.Mail.OutboxDir == "" {
+		p.fail("mail.outbox.dir", "is required by the file mailer")
+	}
+
+	for _, domain := range p.list("register.allowed.domains") {
+		cfg.Register.AllowedDomains = append(cfg.Register.AllowedDomains, strings.ToLower(domain))
+	}
+	cfg.Register.Role = p.string("register.role")
+	if cfg.Register.Role == "" {
+		p.fail("register.role", "is required")
+	}
+
+	if len(p.errs) > 0 {
+		return nil, errors.Join(p.errs...)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeProperties writes a properties file in dir, returning its path
+func writeProperties(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// unsetenv unsets key for the test, restoring it after
+func unsetenv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir) //No application.properties unless a test writes it
+	file := writeProperties(t, dir, "test.properties", "server.port=8001\ndb.url=postgres://file\n")
+
+	tests := []struct {
+		name string
+		env  string //GOPOC_SERVER_PORT, unset if empty
+		args []string
+		port int
+		url  string
+	}{
+		{"defaults", "", []string{"-db.url=postgres://flag"}, 8000, "postgres://flag"},
+		{"file over defaults", "", []string{"-config=" + file}, 8001, "postgres://file"},
+		{"env over file", "8002", []string{"-config=" + file}, 8002, "postgres://file"},
+		{"flags over env", "8002", []string{"-config=" + file, "-server.port=8003"}, 8003, "postgres://file"},
+		{"flag with the default value over env", "8002", []string{"-config=" + file, "-server.port=8000"}, 8000, "postgres://file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.env != "" {
+				t.Setenv(EnvName("server.port"), test.env)
+			} else {
+				unsetenv(t, EnvName("server.port"))
+			}
+			unsetenv(t, EnvName("db.url"))
+			cfg, err := Load(test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Server.Port != test.port || cfg.DB.URL != test.url {
+				t.Fatalf("port %d and url %s, want %d and %s", cfg.Server.Port, cfg.DB.URL, test.port, test.url)
+			}
+		})
+	}
+}
+
+func TestLoadPropertiesFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv(EnvName("db.url"), "postgres://env")
+
+	if _, err := Load(nil); err != nil { //DefaultFile is optional
+		t.Fatalf("without %s: %s", DefaultFile, err)
+	}
+	missing := filepath.Join(dir, "missing.properties")
+	if _, err := Load([]string{"-config=" + missing}); err == nil || !strings.Contains(err.Error(), "cannot read") {
+		t.Fatalf("missing -config file: %v", err)
+	}
+	writeProperties(t, dir, DefaultFile, "server.port=8004\n")
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 8004 {
+		t.Fatalf("port %d, want 8004 from %s", cfg.Server.Port, DefaultFile)
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	unsetenv(t, EnvName("db.url"))
+
+	_, err := Load([]string{"-server.port=0", "-mail.mailer=fax", "-db.min.conns=5", "-db.max.conns=2", "-db.query.timeout=soon"})
+	if err == nil {
+		t.Fatal("invalid settings loaded")
+	}
+	for _, want := range []string{"server.port", "db.url", "mail.mailer", "db.min.conns", "db.query.timeout", "GOPOC_DB_URL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error doesn't report %s: %s", want, err)
+		}
+	}
+	if lines := strings.Count(err.Error(), "\n") + 1; lines != 5 {
+		t.Errorf("%d errors reported, want 5: %s", lines, err)
+	}
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateApiKey creates an API key for the logged user, the key is shown only on this response
+func (h *Handlers) CreateApiKey(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	request := &repo.ApiKeyRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil || request.Name == "" || request.ExpiresInDays < 0 {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	userID := r.Context().Value(repo.ContextKey("user")).(int)
+	permissions, _ := r.Context().Value(repo.ContextKey("permissions")).([]string)
+	data, err := h.services.CreateApiKey(logContext, auditCaller(r), userID, permissions, request)
+	if err != nil {
+		if errors.Is(err, services.ErrScopeNotGranted) {
+			u.Respond(logContext, w, u.Message(false, "Scope not granted to the user"))
+			return
+		}
+		u.Respond(logContext, w, u.Message(false, "Error creating API key"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// ListApiKeys lists the API keys of the logged user
+func (h *Handlers) ListApiKeys(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	userID := r.Context().Value(repo.ContextKey("user")).(int)
+	data, err := h.services.ListApiKeys(logContext, userID)
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error searching API keys"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// RevokeApiKey revokes one API key of the logged user by ID
+func (h *Handlers) RevokeApiKey(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	vars := mux.Vars(r)
+	kID, _ := strconv.Atoi(vars["id"])
+	key := &repo.ApiKey{}
+	key.ID = kID
+	key.UserID = r.Context().Value(repo.ContextKey("user")).(int)
+	if err := h.services.RevokeApiKey(logContext, auditCaller(r), key); err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error revoking API key"))
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "success"))
+}
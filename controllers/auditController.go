@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// ListAudit Lists the audit log of the tenant of the caller, filtered by the query parameters actor, action, from and to (unix timestamps) and limit
+func (h *Handlers) ListAudit(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	query := r.URL.Query()
+	filter := &repo.AuditFilter{TenantID: callerTenant(r), Action: query.Get("action")}
+	var err error
+	for name, target := range map[string]*int64{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" && err == nil {
+			*target, err = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	if value := query.Get("actor"); value != "" && err == nil {
+		filter.ActorID, err = strconv.Atoi(value)
+	}
+	if value := query.Get("limit"); value != "" && err == nil {
+		filter.Limit, err = strconv.Atoi(value)
+	}
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	data, err := h.services.ListAuditEntries(logContext, filter)
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error searching audit log"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// auditCaller Who is calling, to record on the audit log
+func auditCaller(r *http.Request) *repo.AuditCaller {
+	caller := &repo.AuditCaller{TenantID: callerTenant(r), IP: u.ClientIP(r)}
+	caller.ActorID, _ = r.Context().Value(repo.ContextKey("user")).(int)
+	caller.ImpersonatorID, _ = r.Context().Value(repo.ContextKey("actor")).(int)
+	caller.ClientID, _ = r.Context().Value(repo.ContextKey("client")).(string)
+	caller.RequestID, _ = r.Context().Value(repo.ContextKey("requestId")).(string)
+	return caller
+}
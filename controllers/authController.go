@@ -2,16 +2,19 @@ package controllers
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
-	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
 	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
 )
 
 // Authenticate do user authentication
-var Authenticate = func(w http.ResponseWriter, r *http.Request) {
-	logger, logContext := u.GetLoggerAndContext()
+func (h *Handlers) Authenticate(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
 	account := &repo.User{}
 	err := json.NewDecoder(r.Body).Decode(account) //decode the request body into struct and failed if any error occur
 	if err != nil {
@@ -20,17 +23,115 @@ var Authenticate = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := services.Login(logContext, account, account.Password)
+	ip := u.ClientIP(r)
+	wait, err := h.services.CheckLoginThrottle(logContext, account.Email, ip)
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Connection error. Please retry"))
+		return
+	}
+	if wait > 0 {
+		respondThrottled(logContext, w, wait)
+		return
+	}
+
+	resp := h.services.Login(logContext, account, account.Password, ip)
 	u.Respond(logContext, w, resp)
 }
 
+// LoginMfa second login step for users with MFA enabled, exchanges the MFA challenge and a code for the tokens
+func (h *Handlers) LoginMfa(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
+	request := &repo.MfaRequest{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || request.MfaToken == "" || request.Code == "" {
+		logger.Error().Msgf("Invalid request: %s", err)
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	challenge, err := h.services.ParseMfaChallenge(request.MfaToken)
+	if err != nil {
+		logger.Info().Msgf("[LoginMfa] Invalid, expired or malformed MFA token: %s", err)
+		u.Respond(logContext, w, u.Message(false, "Invalid, expired or malformed MFA token"))
+		return
+	}
+
+	ip := u.ClientIP(r)
+	wait, err := h.services.CheckLoginThrottle(logContext, challenge.Email, ip)
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Connection error. Please retry"))
+		return
+	}
+	if wait > 0 {
+		respondThrottled(logContext, w, wait)
+		return
+	}
+
+	resp := h.services.LoginMfa(logContext, challenge, request.Code, ip)
+	u.Respond(logContext, w, resp)
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token pair
+func (h *Handlers) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
+	refresh := &repo.RefreshToken{}
+	err := json.NewDecoder(r.Body).Decode(refresh)
+	if err != nil || refresh.Token == "" {
+		logger.Error().Msgf("Invalid request: %s", err)
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+
+	resp := h.services.RefreshToken(logContext, refresh.Token)
+	u.Respond(logContext, w, resp)
+}
+
+// Logout revokes the current access token and the refresh token sent on the body, if any
+func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
+	token := r.Context().Value(repo.ContextKey("token")).(*repo.Token)
+	refresh := &repo.RefreshToken{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(refresh); err != nil {
+			logger.Error().Msgf("Invalid request: %s", err)
+			u.Respond(logContext, w, u.Message(false, "Invalid request"))
+			return
+		}
+	}
+
+	if err := h.services.Logout(logContext, auditCaller(r), token, refresh.Token); err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error logging out"))
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "Logged out"))
+}
+
 // Validate do user validation - gets ID
-var Validate = func(w http.ResponseWriter, r *http.Request) {
-	_, logContext := u.GetLoggerAndContext()
+func (h *Handlers) Validate(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
 	id := r.Context().Value(repo.ContextKey("user")).(int)
 	role := r.Context().Value(repo.ContextKey("role")).(string)
 	resp := u.Message(true, "success")
 	resp["userId"] = id
 	resp["role"] = role
+	resp["tenantId"] = callerTenant(r)
+	resp["roles"] = r.Context().Value(repo.ContextKey("roles"))
+	resp["permissions"] = r.Context().Value(repo.ContextKey("permissions"))
+	if clientID, _ := r.Context().Value(repo.ContextKey("client")).(string); clientID != "" {
+		resp["clientId"] = clientID
+	}
+	if actor, ok := r.Context().Value(repo.ContextKey("actor")).(int); ok {
+		resp["actorId"] = actor
+	}
 	u.Respond(logContext, w, resp)
 }
+
+// respondThrottled answers 429 with the seconds to wait on Retry-After
+func respondThrottled(logContext *u.LoggerContext, w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	u.Respond(logContext, w, u.Message(false, "Too many login attempts. Please retry later"))
+}
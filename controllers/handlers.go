@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/keys"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
+)
+
+// Handlers the HTTP handlers of the API, answering with the services and publishing the keys they sign with
+type Handlers struct {
+	services *services.Services
+	keys     *keys.KeyRing
+}
+
+// New Creates the handlers on the services and key ring
+func New(svc *services.Services, ring *keys.KeyRing) *Handlers {
+	return &Handlers{services: svc, keys: ring}
+}
@@ -6,16 +6,17 @@ import (
 	"strconv"
 
 	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
-	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
 	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
 	"github.com/gorilla/mux"
 )
 
 // ClearInserts Clears the database
-var ClearInserts = func(w http.ResponseWriter, r *http.Request) {
-	logger, logContext := u.GetLoggerAndContext()
+func (h *Handlers) ClearInserts(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
 	insert := &repo.Insert{}
-	err := services.ClearInserts(logContext, insert)
+	insert.TenantID = callerTenant(r)
+	err := h.services.ClearInserts(logContext, auditCaller(r), insert)
 	if err != nil {
 		logger.Error().Msgf("[ClearInserts] Error while cleaning inserts: %s", err)
 		resp := u.Message(false, "Error while cleaning batches")
@@ -26,13 +27,15 @@ var ClearInserts = func(w http.ResponseWriter, r *http.Request) {
 }
 
 // ListInsert Lists one insert batch
-var ListInsert = func(w http.ResponseWriter, r *http.Request) {
-	logger, logContext := u.GetLoggerAndContext()
+func (h *Handlers) ListInsert(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
 	vars := mux.Vars(r)
 	uID, _ := strconv.Atoi(vars["id"])
 	insert := &repo.Insert{}
+	insert.TenantID = callerTenant(r)
 	insert.ID = uID
-	data, err := services.ListInserts(logContext, insert)
+	data, err := h.services.ListInserts(logContext, insert)
 	if err != nil {
 		logger.Error().Msgf("[ListInsert] Error listing inserts: %s", err)
 		resp := u.Message(false, "Error querying batch")
@@ -45,13 +48,15 @@ var ListInsert = func(w http.ResponseWriter, r *http.Request) {
 }
 
 // InsertSync Inserts a batch of given quantity sync
-var InsertSync = func(w http.ResponseWriter, r *http.Request) {
-	logger, logContext := u.GetLoggerAndContext()
+func (h *Handlers) InsertSync(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
 	vars := mux.Vars(r)
 	qty, _ := strconv.Atoi(vars["qty"])
 	insert := &repo.Insert{}
+	insert.TenantID = callerTenant(r)
 	insert.Quantity = qty
-	data, err := services.InsertBatchSync(logContext, insert)
+	data, err := h.services.InsertBatchSync(logContext, auditCaller(r), insert)
 	if err != nil {
 		logger.Error().Msgf("[InsertSync] Error inserting batch synchronous: %s", err)
 		resp := u.Message(false, "Error inserting batch")
@@ -64,13 +69,15 @@ var InsertSync = func(w http.ResponseWriter, r *http.Request) {
 }
 
 // InsertASync Inserts a batch of given quantity async
-var InsertASync = func(w http.ResponseWriter, r *http.Request) {
-	logger, logContext := u.GetLoggerAndContext()
+func (h *Handlers) InsertASync(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
 	vars := mux.Vars(r)
 	qty, _ := strconv.Atoi(vars["qty"])
 	insert := &repo.Insert{}
+	insert.TenantID = callerTenant(r)
 	insert.Quantity = qty
-	data, err := services.InsertBatchASync(logContext, insert)
+	data, err := h.services.InsertBatchASync(logContext, auditCaller(r), insert)
 	if err != nil {
 		logger.Error().Msgf("[InsertASync] Error inserting batch asynchronous: %s", err)
 		resp := u.Message(false, "Error inserting batch async")
@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"net/http"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// JWKS publishes the public keys used to verify tokens, so other services don't need a shared secret
+func (h *Handlers) JWKS(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	u.Respond(logContext, w, map[string]interface{}{"keys": h.keys.JWKS()})
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// GetMe Gets the logged user, with its roles and permissions
+func (h *Handlers) GetMe(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	account := &repo.User{}
+	account.ID = r.Context().Value(repo.ContextKey("user")).(int)
+	data, err := h.services.GetUserByID(logContext, account)
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error searching user"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	resp["roles"] = r.Context().Value(repo.ContextKey("roles"))
+	resp["permissions"] = r.Context().Value(repo.ContextKey("permissions"))
+	u.Respond(logContext, w, resp)
+}
+
+// UpdateMe Updates the profile of the logged user. Email and password have their own endpoints, role is only changed by admins
+func (h *Handlers) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	account := &repo.User{}
+	err := json.NewDecoder(r.Body).Decode(account)
+	if err != nil || account.Name == "" {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	if account.Email != "" || account.Password != "" || account.Role != "" || account.ID != 0 {
+		u.Respond(logContext, w, u.Message(false, "Only the name can be changed here, use /api/me/email and /api/me/password"))
+		return
+	}
+	userID := r.Context().Value(repo.ContextKey("user")).(int)
+	data, err := h.services.UpdateProfile(logContext, auditCaller(r), userID, account.Name)
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error updating user"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// ChangeMyPassword Changes the password of the logged user, the current password is required
+func (h *Handlers) ChangeMyPassword(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	request := &repo.PasswordChangeRequest{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || request.CurrentPassword == "" || request.Password == "" {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	userID := r.Context().Value(repo.ContextKey("user")).(int)
+	if err := h.services.ChangePassword(logContext, auditCaller(r), userID, request, u.ClientIP(r)); err != nil {
+		respondProfileError(logContext, w, err)
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "Password changed. Please log in again"))
+}
+
+// ChangeMyEmail Sends a verification token to the new email of the logged user, the current password is required
+func (h *Handlers) ChangeMyEmail(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	request := &repo.EmailChangeRequest{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || request.Email == "" || request.Password == "" {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	userID := r.Context().Value(repo.ContextKey("user")).(int)
+	if err := h.services.RequestEmailChange(logContext, auditCaller(r), userID, request, u.ClientIP(r)); err != nil {
+		respondProfileError(logContext, w, err)
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "A verification token was sent to the new email"))
+}
+
+// VerifyMyEmail Confirms an email change with the token sent to the new email
+func (h *Handlers) VerifyMyEmail(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	request := &repo.EmailChangeRequest{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || request.Token == "" {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	data, err := h.services.VerifyEmailChange(logContext, auditCaller(r), request.Token)
+	if err != nil {
+		respondProfileError(logContext, w, err)
+		return
+	}
+	resp := u.Message(true, "Email changed")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// respondProfileError answers the errors of the self-service endpoints
+func respondProfileError(logContext *u.LoggerContext, w http.ResponseWriter, err error) {
+	var throttled *services.ThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		respondThrottled(logContext, w, throttled.Wait)
+	case errors.Is(err, services.ErrInvalidPassword):
+		u.Respond(logContext, w, u.Message(false, "Invalid password"))
+	case errors.Is(err, services.ErrEmailInUse):
+		u.Respond(logContext, w, u.Message(false, "Email already in use"))
+	case errors.Is(err, services.ErrInvalidEmailToken):
+		u.Respond(logContext, w, u.Message(false, "Invalid, used or expired token"))
+	case errors.Is(err, u.ErrPasswordTooShort):
+		u.Respond(logContext, w, u.Message(false, err.Error()))
+	default:
+		u.Respond(logContext, w, u.Message(false, "Connection error. Please retry"))
+	}
+}
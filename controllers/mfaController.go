@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// EnrollTotp starts the TOTP enrollment of the logged user, returning the otpauth URI and the recovery codes
+func (h *Handlers) EnrollTotp(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	account := &repo.User{}
+	account.ID = r.Context().Value(repo.ContextKey("user")).(int)
+	data, err := h.services.EnrollTotp(logContext, auditCaller(r), account)
+	if err != nil {
+		if errors.Is(err, services.ErrTotpAlreadyEnrolled) {
+			u.Respond(logContext, w, u.Message(false, "TOTP already enabled"))
+			return
+		}
+		u.Respond(logContext, w, u.Message(false, "Error enrolling TOTP"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// ConfirmTotp activates the TOTP enrollment of the logged user with a code from the authenticator app
+func (h *Handlers) ConfirmTotp(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	request := &repo.MfaRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil || request.Code == "" {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	userID := r.Context().Value(repo.ContextKey("user")).(int)
+	if err := h.services.ConfirmTotp(logContext, auditCaller(r), userID, request.Code, u.ClientIP(r)); err != nil {
+		var throttled *services.ThrottledError
+		if errors.As(err, &throttled) {
+			respondThrottled(logContext, w, throttled.Wait)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidMfaCode) {
+			u.Respond(logContext, w, u.Message(false, "Invalid MFA code"))
+			return
+		}
+		u.Respond(logContext, w, u.Message(false, "Error confirming TOTP"))
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "success"))
+}
+
+// DisableTotp removes the TOTP enrollment of the logged user, given a TOTP or recovery code
+func (h *Handlers) DisableTotp(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	request := &repo.MfaRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil || request.Code == "" {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	userID := r.Context().Value(repo.ContextKey("user")).(int)
+	if err := h.services.DisableTotp(logContext, auditCaller(r), userID, request.Code, u.ClientIP(r)); err != nil {
+		var throttled *services.ThrottledError
+		if errors.As(err, &throttled) {
+			respondThrottled(logContext, w, throttled.Wait)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidMfaCode) {
+			u.Respond(logContext, w, u.Message(false, "Invalid MFA code"))
+			return
+		}
+		u.Respond(logContext, w, u.Message(false, "Error disabling TOTP"))
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "success"))
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/gorilla/mux"
+)
+
+// OAuthToken token endpoint of RFC 6749, only the client_credentials grant is supported.
+// Clients authenticate with HTTP Basic or with client_id and client_secret on the form body
+func (h *Handlers) OAuthToken(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
+	if err := r.ParseForm(); err != nil {
+		logger.Error().Msgf("Invalid request: %s", err)
+		respondOAuthError(logContext, w, &services.OAuthError{Code: "invalid_request", Description: "Invalid request", Status: http.StatusBadRequest}, false)
+		return
+	}
+	if grantType := r.PostForm.Get("grant_type"); grantType != "client_credentials" {
+		respondOAuthError(logContext, w, &services.OAuthError{Code: "unsupported_grant_type", Description: "Only client_credentials is supported", Status: http.StatusBadRequest}, false)
+		return
+	}
+	clientID, secret, basic := r.BasicAuth()
+	if basic { //RFC 6749 section 2.3.1, credentials are form encoded before going on the header
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	resp, err := h.services.ClientCredentials(logContext, clientID, secret, r.PostForm.Get("scope"))
+	if err != nil {
+		respondOAuthError(logContext, w, services.AsOAuthError(err), basic)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	u.Respond(logContext, w, resp)
+}
+
+// OAuthIntrospect introspection endpoint of RFC 7662, the token goes on the form body
+func (h *Handlers) OAuthIntrospect(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("token") == "" {
+		logger.Error().Msgf("Invalid request: %s", err)
+		respondOAuthError(logContext, w, &services.OAuthError{Code: "invalid_request", Description: "Missing token", Status: http.StatusBadRequest}, false)
+		return
+	}
+	resp := h.services.IntrospectToken(logContext, r.PostForm.Get("token"))
+	w.Header().Set("Cache-Control", "no-store")
+	u.Respond(logContext, w, resp)
+}
+
+// CreateOAuthClient registers an OAuth client, the secret is shown only on this response
+func (h *Handlers) CreateOAuthClient(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	request := &repo.OAuthClientRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil || request.Name == "" {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	permissions, _ := r.Context().Value(repo.ContextKey("permissions")).([]string)
+	data, err := h.services.CreateOAuthClient(logContext, auditCaller(r), callerTenant(r), permissions, request)
+	if err != nil {
+		if errors.Is(err, services.ErrScopeNotGranted) {
+			u.Respond(logContext, w, u.Message(false, "Scope not granted to the user"))
+			return
+		}
+		u.Respond(logContext, w, u.Message(false, "Error creating OAuth client"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// ListOAuthClients lists all OAuth clients, without secrets
+func (h *Handlers) ListOAuthClients(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	data, err := h.services.ListOAuthClients(logContext, callerTenant(r))
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error searching OAuth clients"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// DeleteOAuthClient deletes an OAuth client by ID
+func (h *Handlers) DeleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	vars := mux.Vars(r)
+	cID, _ := strconv.Atoi(vars["id"])
+	client := &repo.OAuthClient{}
+	client.TenantID = callerTenant(r)
+	client.ID = cID
+	if err := h.services.DeleteOAuthClient(logContext, auditCaller(r), client); err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error deleting OAuth client"))
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "success"))
+}
+
+// respondOAuthError answers with the error response of RFC 6749 section 5.2
+func respondOAuthError(logContext *u.LoggerContext, w http.ResponseWriter, err *services.OAuthError, basic bool) {
+	if err.Status == http.StatusUnauthorized && basic {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(err.Status)
+	u.Respond(logContext, w, map[string]interface{}{"error": err.Code, "error_description": err.Description})
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// ForgotPassword sends a password reset token to the email, answering the same whether the email has an account or not
+func (h *Handlers) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
+	request := &repo.PasswordResetRequest{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || request.Email == "" {
+		logger.Error().Msgf("Invalid request: %s", err)
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	if err := h.services.ForgotPassword(logContext, auditCaller(r), request.TenantID, request.Email, u.ClientIP(r)); err != nil {
+		var throttled *services.ThrottledError
+		if errors.As(err, &throttled) {
+			respondThrottled(logContext, w, throttled.Wait)
+			return
+		}
+		u.Respond(logContext, w, u.Message(false, "Connection error. Please retry"))
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "If the email has an account, a reset token was sent to it"))
+}
+
+// ResetPassword sets a new password with the token sent by ForgotPassword
+func (h *Handlers) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
+	request := &repo.PasswordResetRequest{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || request.Token == "" || request.Password == "" {
+		logger.Error().Msgf("Invalid request: %s", err)
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	if err := h.services.ResetPassword(logContext, auditCaller(r), request.Token, request.Password); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidResetToken):
+			u.Respond(logContext, w, u.Message(false, "Invalid, used or expired reset token"))
+		case errors.Is(err, u.ErrPasswordTooShort):
+			u.Respond(logContext, w, u.Message(false, err.Error()))
+		default:
+			u.Respond(logContext, w, u.Message(false, "Connection error. Please retry"))
+		}
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "Password changed"))
+}
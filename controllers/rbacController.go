@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/gorilla/mux"
+)
+
+// ListRoles Lists all roles with their permissions
+func (h *Handlers) ListRoles(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	data, err := h.services.ListRoles(logContext, &repo.Role{})
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error searching roles"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// UpsertRole Inserts or updates a role and its permissions
+func (h *Handlers) UpsertRole(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	role := &repo.Role{}
+	if err := json.NewDecoder(r.Body).Decode(role); err != nil || role.Name == "" {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	permissions, _ := r.Context().Value(repo.ContextKey("permissions")).([]string)
+	data, err := h.services.UpsertRole(logContext, auditCaller(r), permissions, role)
+	if err != nil {
+		if errors.Is(err, repo.ErrUnknownPermission) {
+			u.Respond(logContext, w, u.Message(false, "Unknown permission"))
+			return
+		}
+		if errors.Is(err, services.ErrPermissionNotGranted) {
+			u.Respond(logContext, w, u.Message(false, "Permission not granted to the caller"))
+			return
+		}
+		u.Respond(logContext, w, u.Message(false, "Error updating role"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// DeleteRole Deletes a role by ID
+func (h *Handlers) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	vars := mux.Vars(r)
+	rID, _ := strconv.Atoi(vars["id"])
+	role := &repo.Role{}
+	role.ID = rID
+	if err := h.services.DeleteRole(logContext, auditCaller(r), role); err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error deleting role"))
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "success"))
+}
+
+// ListPermissions Lists all permissions
+func (h *Handlers) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	data, err := h.services.ListPermissions(logContext, &repo.Permission{})
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error searching permissions"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// UpsertPermission Inserts or updates a permission
+func (h *Handlers) UpsertPermission(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	permission := &repo.Permission{}
+	if err := json.NewDecoder(r.Body).Decode(permission); err != nil || permission.Name == "" {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	data, err := h.services.UpsertPermission(logContext, auditCaller(r), permission)
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error updating permission"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// GetUserRoles Lists the roles granted to an user by ID
+func (h *Handlers) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	vars := mux.Vars(r)
+	uID, _ := strconv.Atoi(vars["id"])
+	account := &repo.User{}
+	account.TenantID = callerTenant(r)
+	account.ID = uID
+	data, err := h.services.GetUserRoles(logContext, account)
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error searching user roles"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// SetUserRoles Replaces the roles granted to an user by ID
+func (h *Handlers) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	vars := mux.Vars(r)
+	uID, _ := strconv.Atoi(vars["id"])
+	request := &repo.UserRoles{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	account := &repo.User{}
+	account.TenantID = callerTenant(r)
+	account.ID = uID
+	permissions, _ := r.Context().Value(repo.ContextKey("permissions")).([]string)
+	data, err := h.services.SetUserRoles(logContext, auditCaller(r), permissions, account, request.Roles)
+	if err != nil {
+		if errors.Is(err, repo.ErrUnknownRole) {
+			u.Respond(logContext, w, u.Message(false, "Unknown role"))
+			return
+		}
+		if errors.Is(err, services.ErrPermissionNotGranted) {
+			u.Respond(logContext, w, u.Message(false, "Permission not granted to the caller"))
+			return
+		}
+		u.Respond(logContext, w, u.Message(false, "Error updating user roles"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
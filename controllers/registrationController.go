@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// Register signs up a new user, which stays pending until activated with the token sent by email
+func (h *Handlers) Register(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
+	request := &repo.RegisterRequest{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || request.Email == "" || request.Password == "" {
+		logger.Error().Msgf("Invalid request: %s", err)
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	if err := h.services.Register(logContext, auditCaller(r), request, u.ClientIP(r)); err != nil {
+		var throttled *services.ThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			respondThrottled(logContext, w, throttled.Wait)
+		case errors.Is(err, services.ErrInvalidEmail):
+			u.Respond(logContext, w, u.Message(false, "Invalid email"))
+		case errors.Is(err, services.ErrDomainNotAllowed):
+			u.Respond(logContext, w, u.Message(false, "Email domain not allowed"))
+		case errors.Is(err, services.ErrUnknownTenant):
+			u.Respond(logContext, w, u.Message(false, "Unknown tenant"))
+		case errors.Is(err, u.ErrPasswordTooShort):
+			u.Respond(logContext, w, u.Message(false, err.Error()))
+		default:
+			u.Respond(logContext, w, u.Message(false, "Connection error. Please retry"))
+		}
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "Check your email to activate the account"))
+}
+
+// Activate activates a registered user with the token sent by email
+func (h *Handlers) Activate(w http.ResponseWriter, r *http.Request) {
+	logger, _ := u.GetLoggerAndContext()
+	logContext := u.GetRequestContext(r)
+	request := &repo.RegisterRequest{}
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || request.Token == "" {
+		logger.Error().Msgf("Invalid request: %s", err)
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	if err := h.services.Activate(logContext, auditCaller(r), request.Token); err != nil {
+		if errors.Is(err, services.ErrInvalidActivationToken) {
+			u.Respond(logContext, w, u.Message(false, "Invalid, used or expired activation token"))
+			return
+		}
+		u.Respond(logContext, w, u.Message(false, "Connection error. Please retry"))
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "Account activated"))
+}
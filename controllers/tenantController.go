@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/services"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// ListTenants Lists all tenants
+func (h *Handlers) ListTenants(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	data, err := h.services.ListTenants(logContext)
+	if err != nil {
+		u.Respond(logContext, w, u.Message(false, "Error searching tenants"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// UpsertTenant Creates a tenant, or renames it when the id is sent
+func (h *Handlers) UpsertTenant(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	tenant := &repo.Tenant{}
+	if err := json.NewDecoder(r.Body).Decode(tenant); err != nil || tenant.Name == "" {
+		u.Respond(logContext, w, u.Message(false, "Invalid request"))
+		return
+	}
+	data, err := h.services.UpsertTenant(logContext, auditCaller(r), tenant)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownTenant) {
+			u.Respond(logContext, w, u.Message(false, "Unknown tenant"))
+			return
+		}
+		u.Respond(logContext, w, u.Message(false, "Error updating tenant"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// callerTenant The tenant of the caller, every query of an admin route is limited to it
+func callerTenant(r *http.Request) int {
+	tenantID, _ := r.Context().Value(repo.ContextKey("tenant")).(int)
+	return repo.TenantOrDefault(tenantID)
+}
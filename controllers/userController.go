@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"strconv"
@@ -13,16 +14,11 @@ import (
 )
 
 // ListUsers Lists all users
-var ListUsers = func(w http.ResponseWriter, r *http.Request) {
-	_, logContext := u.GetLoggerAndContext()
-	role := r.Context().Value(repo.ContextKey("role")).(string)
-	if role != "admin" {
-		resp := u.Message(false, "Unauthorized user")
-		u.Respond(logContext, w, resp)
-		return
-	}
+func (h *Handlers) ListUsers(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
 	account := &repo.User{}
-	data, err := services.ListUsers(logContext, account)
+	account.TenantID = callerTenant(r)
+	data, err := h.services.ListUsers(logContext, account)
 	if err != nil {
 		resp := u.Message(false, "Error searching users")
 		u.Respond(logContext, w, resp)
@@ -34,19 +30,14 @@ var ListUsers = func(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetUserByID Get an user by ID
-var GetUserByID = func(w http.ResponseWriter, r *http.Request) {
-	_, logContext := u.GetLoggerAndContext()
-	role := r.Context().Value(repo.ContextKey("role")).(string)
-	if role != "admin" {
-		resp := u.Message(false, "Unauthorized user")
-		u.Respond(logContext, w, resp)
-		return
-	}
+func (h *Handlers) GetUserByID(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
 	vars := mux.Vars(r)
 	uID, _ := strconv.Atoi(vars["id"])
 	account := &repo.User{}
+	account.TenantID = callerTenant(r)
 	account.ID = uID
-	data, err := services.GetUserByID(logContext, account)
+	data, err := h.services.GetUserByID(logContext, account)
 	if err != nil {
 		resp := u.Message(false, "Error searching user")
 		u.Respond(logContext, w, resp)
@@ -58,22 +49,19 @@ var GetUserByID = func(w http.ResponseWriter, r *http.Request) {
 }
 
 // Upsert Inserts or updates an user
-var Upsert = func(w http.ResponseWriter, r *http.Request) {
-	_, logContext := u.GetLoggerAndContext()
-	role := r.Context().Value(repo.ContextKey("role")).(string)
-	if role != "admin" {
-		resp := u.Message(false, "Unauthorized user")
-		u.Respond(logContext, w, resp)
-		return
-	}
+func (h *Handlers) Upsert(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
 	account := &repo.User{}
 	err := json.NewDecoder(r.Body).Decode(account)
 	if err != nil {
 		u.Respond(logContext, w, u.Message(false, "Invalid request"))
 		return
 	}
+	if callerTenant(r) != repo.DefaultTenant || account.TenantID == 0 { //Only admins of the default tenant work on other tenants
+		account.TenantID = callerTenant(r)
+	}
 
-	us, err2 := services.Upsert(logContext, account)
+	us, err2 := h.services.Upsert(logContext, auditCaller(r), account)
 	if err2 != nil {
 		resp := u.Message(false, "Error updating user")
 		u.Respond(logContext, w, resp)
@@ -85,20 +73,79 @@ var Upsert = func(w http.ResponseWriter, r *http.Request) {
 }
 
 // Delete Deletes an user by ID
-var Delete = func(w http.ResponseWriter, r *http.Request) {
-	_, logContext := u.GetLoggerAndContext()
-	role := r.Context().Value(repo.ContextKey("role")).(string)
-	if role != "admin" {
-		resp := u.Message(false, "Unauthorized user")
+func (h *Handlers) Delete(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	vars := mux.Vars(r)
+	uID, _ := strconv.Atoi(vars["id"])
+	account := &repo.User{}
+	account.TenantID = callerTenant(r)
+	account.ID = uID
+	if err := h.services.Delete(logContext, auditCaller(r), account); err != nil {
+		resp := u.Message(false, "Error deleting user")
 		u.Respond(logContext, w, resp)
 		return
 	}
+	u.Respond(logContext, w, u.Message(true, "success"))
+}
+
+// Unlock Removes the login lock of an user by ID
+func (h *Handlers) Unlock(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
 	vars := mux.Vars(r)
 	uID, _ := strconv.Atoi(vars["id"])
 	account := &repo.User{}
+	account.TenantID = callerTenant(r)
 	account.ID = uID
-	if err := services.Delete(logContext, account); err != nil {
-		resp := u.Message(false, "Error deleting user")
+	if err := h.services.UnlockAccount(logContext, account); err != nil {
+		resp := u.Message(false, "Error unlocking user")
+		u.Respond(logContext, w, resp)
+		return
+	}
+	u.Respond(logContext, w, u.Message(true, "success"))
+}
+
+// Impersonate Issues a short lived token to act as the user by ID, the logged admin is recorded on its act claim
+func (h *Handlers) Impersonate(w http.ResponseWriter, r *http.Request) {
+	logContext := u.GetRequestContext(r)
+	vars := mux.Vars(r)
+	uID, _ := strconv.Atoi(vars["id"])
+	account := &repo.User{}
+	account.TenantID = callerTenant(r)
+	account.ID = uID
+	data, err := h.services.Impersonate(logContext, auditCaller(r), account)
+	if err != nil {
+		if errors.Is(err, services.ErrCannotImpersonate) {
+			u.Respond(logContext, w, u.Message(false, "User cannot be impersonated"))
+			return
+		}
+		u.Respond(logContext, w, u.Message(false, "Error impersonating user"))
+		return
+	}
+	resp := u.Message(true, "success")
+	resp["data"] = data
+	u.Respond(logContext, w, resp)
+}
+
+// Disable Disables an user by ID without deleting it, revoking its tokens
+func (h *Handlers) Disable(w http.ResponseWriter, r *http.Request) {
+	h.setUserStatus(w, r, repo.UserDisabled)
+}
+
+// Enable Enables a disabled or pending user by ID
+func (h *Handlers) Enable(w http.ResponseWriter, r *http.Request) {
+	h.setUserStatus(w, r, repo.UserActive)
+}
+
+func (h *Handlers) setUserStatus(w http.ResponseWriter, r *http.Request, status string) {
+	logContext := u.GetRequestContext(r)
+	vars := mux.Vars(r)
+	uID, _ := strconv.Atoi(vars["id"])
+	account := &repo.User{}
+	account.TenantID = callerTenant(r)
+	account.ID = uID
+	account.Status = status
+	if err := h.services.SetUserStatus(logContext, auditCaller(r), account); err != nil {
+		resp := u.Message(false, "Error updating user")
 		u.Respond(logContext, w, resp)
 		return
 	}
@@ -2,53 +2,84 @@ package db
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/config"
 	"github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/rs/zerolog"
 )
 
 type DatabaseContext context.Context
 
-var connPool *pgxpool.Pool
-var dbContext *DatabaseContext
+// DB the connection pool to the database, created by Open
+type DB struct {
+	pool         *pgxpool.Pool
+	queryTimeout time.Duration
+	columns      sync.Map //table -> map[string]bool, see tableColumns
+}
+
+type dbKey struct{}
 
-func GetDBConnection(logContext *utils.LoggerContext) *pgxpool.Conn {
-	logger := zerolog.Ctx(*logContext)
-	// Create database connection
-	var err error
-	connection, err := connPool.Acquire(*dbContext)
+// errNoDatabase a query without transaction got a context not created by DB
+var errNoDatabase = errors.New("database not initialized")
+
+// Open Creates the connection pool with the database settings, connections are opened on demand
+func Open(cfg config.DB) (*DB, error) {
+	logger, _ := utils.GetLoggerAndContext()
+	poolConfig, err := Config(cfg)
 	if err != nil {
-		logger.Error().Err(err).Msg("Error while acquiring connection from the database pool!!")
-		panic("Error while acquiring connection from the database pool!!")
+		return nil, err
 	}
-
-	err = connection.Ping(*dbContext)
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
-		logger.Error().Err(err).Msg("Could not ping database")
+		logger.Error().Err(err).Msg("Error while creating connection pool to the database!!")
+		return nil, err
 	}
-
-	logger.Info().Msg("Connected to the database!!")
-
-	return connection
-
+	return &DB{pool: pool, queryTimeout: cfg.QueryTimeout}, nil
 }
 
-func GetDBContext() *DatabaseContext {
-	return dbContext
+// Close Closes every connection of the pool, waiting for the ones in use
+func (database *DB) Close() {
+	logger, _ := utils.GetLoggerAndContext()
+	database.pool.Close()
+	logger.Info().Msg("DB connection pool closed.")
 }
 
-func GetDBConnPool() *pgxpool.Pool {
-	return connPool
+// Context Returns the context of queries made without a transaction, SelectAll and SelectOne use it to reach the pool.
+// Queries are cancelled with ctx, usually the context of the request, and each one is limited to db.query.timeout
+func (database *DB) Context(ctx context.Context) *DatabaseContext {
+	dbContext := context.WithValue(ctx, dbKey{}, database)
+	return (*DatabaseContext)(&dbContext)
 }
 
-func init() {
-	initDBContext := context.Background()
-	dbContext = (*DatabaseContext)(&initDBContext)
+// GetTransaction returns a DB transaction, rolled back if ctx is cancelled before the commit
+func (database *DB) GetTransaction(ctx context.Context) (*pgx.Tx, *DatabaseContext, error) {
 	logger, _ := utils.GetLoggerAndContext()
-	initPool, err := pgxpool.NewWithConfig(*dbContext, Config())
+	txContext := database.Context(ctx)
+	tx, err := database.pool.Begin(*txContext) //The connection goes back to the pool on commit or rollback
 	if err != nil {
-		logger.Error().Err(err).Msg("Error while creating connection pool to the database!!")
-		panic("Error while creating connection pool to the database!!")
+		logger.Error().Err(err).Msgf("[GetTransaction] Error starting transaction: %s", err)
+		return nil, nil, err
+	}
+	return &tx, txContext, nil
+}
+
+// QueryContext Returns the context of one query on txContext, limited to db.query.timeout of its DB. Call cancel when done
+func QueryContext(txContext *DatabaseContext) (context.Context, context.CancelFunc) {
+	if database := fromContext(txContext); database != nil && database.queryTimeout > 0 {
+		return context.WithTimeout(*txContext, database.queryTimeout)
+	}
+	return context.WithCancel(*txContext)
+}
+
+// fromContext Returns the DB of a context created by DB, nil if none
+func fromContext(ctx *DatabaseContext) *DB {
+	if ctx == nil || *ctx == nil {
+		return nil
 	}
-	connPool = initPool
+	database, _ := (*ctx).Value(dbKey{}).(*DB)
+	return database
 }
@@ -3,46 +3,43 @@ package db
 import (
 	"context"
 	"errors"
-	"fmt"
 	"github.com/elnerribeiro/go-ws-db-auth-v2/utils"
 	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 	"strconv"
 )
 
+// SqlValue a column and its value. As a filter, Op compares them (Eq when empty) or joins the filters of Group, see Filter
 type SqlValue struct {
 	Name  string
 	Value any
+	Op    Operator
+	Group SqlData
 }
-type SqlData []SqlValue
-
-// GetTransaction returns a DB transaction
-func GetTransaction() (*pgx.Tx, *DatabaseContext, error) {
-	logger, logContext := utils.GetLoggerAndContext()
 
-	database := GetDBConnection(logContext)
-	if database == nil {
-		logger.Error().Msgf("[GetTransaction] Database not initialized.")
-		return nil, nil, errors.New("Database not initialized")
-	}
-	dbContext := GetDBContext()
-	tx, err := database.Begin(*dbContext)
-	if err != nil {
-		logger.Error().Err(err).Msgf("[GetTransaction] Error starting transaction: %s", err)
-		return nil, nil, err
-	}
-	transactionContext := context.Background()
-	return &tx, (*DatabaseContext)(&transactionContext), nil
-}
+// SqlData columns of an insert or update, or filters that must all match
+type SqlData []SqlValue
 
-// Delete removes rows from a table, given filters
+// Delete removes rows from a table, given filters. Table and column names are quoted and must exist,
+// or an UnknownTableError or UnknownColumnError is returned without querying
 func Delete(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, table string, filters SqlData) error {
 	logger := zerolog.Ctx(*logContext)
 	if transaction == nil {
 		logger.Error().Msgf("[Delete] Transaction not found.")
 		return errors.New("not inside transaction")
 	}
-	_, err := (*transaction).Exec(*txContext, generateDeleteExpression(table, filters), generateArguments(filters)...)
+	if err := checkColumns(txContext, transaction, table, columnNames(nil, filters)...); err != nil {
+		logger.Error().Err(err).Msgf("[Delete] Invalid table or columns: %s", err)
+		return err
+	}
+	query, args, err := generateDeleteExpression(table, filters)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Delete] Invalid filters: %s", err)
+		return err
+	}
+	ctx, cancel := QueryContext(txContext)
+	defer cancel()
+	_, err = (*transaction).Exec(ctx, query, args...)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[Delete] Error executing delete query: %s", err)
 		return err
@@ -50,16 +47,12 @@ func Delete(logContext *utils.LoggerContext, txContext *DatabaseContext, transac
 	return nil
 }
 
-func generateDeleteExpression(table string, filters SqlData) string {
-	data := "delete from " + table + " where 1=1"
-	if len(filters) > 0 {
-		count := 0
-		for _, v := range filters {
-			count++
-			data += " and " + v.Name + " = $" + strconv.Itoa(count)
-		}
+func generateDeleteExpression(table string, filters SqlData) (string, []any, error) {
+	where, args, err := Where(filters, 0)
+	if err != nil {
+		return "", nil, err
 	}
-	return data
+	return "delete from " + quoteIdentifier(table) + where, args, nil
 }
 
 func generateArguments(filters SqlData) []any {
@@ -70,58 +63,37 @@ func generateArguments(filters SqlData) []any {
 	return arr
 }
 
-func getValue(value SqlValue) any {
-	if value.Value == nil {
-		return nil
-	}
-	switch value.Value.(type) {
-	default:
-		return fmt.Sprintf("%s", value.Value)
-	case int64:
-		return value.Value.(int64)
-	case int32:
-		return value.Value.(int32)
-	case int16:
-		return value.Value.(int16)
-	case int8:
-		return value.Value.(int8)
-	case int:
-		x, _ := strconv.Atoi(fmt.Sprintf("%d", value.Value))
-		return x
-	case float64:
-		return value.Value.(float64)
-	case float32:
-		return value.Value.(float32)
-	}
-}
-
-func generateArgumentsForUpdate(filters SqlData, params SqlData) []any {
-	var arr []any
-	for _, v := range params {
-		arr = append(arr, getValue(v))
-	}
-	for _, v := range filters {
-		arr = append(arr, getValue(v))
-	}
-	return arr
-}
-
 // Update updates rows on a table, given filters
 func Update(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, table string, params SqlData, filters SqlData) error {
+	_, err := updateRows(logContext, txContext, transaction, table, params, filters)
+	return err
+}
+
+// updateRows Update returning how many rows were updated
+func updateRows(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, table string, params SqlData, filters SqlData) (int64, error) {
 	logger := zerolog.Ctx(*logContext)
 	if transaction == nil {
 		logger.Error().Msgf("[Update] Transaction not found.")
-		return errors.New("not inside transaction")
+		return 0, errors.New("not inside transaction")
+	}
+	if err := checkColumns(txContext, transaction, table, columnNames(params, filters)...); err != nil {
+		logger.Error().Err(err).Msgf("[Update] Invalid table or columns: %s", err)
+		return 0, err
+	}
+	update, queryParams, err := generateUpdateExpression(table, filters, params)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Update] Invalid filters: %s", err)
+		return 0, err
 	}
-	update := generateUpdateExpression(table, filters, params)
-	queryParams := generateArgumentsForUpdate(filters, params)
 
-	_, err := (*transaction).Exec(*txContext, update, queryParams...)
+	ctx, cancel := QueryContext(txContext)
+	defer cancel()
+	tag, err := (*transaction).Exec(ctx, update, queryParams...)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[Update] Error executing update query: %s", err)
-		return err
+		return 0, err
 	}
-	return nil
+	return tag.RowsAffected(), nil
 }
 
 func addCommaIfNeeded(count int) string {
@@ -132,23 +104,20 @@ func addCommaIfNeeded(count int) string {
 	}
 }
 
-func generateUpdateExpression(table string, filters SqlData, params SqlData) string {
-	data := "update " + table + " set "
+func generateUpdateExpression(table string, filters SqlData, params SqlData) (string, []any, error) {
+	data := "update " + quoteIdentifier(table) + " set "
 	count := 0
 	if len(params) > 0 {
 		for _, k := range params {
 			count++
-			data += addCommaIfNeeded(count) + k.Name + " = $" + strconv.Itoa(count)
+			data += addCommaIfNeeded(count) + quoteIdentifier(k.Name) + " = $" + strconv.Itoa(count)
 		}
 	}
-	data = data + " where 1=1"
-	if len(filters) > 0 {
-		for _, k := range filters {
-			count++
-			data += " and " + k.Name + " = $" + strconv.Itoa(count)
-		}
+	where, args, err := Where(filters, count) //Placeholders of the filters come after the ones of the values
+	if err != nil {
+		return "", nil, err
 	}
-	return data
+	return data + where, append(generateArguments(params), args...), nil
 }
 
 // Insert inserts a row on the table
@@ -158,8 +127,14 @@ func Insert(logContext *utils.LoggerContext, txContext *DatabaseContext, transac
 		logger.Error().Msgf("[Insert] Transaction not found.")
 		return errors.New("not inside transaction")
 	}
+	if err := checkColumns(txContext, transaction, table, columnNames(params, nil)...); err != nil {
+		logger.Error().Err(err).Msgf("[Insert] Invalid table or columns: %s", err)
+		return err
+	}
 
-	_, err := (*transaction).Exec(*txContext, generateInsertExpression(table, params), generateArguments(params)...)
+	ctx, cancel := QueryContext(txContext)
+	defer cancel()
+	_, err := (*transaction).Exec(ctx, generateInsertExpression(table, params), generateArguments(params)...)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[Insert] Error executing insert query: %s", err)
 		return err
@@ -168,12 +143,12 @@ func Insert(logContext *utils.LoggerContext, txContext *DatabaseContext, transac
 }
 
 func generateInsertExpression(table string, params SqlData) string {
-	data := "insert into " + table + " ("
+	data := "insert into " + quoteIdentifier(table) + " ("
 	if len(params) > 0 {
 		count := 0
 		for _, k := range params {
 			count++
-			data += addCommaIfNeeded(count) + k.Name
+			data += addCommaIfNeeded(count) + quoteIdentifier(k.Name)
 		}
 	}
 	data = data + ") values ("
@@ -195,9 +170,15 @@ func InsertReturningPostgres[T interface{}](logContext *utils.LoggerContext, txC
 		logger.Error().Msgf("[Insert] Transaction not found.")
 		return nil, errors.New("not inside transaction")
 	}
+	if err := checkColumns(txContext, transaction, table, append(columnNames(params, nil), pk)...); err != nil {
+		logger.Error().Err(err).Msgf("[Insert] Invalid table or columns: %s", err)
+		return nil, err
+	}
 
 	var id int32
-	err := (*transaction).QueryRow(*txContext, generateInsertReturningExpression(table, params, pk), generateArguments(params)...).Scan(&id)
+	ctx, cancel := QueryContext(txContext)
+	defer cancel()
+	err := (*transaction).QueryRow(ctx, generateInsertReturningExpression(table, params, pk), generateArguments(params)...).Scan(&id)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[Insert] Error executing insert query: %s", err)
 		return nil, err
@@ -205,16 +186,16 @@ func InsertReturningPostgres[T interface{}](logContext *utils.LoggerContext, txC
 	paramsValue := SqlValue{Name: pk, Value: id}
 	var paramsQuery SqlData
 	paramsQuery = append(paramsQuery, paramsValue)
-	return SelectOne[T](logContext, txContext, transaction, "select * from "+table+" where "+pk+" = $1", paramsQuery)
+	return SelectOne[T](logContext, txContext, transaction, "select * from "+quoteIdentifier(table)+" where "+quoteIdentifier(pk)+" = $1", paramsQuery)
 }
 
 func generateInsertReturningExpression(table string, params SqlData, pk string) string {
-	data := "insert into " + table + " ("
+	data := "insert into " + quoteIdentifier(table) + " ("
 	if len(params) > 0 {
 		count := 0
 		for _, k := range params {
 			count++
-			data += addCommaIfNeeded(count) + k.Name
+			data += addCommaIfNeeded(count) + quoteIdentifier(k.Name)
 		}
 	}
 	data = data + ") values ("
@@ -225,22 +206,53 @@ func generateInsertReturningExpression(table string, params SqlData, pk string)
 			data += addCommaIfNeeded(count) + "$" + strconv.Itoa(count)
 		}
 	}
-	data = data + ") RETURNING " + pk
+	data = data + ") RETURNING " + quoteIdentifier(pk)
 	return data
 }
 
 // SelectAll retrieves all rows from a table, given a params filter
 func SelectAll[T interface{}](logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, query string, params SqlData) ([]T, error) {
+	return selectAll[T](logContext, txContext, transaction, query, generateArguments(params))
+}
+
+// SelectWhere retrieves all rows of a query with no where clause, adding one for the filters. suffix goes after it,
+// as an order by or limit
+func SelectWhere[T interface{}](logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, query string, filters SqlData, suffix string) ([]T, error) {
+	logger := zerolog.Ctx(*logContext)
+	where, args, err := Where(filters, 0)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[SelectWhere] Invalid filters: %s", err)
+		return nil, err
+	}
+	return selectAll[T](logContext, txContext, transaction, query+where+suffix, args)
+}
+
+func selectAll[T interface{}](logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, query string, args []any) ([]T, error) {
 	logger := zerolog.Ctx(*logContext)
 	var rows pgx.Rows
 	var err error
-	if transaction == nil || txContext == nil {
-		db := GetDBConnection(logContext)
-		dbContext := GetDBContext()
-		defer db.Release()
-		rows, err = db.Query(*dbContext, query, generateArguments(params)...)
+	if transaction == nil {
+		database := fromContext(txContext)
+		if database == nil {
+			logger.Error().Msgf("[SelectAll] Database not initialized.")
+			return nil, errNoDatabase
+		}
+		if tenantID := TenantFrom(txContext); tenantID != 0 { //Row level security needs the tenant set on a transaction
+			tx, tenantContext, err := database.GetTenantTransaction(*txContext, tenantID)
+			if err != nil {
+				logger.Error().Err(err).Msgf("[SelectAll] Error starting tenant transaction: %s", err)
+				return nil, err
+			}
+			defer Rollback(logContext, tenantContext, tx)
+			return selectAll[T](logContext, tenantContext, tx, query, args)
+		}
+	}
+	ctx, cancel := QueryContext(txContext)
+	defer cancel() //Only after CollectRows read every row
+	if transaction == nil {
+		rows, err = fromContext(txContext).pool.Query(ctx, query, args...) //CollectRows closes the rows, releasing the connection
 	} else {
-		rows, err = (*transaction).Query(*txContext, query, generateArguments(params)...)
+		rows, err = (*transaction).Query(ctx, query, args...)
 	}
 	if err != nil {
 		logger.Error().Err(err).Msgf("[SelectAll] Error executing query - %s: %s", query, err)
@@ -273,18 +285,22 @@ func SelectOne[T interface{}](logContext *utils.LoggerContext, txContext *Databa
 	return nil, nil
 }
 
+// Rollback rolls the transaction back, even when its context was already cancelled
 func Rollback(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx) {
 	logger := zerolog.Ctx(*logContext)
-	err := (*transaction).Rollback(*txContext)
+	err := (*transaction).Rollback(context.WithoutCancel(*txContext))
 	if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
 		logger.Err(err).Msg("Error rolling back transaction")
 	}
 }
 
-func Commit(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx) {
+// Commit commits the transaction, failing if its context was cancelled. Nothing is committed when it returns an error
+func Commit(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx) error {
 	logger := zerolog.Ctx(*logContext)
 	err := (*transaction).Commit(*txContext)
-	if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+	if err != nil {
 		logger.Err(err).Msg("Error commiting transaction")
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Operator how a filter compares its column with its value, Eq when empty
+type Operator string
+
+// Operators of filters. In takes a slice, Between a [2]any, IsNull and IsNotNull no value,
+// And and Or join the filters of Group
+const (
+	Eq        Operator = "="
+	Ne        Operator = "<>"
+	Lt        Operator = "<"
+	Le        Operator = "<="
+	Gt        Operator = ">"
+	Ge        Operator = ">="
+	In        Operator = "in"
+	ILike     Operator = "ilike"
+	IsNull    Operator = "is null"
+	IsNotNull Operator = "is not null"
+	Between   Operator = "between"
+	And       Operator = "and"
+	Or        Operator = "or"
+)
+
+// ErrInvalidFilter a filter has an unknown operator or a value its operator can't use
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Filter a filter comparing the column name with value by op
+func Filter(name string, op Operator, value any) SqlValue {
+	return SqlValue{Name: name, Op: op, Value: value}
+}
+
+// InRange a filter for name between from and to, both included
+func InRange(name string, from any, to any) SqlValue {
+	return SqlValue{Name: name, Op: Between, Value: [2]any{from, to}}
+}
+
+// AllOf a group of filters that must all match
+func AllOf(filters ...SqlValue) SqlValue {
+	return SqlValue{Op: And, Group: filters}
+}
+
+// AnyOf a group of filters of which at least one must match
+func AnyOf(filters ...SqlValue) SqlValue {
+	return SqlValue{Op: Or, Group: filters}
+}
+
+// Where Builds the where clause of filters, all of which must match, with placeholders numbered after the first
+// `after` ones already on the query. Column names are quoted, as table.column or schema.table.column when they have
+// dots. Update, Delete and Repository check them against their table, which qualified names must name, while
+// SelectWhere leaves them to the query, where they may name any table it joins. Returns the clause, empty without
+// filters, and its arguments
+func Where(filters SqlData, after int) (string, []any, error) {
+	if len(filters) == 0 {
+		return "", nil, nil
+	}
+	w := &whereBuilder{count: after}
+	expression, err := w.group(And, filters)
+	if err != nil {
+		return "", nil, err
+	}
+	return " where " + expression, w.args, nil
+}
+
+// whereBuilder numbers placeholders and collects arguments while building a where clause
+type whereBuilder struct {
+	count int
+	args  []any
+}
+
+func (w *whereBuilder) placeholder(value any) string {
+	w.count++
+	w.args = append(w.args, value)
+	return "$" + strconv.Itoa(w.count)
+}
+
+func (w *whereBuilder) group(op Operator, filters SqlData) (string, error) {
+	if len(filters) == 0 { //Nothing to match: and is always true, or is always false
+		if op == And {
+			return "true", nil
+		}
+		return "false", nil
+	}
+	var parts []string
+	for _, v := range filters {
+		part, err := w.filter(v)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return "(" + strings.Join(parts, " "+string(op)+" ") + ")", nil
+}
+
+func (w *whereBuilder) filter(value SqlValue) (string, error) {
+	switch value.Op {
+	case "", Eq, Ne, Lt, Le, Gt, Ge, ILike:
+		op := value.Op
+		if op == "" {
+			op = Eq
+		}
+		return quoteIdentifier(value.Name) + " " + string(op) + " " + w.placeholder(getValue(value)), nil
+	case IsNull, IsNotNull:
+		return quoteIdentifier(value.Name) + " " + string(value.Op), nil
+	case In:
+		kind := reflect.ValueOf(value.Value).Kind()
+		if kind != reflect.Slice && kind != reflect.Array {
+			return "", fmt.Errorf("%w: %s in needs a slice, got %T", ErrInvalidFilter, value.Name, value.Value)
+		}
+		return quoteIdentifier(value.Name) + " = any(" + w.placeholder(getValue(value)) + ")", nil
+	case Between:
+		bounds, ok := value.Value.([2]any)
+		if !ok {
+			return "", fmt.Errorf("%w: %s between needs a [2]any, got %T", ErrInvalidFilter, value.Name, value.Value)
+		}
+		from := w.placeholder(getValue(SqlValue{Value: bounds[0]}))
+		to := w.placeholder(getValue(SqlValue{Value: bounds[1]}))
+		return quoteIdentifier(value.Name) + " between " + from + " and " + to, nil
+	case And, Or:
+		return w.group(value.Op, value.Group)
+	}
+	return "", fmt.Errorf("%w: unknown operator %q on %s", ErrInvalidFilter, value.Op, value.Name)
+}
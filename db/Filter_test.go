@@ -0,0 +1,82 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWhere(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters SqlData
+		after   int //placeholders already on the query
+		where   string
+		args    []any
+	}{
+		{"none", nil, 0, "", nil},
+		{"eq by default", SqlData{{Name: "id", Value: 1}}, 0, ` where "id" = $1`, []any{1}},
+		{"and", SqlData{Filter("status", Ne, "revoked"), Filter("name", ILike, "a%")}, 0,
+			` where ("status" <> $1 and "name" ilike $2)`, []any{"revoked", "a%"}},
+		{"after", SqlData{Filter("id", Gt, 5)}, 2, ` where "id" > $3`, []any{5}},
+		{"in", SqlData{Filter("id", In, []int{1, 2}), Filter("role", Eq, "admin")}, 0,
+			` where ("id" = any($1) and "role" = $2)`, []any{[]int{1, 2}, "admin"}},
+		{"between", SqlData{InRange("tstamp", 10, 20), Filter("id", Le, 9)}, 1,
+			` where ("tstamp" between $2 and $3 and "id" <= $4)`, []any{10, 20, 9}},
+		{"null", SqlData{Filter("tstampexp", IsNull, nil), Filter("deleted", IsNotNull, nil)}, 0,
+			` where ("tstampexp" is null and "deleted" is not null)`, nil},
+		{"groups", SqlData{Filter("tenant_id", Eq, 2), AnyOf(Filter("id", In, []int{3}), AllOf(InRange("tstamp", 1, 2), Filter("status", Eq, "active")))}, 0,
+			` where ("tenant_id" = $1 and ("id" = any($2) or ("tstamp" between $3 and $4 and "status" = $5)))`, []any{2, []int{3}, 1, 2, "active"}},
+		{"empty groups", SqlData{AllOf(), AnyOf()}, 0, ` where (true and false)`, nil},
+		{"qualified", SqlData{Filter("public.user_db.email", Eq, "a@b.c")}, 0, ` where "public"."user_db"."email" = $1`, []any{"a@b.c"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			where, args, err := Where(test.filters, test.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if where != test.where {
+				t.Fatalf("where %q, want %q", where, test.where)
+			}
+			if !reflect.DeepEqual(args, test.args) {
+				t.Fatalf("args %#v, want %#v", args, test.args)
+			}
+		})
+	}
+}
+
+func TestWhereInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters SqlData
+	}{
+		{"in without slice", SqlData{Filter("id", In, 1)}},
+		{"between without bounds", SqlData{Filter("id", Between, []int{1, 2})}},
+		{"unknown operator", SqlData{Filter("id", Operator("like"), "a")}},
+		{"inside a group", SqlData{AnyOf(Filter("id", Eq, 1), Filter("id", In, "a"))}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := Where(test.filters, 0); !errors.Is(err, ErrInvalidFilter) {
+				t.Fatalf("error %v, want ErrInvalidFilter", err)
+			}
+		})
+	}
+}
+
+func TestUpdatePlaceholders(t *testing.T) {
+	params := SqlData{{Name: "name", Value: "a"}, {Name: "status", Value: "active"}}
+	filters := SqlData{Filter("id", In, []int{1, 2}), InRange("tstamp", 3, 4)}
+	query, args, err := generateUpdateExpression("user_db", filters, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `update "user_db" set  "name" = $1, "status" = $2 where ("id" = any($3) and "tstamp" between $4 and $5)`
+	if query != want {
+		t.Fatalf("query %q, want %q", query, want)
+	}
+	if !reflect.DeepEqual(args, []any{"a", "active", []int{1, 2}, 3, 4}) {
+		t.Fatalf("args %#v", args)
+	}
+}
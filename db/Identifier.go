@@ -0,0 +1,126 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UnknownTableError the table has no columns on information_schema, it doesn't exist or the role can't see it
+type UnknownTableError struct {
+	Table string
+}
+
+func (e *UnknownTableError) Error() string {
+	return "unknown table " + e.Table
+}
+
+// UnknownColumnError the column is not on the table
+type UnknownColumnError struct {
+	Table  string
+	Column string
+}
+
+func (e *UnknownColumnError) Error() string {
+	return "unknown column " + e.Column + " on table " + e.Table
+}
+
+// quoteIdentifier Quotes a table or column name, as schema.table when it has a dot, so it can't change the query
+func quoteIdentifier(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
+
+// checkColumns Fails with UnknownTableError or UnknownColumnError unless the table has every column of names.
+// Names with dots are columns qualified by the table, see columnOf
+func checkColumns(txContext *DatabaseContext, transaction *pgx.Tx, table string, names ...string) error {
+	columns, err := tableColumns(txContext, transaction, table)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if column, ok := columnOf(table, name); !ok || !columns[column] {
+			return &UnknownColumnError{Table: table, Column: name}
+		}
+	}
+	return nil
+}
+
+// columnOf Returns the column of a name, bare or qualified as table.column or schema.table.column. ok is false when
+// the qualifier is not the table: its name as given, its name without the schema or, for tables named without a
+// schema, the table on any schema
+func columnOf(table string, name string) (string, bool) {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return name, true
+	}
+	qualifier, column := name[:i], name[i+1:]
+	bare := table[strings.LastIndex(table, ".")+1:]
+	if qualifier == table || qualifier == bare {
+		return column, true
+	}
+	return column, !strings.Contains(table, ".") && strings.HasSuffix(qualifier, "."+bare) && strings.Count(qualifier, ".") == 1
+}
+
+// tableColumns Returns the columns of the table, read from information_schema once and then kept on the DB
+// of txContext. Tables are on the current schema, unless named as schema.table. Without a transaction, reads on the pool
+func tableColumns(txContext *DatabaseContext, transaction *pgx.Tx, table string) (map[string]bool, error) {
+	database := fromContext(txContext)
+	if database != nil {
+		if columns, ok := database.columns.Load(table); ok {
+			return columns.(map[string]bool), nil
+		}
+	}
+	if transaction == nil && database == nil {
+		return nil, errNoDatabase
+	}
+
+	var schema any //nil is the current schema
+	name := table
+	if i := strings.Index(table, "."); i >= 0 {
+		schema, name = table[:i], table[i+1:]
+	}
+	ctx, cancel := QueryContext(txContext)
+	defer cancel()
+	query := "select column_name from information_schema.columns where table_schema = coalesce($1::text, current_schema()) and table_name = $2"
+	var rows pgx.Rows
+	var err error
+	if transaction == nil {
+		rows, err = database.pool.Query(ctx, query, schema, name)
+	} else {
+		rows, err = (*transaction).Query(ctx, query, schema, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 { //Not kept, the table may still be created
+		return nil, &UnknownTableError{Table: table}
+	}
+	columns := make(map[string]bool, len(names))
+	for _, v := range names {
+		columns[v] = true
+	}
+	if database != nil {
+		database.columns.Store(table, columns)
+	}
+	return columns, nil
+}
+
+// columnNames Returns the columns of params and filters, including the ones inside filter groups
+func columnNames(params SqlData, filters SqlData) []string {
+	var names []string
+	for _, v := range params {
+		names = append(names, v.Name)
+	}
+	for _, v := range filters {
+		if v.Op == And || v.Op == Or {
+			names = append(names, columnNames(nil, v.Group)...)
+		} else {
+			names = append(names, v.Name)
+		}
+	}
+	return names
+}
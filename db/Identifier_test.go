@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name   string
+		quoted string
+	}{
+		{"email", `"email"`},
+		{"Email", `"Email"`},
+		{"user_db.email", `"user_db"."email"`},
+		{"public.user_db.email", `"public"."user_db"."email"`},
+		{`id" = 1; drop table user_db; --`, `"id"" = 1; drop table user_db; --"`},
+		{"id = 1 or true", `"id = 1 or true"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if quoted := quoteIdentifier(test.name); quoted != test.quoted {
+				t.Fatalf("quoted %s, want %s", quoted, test.quoted)
+			}
+		})
+	}
+}
+
+func TestCheckColumns(t *testing.T) {
+	database := &DB{}
+	database.columns.Store("user_db", map[string]bool{"id": true, "email": true})
+	database.columns.Store("auth.api_key", map[string]bool{"id": true, "user_id": true})
+	dbContext := database.Context(context.Background())
+
+	tests := []struct {
+		name    string
+		table   string
+		columns []string
+		unknown string //column of the UnknownColumnError, none if empty
+	}{
+		{"bare", "user_db", []string{"id", "email"}, ""},
+		{"table", "user_db", []string{"user_db.email"}, ""},
+		{"any schema", "user_db", []string{"public.user_db.email"}, ""},
+		{"missing", "user_db", []string{"id", "password"}, "password"},
+		{"other table", "user_db", []string{"api_key.id"}, "api_key.id"},
+		{"other table on schema", "user_db", []string{"public.api_key.id"}, "public.api_key.id"},
+		{"too many parts", "user_db", []string{"db.public.user_db.id"}, "db.public.user_db.id"},
+		{"schema table", "auth.api_key", []string{"auth.api_key.user_id", "api_key.id", "id"}, ""},
+		{"other schema", "auth.api_key", []string{"public.api_key.id"}, "public.api_key.id"},
+		{"missing on schema table", "auth.api_key", []string{"api_key.email"}, "api_key.email"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkColumns(dbContext, nil, test.table, test.columns...)
+			var unknown *UnknownColumnError
+			switch {
+			case test.unknown == "" && err != nil:
+				t.Fatalf("error %v", err)
+			case test.unknown != "" && (!errors.As(err, &unknown) || unknown.Column != test.unknown):
+				t.Fatalf("error %v, want unknown column %s", err, test.unknown)
+			}
+		})
+	}
+}
+
+func TestColumnNames(t *testing.T) {
+	params := SqlData{{Name: "name"}}
+	filters := SqlData{Filter("id", Eq, 1), AnyOf(Filter("email", Eq, "a"), AllOf(Filter("status", IsNull, nil)))}
+	names := columnNames(params, filters)
+	want := []string{"name", "id", "email", "status"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("names %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,235 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// ErrNotFound no row has the primary key given to Update or Patch. It is also a sql.ErrNoRows
+var ErrNotFound = fmt.Errorf("row not found: %w", sql.ErrNoRows)
+
+// Repository CRUD of the rows of one table as T, found by the pk column. Rows are read and returned with the columns
+// of the db tags of T, so T may leave columns of the table out. Reads without a transaction use the pool, as SelectAll
+type Repository[T interface{}] struct {
+	table   string
+	pk      string
+	columns string
+}
+
+// Sort orders a List by a column, ascending unless Desc
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// ListOptions filters, order and page of a List. Limit 0 lists every row. ForUpdate locks the rows listed until the end
+// of the transaction
+type ListOptions struct {
+	Filters   SqlData
+	Sort      []Sort
+	Limit     int
+	Offset    int
+	ForUpdate bool
+}
+
+type countRow struct {
+	Count int `db:"count"`
+}
+
+type existsRow struct {
+	Found bool `db:"found"`
+}
+
+// NewRepository creates a Repository of the table with primary key pk
+func NewRepository[T interface{}](table string, pk string) *Repository[T] {
+	var names []string
+	for _, field := range fieldsOf(reflect.TypeOf((*T)(nil)).Elem()) {
+		names = append(names, quoteIdentifier(field.name))
+	}
+	return &Repository[T]{table: table, pk: pk, columns: strings.Join(names, ", ")}
+}
+
+// Get gets the row with primary key id, nil if none
+func (r *Repository[T]) Get(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, id any) (*T, error) {
+	result, err := r.List(logContext, txContext, transaction, ListOptions{Filters: SqlData{{Name: r.pk, Value: id}}, Limit: 1})
+	if err != nil || len(result) == 0 {
+		return nil, err
+	}
+	return &result[0], nil
+}
+
+// List lists the rows matching the filters of opts, in its order and page
+func (r *Repository[T]) List(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, opts ListOptions) ([]T, error) {
+	logger := zerolog.Ctx(*logContext)
+	names := columnNames(nil, opts.Filters)
+	for _, v := range opts.Sort {
+		names = append(names, v.Column)
+	}
+	where, args, err := r.where(txContext, transaction, opts.Filters, names...)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[List] Invalid filters of %s: %s", r.table, err)
+		return nil, err
+	}
+	query := "select " + r.columns + " from " + quoteIdentifier(r.table) + where
+	for i, v := range opts.Sort {
+		if i == 0 {
+			query += " order by "
+		} else {
+			query += ", "
+		}
+		query += quoteIdentifier(v.Column)
+		if v.Desc {
+			query += " desc"
+		}
+	}
+	if opts.Limit > 0 {
+		query += " limit " + strconv.Itoa(opts.Limit)
+	}
+	if opts.Offset > 0 {
+		query += " offset " + strconv.Itoa(opts.Offset)
+	}
+	if opts.ForUpdate {
+		query += " for update"
+	}
+	return selectAll[T](logContext, txContext, transaction, query, args)
+}
+
+// Count counts the rows matching the filters
+func (r *Repository[T]) Count(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, filters SqlData) (int, error) {
+	logger := zerolog.Ctx(*logContext)
+	where, args, err := r.where(txContext, transaction, filters, columnNames(nil, filters)...)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Count] Invalid filters of %s: %s", r.table, err)
+		return 0, err
+	}
+	val, err := selectAll[countRow](logContext, txContext, transaction, "select count(*)::int as count from "+quoteIdentifier(r.table)+where, args)
+	if err != nil || len(val) == 0 {
+		return 0, err
+	}
+	return val[0].Count, nil
+}
+
+// Exists tells whether some row matches the filters
+func (r *Repository[T]) Exists(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, filters SqlData) (bool, error) {
+	logger := zerolog.Ctx(*logContext)
+	where, args, err := r.where(txContext, transaction, filters, columnNames(nil, filters)...)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Exists] Invalid filters of %s: %s", r.table, err)
+		return false, err
+	}
+	val, err := selectAll[existsRow](logContext, txContext, transaction, "select exists(select 1 from "+quoteIdentifier(r.table)+where+") as found", args)
+	if err != nil || len(val) == 0 {
+		return false, err
+	}
+	return val[0].Found, nil
+}
+
+// Create inserts v, leaving out the pk, readonly and empty omitempty fields, and returns the inserted row
+func (r *Repository[T]) Create(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, v *T) (*T, error) {
+	logger := zerolog.Ctx(*logContext)
+	if transaction == nil {
+		logger.Error().Msgf("[Create] Transaction not found.")
+		return nil, errors.New("not inside transaction")
+	}
+	data, err := ToSqlData(v, StructOptions{})
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Create] Cannot map struct: %s", err)
+		return nil, err
+	}
+	if err := checkColumns(txContext, transaction, r.table, columnNames(data, nil)...); err != nil {
+		logger.Error().Err(err).Msgf("[Create] Invalid table or columns: %s", err)
+		return nil, err
+	}
+	query := generateInsertExpression(r.table, data)
+	if len(data) == 0 { //Every column gets its default
+		query = "insert into " + quoteIdentifier(r.table) + " default values"
+	}
+	ctx, cancel := QueryContext(txContext)
+	defer cancel()
+	rows, err := (*transaction).Query(ctx, query+" returning "+r.columns, generateArguments(data)...)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Create] Error executing insert on %s: %s", r.table, err)
+		return nil, err
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[T])
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Create] Error reading row inserted on %s: %s", r.table, err)
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Update writes every field of v to its row, found by the pk, empty ones included. Readonly fields are left out.
+// ErrNoPrimaryKey when the pk of v is zero, ErrNotFound when no row has it
+func (r *Repository[T]) Update(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, v *T) error {
+	return r.update(logContext, txContext, transaction, v, StructOptions{KeepEmpty: true})
+}
+
+// Patch writes the given columns of v to its row, found by the pk, or its non empty fields when no column is given.
+// Fails as Update
+func (r *Repository[T]) Patch(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, v *T, columns ...string) error {
+	return r.update(logContext, txContext, transaction, v, StructOptions{KeepEmpty: len(columns) > 0, Only: columns})
+}
+
+// Delete deletes the row with primary key id
+func (r *Repository[T]) Delete(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, id any) error {
+	return Delete(logContext, txContext, transaction, r.table, SqlData{{Name: r.pk, Value: id}})
+}
+
+func (r *Repository[T]) update(logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, v *T, opts StructOptions) error {
+	logger := zerolog.Ctx(*logContext)
+	key, err := ToSqlData(v, StructOptions{IncludePK: true, IncludeReadOnly: true, KeepEmpty: true, Only: []string{r.pk}})
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Update] Cannot map struct: %s", err)
+		return err
+	}
+	if len(key) == 0 {
+		logger.Error().Msgf("[Update] %T has no field for %s", v, r.pk)
+		return ErrNoPrimaryKey
+	}
+	if key[0].Value == nil || reflect.ValueOf(key[0].Value).IsZero() { //Would be a row never inserted
+		logger.Error().Msgf("[Update] %T has a zero %s", v, r.pk)
+		return ErrNoPrimaryKey
+	}
+	opts.IncludePK = true //The pk is filtered out by name below, whatever its tag
+	data, err := ToSqlData(v, opts)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Update] Cannot map struct: %s", err)
+		return err
+	}
+	var params SqlData
+	for _, value := range data {
+		if value.Name != r.pk {
+			params = append(params, value)
+		}
+	}
+	if len(params) == 0 { //Nothing to write, but the row must exist all the same
+		found, err := r.Exists(logContext, txContext, transaction, key)
+		if err == nil && !found {
+			err = ErrNotFound
+		}
+		return err
+	}
+	count, err := updateRows(logContext, txContext, transaction, r.table, params, key)
+	if err == nil && count == 0 {
+		logger.Error().Msgf("[Update] No row of %s with %s %v", r.table, r.pk, key[0].Value)
+		err = ErrNotFound
+	}
+	return err
+}
+
+// where Checks that the table has the columns and builds the where clause of the filters
+func (r *Repository[T]) where(txContext *DatabaseContext, transaction *pgx.Tx, filters SqlData, columns ...string) (string, []any, error) {
+	if err := checkColumns(txContext, transaction, r.table, columns...); err != nil {
+		return "", nil, err
+	}
+	return Where(filters, 0)
+}
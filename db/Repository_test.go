@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// item a row of table items
+type item struct {
+	ID      int    `db:"id,pk"`
+	Name    string `db:"name,omitempty"`
+	Status  string `db:"status,omitempty"`
+	Created int64  `db:"created,readonly"`
+}
+
+// execTx a transaction that only runs Exec, recording the statement and answering with tag
+type execTx struct {
+	pgx.Tx
+	tag  string
+	sql  string
+	args []any
+}
+
+func (tx *execTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	tx.sql, tx.args = sql, arguments
+	return pgconn.NewCommandTag(tx.tag), nil
+}
+
+func TestRepositoryColumns(t *testing.T) {
+	items := NewRepository[item]("items", "id")
+	if want := `"id", "name", "status", "created"`; items.columns != want {
+		t.Fatalf("columns %s, want %s", items.columns, want)
+	}
+}
+
+func TestRepositoryUpdate(t *testing.T) {
+	_, logContext := utils.GetLoggerAndContext()
+	database := &DB{}
+	database.columns.Store("items", map[string]bool{"id": true, "name": true, "status": true, "created": true})
+	dbContext := database.Context(context.Background())
+	items := NewRepository[item]("items", "id")
+
+	tests := []struct {
+		name    string
+		v       *item
+		columns []string //Patch these columns, Update if nil
+		patch   bool
+		tag     string //answered by the database
+		sql     string //run, none if empty
+		args    []any
+		err     error
+	}{
+		{"update", &item{ID: 1, Name: "a", Created: 5}, nil, false, "UPDATE 1",
+			`update "items" set  "name" = $1, "status" = $2 where "id" = $3`, []any{"a", "", 1}, nil},
+		{"update missing", &item{ID: 2, Name: "a"}, nil, false, "UPDATE 0",
+			`update "items" set  "name" = $1, "status" = $2 where "id" = $3`, []any{"a", "", 2}, ErrNotFound},
+		{"update zero pk", &item{Name: "a"}, nil, false, "UPDATE 1", "", nil, ErrNoPrimaryKey},
+		{"patch non empty", &item{ID: 3, Status: "active"}, nil, true, "UPDATE 1",
+			`update "items" set  "status" = $1 where "id" = $2`, []any{"active", 3}, nil},
+		{"patch columns", &item{ID: 4, Name: "b"}, []string{"status"}, true, "UPDATE 1",
+			`update "items" set  "status" = $1 where "id" = $2`, []any{"", 4}, nil},
+		{"patch readonly column", &item{ID: 5, Created: 5}, []string{"name", "created"}, true, "UPDATE 1",
+			`update "items" set  "name" = $1 where "id" = $2`, []any{"", 5}, nil},
+		{"patch missing", &item{ID: 6, Name: "c"}, nil, true, "UPDATE 0",
+			`update "items" set  "name" = $1 where "id" = $2`, []any{"c", 6}, ErrNotFound},
+		{"patch zero pk", &item{Name: "c"}, nil, true, "UPDATE 1", "", nil, ErrNoPrimaryKey},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := &execTx{tag: test.tag}
+			var transaction pgx.Tx = tx
+			var err error
+			if test.patch {
+				err = items.Patch(logContext, dbContext, &transaction, test.v, test.columns...)
+			} else {
+				err = items.Update(logContext, dbContext, &transaction, test.v)
+			}
+			if !errors.Is(err, test.err) {
+				t.Fatalf("error %v, want %v", err, test.err)
+			}
+			if tx.sql != test.sql {
+				t.Fatalf("sql %q, want %q", tx.sql, test.sql)
+			}
+			if !reflect.DeepEqual(tx.args, test.args) {
+				t.Fatalf("args %#v, want %#v", tx.args, test.args)
+			}
+		})
+	}
+}
+
+func TestNotFoundIsNoRows(t *testing.T) {
+	if !errors.Is(ErrNotFound, sql.ErrNoRows) {
+		t.Fatal("ErrNotFound is not a sql.ErrNoRows")
+	}
+}
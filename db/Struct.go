@@ -0,0 +1,206 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// Errors of the struct helpers
+var (
+	ErrNotStruct    = errors.New("not a struct")
+	ErrNoPrimaryKey = errors.New("no primary key")
+)
+
+// StructOptions chooses the fields ToSqlData maps. By default primary keys, readonly and empty omitempty fields are left out
+type StructOptions struct {
+	IncludePK       bool     //keeps the pk fields, usually generated by the database
+	IncludeReadOnly bool     //keeps the readonly fields, filled by the database
+	KeepEmpty       bool     //keeps omitempty fields even when empty, as zero or NULL
+	Only            []string //when not empty, maps only these columns
+}
+
+// structField one column of a struct, from its db tag: db:"name,omitempty,pk,readonly". db:"-" has no column
+type structField struct {
+	index     []int
+	name      string
+	omitEmpty bool
+	pk        bool
+	readOnly  bool
+}
+
+// structFields columns of each struct type, computed once
+var structFields sync.Map //reflect.Type -> []structField
+
+func fieldsOf(t reflect.Type) []structField {
+	if fields, ok := structFields.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields := appendFields(nil, t, nil)
+	structFields.Store(t, fields)
+	return fields
+}
+
+func appendFields(fields []structField, t reflect.Type, parent []int) []structField {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int(nil), parent...), i)
+		tag, _ := sf.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct { //Embedded structs add their columns, as pgx reads them
+			fields = appendFields(fields, sf.Type, index)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		field := structField{index: index, name: parts[0]}
+		if field.name == "" {
+			field.name = strings.ToLower(sf.Name)
+		}
+		for _, option := range parts[1:] {
+			switch option {
+			case "omitempty":
+				field.omitEmpty = true
+			case "pk":
+				field.pk = true
+			case "readonly":
+				field.readOnly = true
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// structValue Returns the struct v points to, or v itself
+func structValue(v any) (reflect.Value, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%w: %T", ErrNotStruct, v)
+	}
+	return value, nil
+}
+
+// ToSqlData Maps the fields of the struct v, or pointer to it, to columns by their db tags, chosen by opts
+func ToSqlData(v any, opts StructOptions) (SqlData, error) {
+	value, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+	var data SqlData
+	for _, field := range fieldsOf(value.Type()) {
+		if (field.pk && !opts.IncludePK) || (field.readOnly && !opts.IncludeReadOnly) {
+			continue
+		}
+		if len(opts.Only) > 0 && !slices.Contains(opts.Only, field.name) {
+			continue
+		}
+		fieldValue := value.FieldByIndex(field.index)
+		if field.omitEmpty && !opts.KeepEmpty && fieldValue.IsZero() {
+			continue
+		}
+		data = append(data, SqlValue{Name: field.name, Value: columnValue(fieldValue)})
+	}
+	return data, nil
+}
+
+// primaryKey Returns the pk columns of v and their values, failing if it has none or one is empty
+func primaryKey(value reflect.Value) (SqlData, error) {
+	var filters SqlData
+	for _, field := range fieldsOf(value.Type()) {
+		if !field.pk {
+			continue
+		}
+		fieldValue := value.FieldByIndex(field.index)
+		if fieldValue.IsZero() {
+			return nil, fmt.Errorf("%w: %s is empty", ErrNoPrimaryKey, field.name)
+		}
+		filters = append(filters, SqlValue{Name: field.name, Value: columnValue(fieldValue)})
+	}
+	if filters == nil {
+		return nil, fmt.Errorf("%w: on %s", ErrNoPrimaryKey, value.Type())
+	}
+	return filters, nil
+}
+
+// pkName Returns the single pk column of the type, empty if it has none or more than one
+func pkName(t reflect.Type) string {
+	name := ""
+	for _, field := range fieldsOf(t) {
+		if field.pk {
+			if name != "" {
+				return ""
+			}
+			name = field.name
+		}
+	}
+	return name
+}
+
+// columnValue the value of a field, pointers replaced by what they point to or nil
+func columnValue(value reflect.Value) any {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	return value.Interface()
+}
+
+// InsertStruct Inserts v on the table with the columns ToSqlData maps by opts. With a single pk column, returns the
+// inserted row as read back from the table, else v itself
+func InsertStruct[T interface{}](logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, table string, v *T, opts StructOptions) (*T, error) {
+	logger := zerolog.Ctx(*logContext)
+	data, err := ToSqlData(v, opts)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[InsertStruct] Cannot map struct: %s", err)
+		return nil, err
+	}
+	if pk := pkName(reflect.TypeOf(v).Elem()); pk != "" {
+		return InsertReturningPostgres[T](logContext, txContext, transaction, table, data, pk)
+	}
+	if err := Insert(logContext, txContext, transaction, table, data); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// UpdateStruct Updates the row of v on the table, found by its pk fields, with the columns ToSqlData maps by opts
+func UpdateStruct[T interface{}](logContext *utils.LoggerContext, txContext *DatabaseContext, transaction *pgx.Tx, table string, v *T, opts StructOptions) error {
+	logger := zerolog.Ctx(*logContext)
+	value, err := structValue(v)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UpdateStruct] Cannot map struct: %s", err)
+		return err
+	}
+	filters, err := primaryKey(value)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UpdateStruct] Cannot find row: %s", err)
+		return err
+	}
+	opts.IncludePK = false //The pk finds the row, it is never changed
+	data, err := ToSqlData(v, opts)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UpdateStruct] Cannot map struct: %s", err)
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return Update(logContext, txContext, transaction, table, data, filters)
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// audited columns embedded on row, as pgx reads them
+type audited struct {
+	Created int64 `db:"created,readonly"`
+}
+
+// row a table row with every kind of db tag
+type row struct {
+	ID       int     `db:"id,pk"`
+	Name     string  `db:"name"`
+	Email    string  `db:"email,omitempty"`
+	Note     *string `db:"note"`
+	Password string  `db:"-"`
+	Status   string
+	internal string
+	audited
+}
+
+func TestToSqlData(t *testing.T) {
+	note := "note"
+	full := &row{ID: 1, Name: "a", Email: "a@b.c", Note: &note, Password: "secret", Status: "active", internal: "x", audited: audited{Created: 9}}
+	empty := &row{ID: 2}
+
+	tests := []struct {
+		name string
+		v    any
+		opts StructOptions
+		data SqlData
+	}{
+		{"defaults", full, StructOptions{},
+			SqlData{{Name: "name", Value: "a"}, {Name: "email", Value: "a@b.c"}, {Name: "note", Value: "note"}, {Name: "status", Value: "active"}}},
+		{"value", *full, StructOptions{},
+			SqlData{{Name: "name", Value: "a"}, {Name: "email", Value: "a@b.c"}, {Name: "note", Value: "note"}, {Name: "status", Value: "active"}}},
+		{"pk and readonly", full, StructOptions{IncludePK: true, IncludeReadOnly: true},
+			SqlData{{Name: "id", Value: 1}, {Name: "name", Value: "a"}, {Name: "email", Value: "a@b.c"}, {Name: "note", Value: "note"}, {Name: "status", Value: "active"}, {Name: "created", Value: int64(9)}}},
+		{"omitempty left out", empty, StructOptions{},
+			SqlData{{Name: "name", Value: ""}, {Name: "note", Value: nil}, {Name: "status", Value: ""}}},
+		{"keep empty", empty, StructOptions{KeepEmpty: true},
+			SqlData{{Name: "name", Value: ""}, {Name: "email", Value: ""}, {Name: "note", Value: nil}, {Name: "status", Value: ""}}},
+		{"only", full, StructOptions{Only: []string{"email", "id", "password"}},
+			SqlData{{Name: "email", Value: "a@b.c"}}},
+		{"only with pk", full, StructOptions{IncludePK: true, Only: []string{"id"}},
+			SqlData{{Name: "id", Value: 1}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := ToSqlData(test.v, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(data, test.data) {
+				t.Fatalf("data %+v, want %+v", data, test.data)
+			}
+		})
+	}
+}
+
+func TestToSqlDataNotStruct(t *testing.T) {
+	var missing *row
+	for _, v := range []any{1, "row", missing, nil} {
+		if _, err := ToSqlData(v, StructOptions{}); !errors.Is(err, ErrNotStruct) {
+			t.Errorf("%#v: error %v, want ErrNotStruct", v, err)
+		}
+	}
+}
+
+func TestPrimaryKey(t *testing.T) {
+	type pair struct {
+		TenantID int    `db:"tenant_id,pk"`
+		Name     string `db:"name,pk"`
+		Value    string `db:"value"`
+	}
+	type noKey struct {
+		Name string `db:"name"`
+	}
+
+	tests := []struct {
+		name    string
+		v       any
+		filters SqlData
+		pkName  string
+	}{
+		{"single", row{ID: 3}, SqlData{{Name: "id", Value: 3}}, "id"},
+		{"zero", row{}, nil, "id"},
+		{"composite", pair{TenantID: 1, Name: "a"}, SqlData{{Name: "tenant_id", Value: 1}, {Name: "name", Value: "a"}}, ""},
+		{"composite with a zero", pair{TenantID: 1}, nil, ""},
+		{"none", noKey{Name: "a"}, nil, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value := reflect.ValueOf(test.v)
+			filters, err := primaryKey(value)
+			if test.filters == nil && !errors.Is(err, ErrNoPrimaryKey) {
+				t.Fatalf("error %v, want ErrNoPrimaryKey", err)
+			}
+			if !reflect.DeepEqual(filters, test.filters) {
+				t.Fatalf("filters %+v, want %+v", filters, test.filters)
+			}
+			if name := pkName(value.Type()); name != test.pkName {
+				t.Fatalf("pk name %q, want %q", name, test.pkName)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TenantRole role used by tenant scoped transactions. Row level security policies on tenant scoped tables
+// only show and accept rows of the tenant on the app.tenant_id setting
+const TenantRole = "app_tenant"
+
+type tenantKey struct{}
+
+// TenantContext Returns a copy of the context that makes SelectAll and SelectOne, called without a transaction,
+// see only rows of the tenant. Tenant 0 means no tenant, the queries see every row.
+func TenantContext(dbContext *DatabaseContext, tenantID int) *DatabaseContext {
+	if tenantID == 0 {
+		return dbContext
+	}
+	ctx := context.WithValue(*dbContext, tenantKey{}, tenantID)
+	return (*DatabaseContext)(&ctx)
+}
+
+// TenantFrom Returns the tenant of a context created by TenantContext or GetTenantTransaction, 0 if none
+func TenantFrom(ctx *DatabaseContext) int {
+	if ctx == nil || *ctx == nil {
+		return 0
+	}
+	tenantID, _ := (*ctx).Value(tenantKey{}).(int)
+	return tenantID
+}
+
+// GetTenantTransaction returns a DB transaction that only sees and changes rows of the tenant.
+// Tenant 0 returns a plain transaction, as GetTransaction.
+func (database *DB) GetTenantTransaction(ctx context.Context, tenantID int) (*pgx.Tx, *DatabaseContext, error) {
+	tx, txContext, err := database.GetTransaction(ctx)
+	if err != nil || tenantID == 0 {
+		return tx, txContext, err
+	}
+	queryContext, cancel := QueryContext(txContext)
+	defer cancel()
+	if _, err := (*tx).Exec(queryContext, "select set_config('app.tenant_id', $1, true)", strconv.Itoa(tenantID)); err != nil {
+		_ = (*tx).Rollback(context.WithoutCancel(*txContext))
+		return nil, nil, err
+	}
+	if _, err := (*tx).Exec(queryContext, "set local role "+TenantRole); err != nil {
+		_ = (*tx).Rollback(context.WithoutCancel(*txContext))
+		return nil, nil, err
+	}
+	tenantContext := context.WithValue(*txContext, tenantKey{}, tenantID)
+	return tx, (*DatabaseContext)(&tenantContext), nil
+}
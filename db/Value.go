@@ -0,0 +1,55 @@
+package db
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"sync"
+)
+
+// encoders encoders of custom types, see RegisterEncoder
+var encoders sync.Map //reflect.Type -> func(any) any
+
+// RegisterEncoder Makes queries bind values of type T, or pointers to them, as encode returns them. For types pgx
+// can't encode itself, encode returns one it can, as a string, []byte or a pgtype value. Register on startup
+func RegisterEncoder[T interface{}](encode func(value T) any) {
+	encoders.Store(reflect.TypeOf((*T)(nil)).Elem(), func(value any) any {
+		return encode(value.(T))
+	})
+}
+
+func getValue(value SqlValue) any {
+	return encodeValue(value.Value)
+}
+
+// encodeValue Returns the value bound to a query parameter. Registered encoders go first, then driver.Valuer and
+// pgtype values, and every other value, as bool, time.Time, []byte, maps and slices, as is, encoded by the pgx codecs
+// of its parameter type. Pointers are bound as what they point to, nil ones as NULL, and slices of registered types,
+// as the ones of In filters, element by element
+func encodeValue(value any) any {
+	if value == nil {
+		return nil
+	}
+	if encode, ok := encoders.Load(reflect.TypeOf(value)); ok {
+		return encode.(func(any) any)(value)
+	}
+	if _, ok := value.(driver.Valuer); ok { //pgtype values are Valuers too, pgx encodes them natively
+		return value
+	}
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Pointer:
+		if reflected.IsNil() {
+			return nil
+		}
+		return encodeValue(reflected.Elem().Interface())
+	case reflect.Slice:
+		if _, ok := encoders.Load(reflected.Type().Elem()); ok {
+			encoded := make([]any, reflected.Len())
+			for i := range encoded {
+				encoded[i] = encodeValue(reflected.Index(i).Interface())
+			}
+			return encoded
+		}
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// cents a custom type bound through RegisterEncoder
+type cents struct {
+	value int
+}
+
+// status a custom type bound through its driver.Valuer
+type status struct {
+	name string
+}
+
+func (s status) Value() (driver.Value, error) {
+	return s.name, nil
+}
+
+func TestEncodeValue(t *testing.T) {
+	RegisterEncoder(func(value cents) any {
+		return value.value
+	})
+	number := int64(7)
+	var noNumber *int64
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	id := pgtype.UUID{Bytes: [16]byte{1, 2, 3}, Valid: true}
+
+	tests := []struct {
+		name  string
+		value any
+		oid   uint32 //type of the parameter the value is bound to
+		bound any    //value given to pgx
+		text  string //value as encoded by pgx, in text format
+	}{
+		{"bool", true, pgtype.BoolOID, true, "t"},
+		{"time", when, pgtype.TimestamptzOID, when, "2024-01-02 03:04:05Z"},
+		{"bytes", []byte{1, 2}, pgtype.ByteaOID, []byte{1, 2}, `\x0102`},
+		{"uuid", id, pgtype.UUIDOID, id, "01020300-0000-0000-0000-000000000000"},
+		{"json map", map[string]any{"a": 1}, pgtype.JSONBOID, map[string]any{"a": 1}, `{"a":1}`},
+		{"slice", []int{1, 2}, pgtype.Int8ArrayOID, []int{1, 2}, "{1,2}"},
+		{"pointer", &number, pgtype.Int8OID, int64(7), "7"},
+		{"nil pointer", noNumber, pgtype.Int8OID, nil, ""},
+		{"nil", nil, pgtype.Int8OID, nil, ""},
+		{"valuer", status{"active"}, pgtype.TextOID, status{"active"}, "active"},
+		{"pgtype", pgtype.Text{String: "text", Valid: true}, pgtype.TextOID, pgtype.Text{String: "text", Valid: true}, "text"},
+		{"encoder", cents{5}, pgtype.Int8OID, 5, "5"},
+		{"encoder pointer", &cents{6}, pgtype.Int8OID, 6, "6"},
+		{"encoder slice", []cents{{1}, {2}}, pgtype.Int8ArrayOID, []any{1, 2}, "{1,2}"},
+	}
+	types := pgtype.NewMap()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bound := getValue(SqlValue{Name: "column", Value: test.value})
+			if !reflect.DeepEqual(bound, test.bound) {
+				t.Fatalf("bound %#v, want %#v", bound, test.bound)
+			}
+			text, err := types.Encode(test.oid, pgtype.TextFormatCode, bound, nil)
+			if err != nil {
+				t.Fatalf("pgx cannot encode %#v: %s", bound, err)
+			}
+			if string(text) != test.text {
+				t.Fatalf("encoded %q, want %q", text, test.text)
+			}
+		})
+	}
+}
+
+func TestEncodeInFilter(t *testing.T) {
+	RegisterEncoder(func(value cents) any {
+		return value.value
+	})
+	where, args, err := Where(SqlData{Filter("amount", In, []cents{{1}, {2}})}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if where != ` where "amount" = any($1)` {
+		t.Fatalf("where %q", where)
+	}
+	if !reflect.DeepEqual(args, []any{[]any{1, 2}}) {
+		t.Fatalf("args %#v", args)
+	}
+}
@@ -1,11 +1,82 @@
 \c test;
+drop table if exists audit_log;
+drop table if exists revoked_token;
+drop table if exists revoked_user;
+drop table if exists refresh_token;
+drop table if exists login_attempt;
+drop table if exists recovery_code;
+drop table if exists user_totp;
+drop table if exists user_roles;
+drop table if exists role_permissions;
+drop table if exists roles;
+drop table if exists permissions;
+drop table if exists api_key;
+drop table if exists oauth_client;
+drop table if exists password_reset;
+drop table if exists email_change;
+drop table if exists account_activation;
 drop table if exists user_db;
 drop table if exists insert_batch;
 drop table if exists ins_id;
-create table user_db (id serial not null, email varchar(50) not null, role varchar(20) not null, password varchar(128) not null, primary key (id));
-create table ins_id (id serial not null, type varchar(20) not null, quantity int not null, status varchar(20) not null, tstampinit bigint, tstampend bigint, primary key (id));
-create table insert_batch(id serial not null, id_ins_id int not null, pos int not null, primary key(id), foreign key (id_ins_id) references ins_id(id));
---PWD abc
+drop table if exists tenants;
+create table tenants (id serial not null, name varchar(100) not null, tstampinit bigint not null, primary key (id), unique (name));
+create table user_db (id serial not null, email varchar(50) not null, name varchar(100) not null default '', role varchar(20) not null, password varchar(255) not null, status varchar(20) not null default 'active', tenant_id int not null default coalesce(nullif(current_setting('app.tenant_id', true), '')::int, 1), primary key (id), unique (tenant_id, email), foreign key (tenant_id) references tenants(id));
+create table refresh_token (id serial not null, user_id int not null, family varchar(64) not null, token_hash varchar(64) not null, status varchar(20) not null, tstampinit bigint not null, tstampexp bigint not null, primary key (id), foreign key (user_id) references user_db(id) on delete cascade);
+create unique index refresh_token_hash_idx on refresh_token (token_hash);
+create index refresh_token_family_idx on refresh_token (family);
+create table revoked_token (jti varchar(64) not null, user_id int not null, tstampexp bigint not null, primary key (jti));
+create index revoked_token_exp_idx on revoked_token (tstampexp);
+create table revoked_user (user_id int not null, tstamprevoked bigint not null, primary key (user_id));
+create table login_attempt (key varchar(320) not null, failures int not null, tstamplast bigint not null, tstamplocked bigint not null, primary key (key));
+create table user_totp (user_id int not null, secret varchar(64) not null, status varchar(20) not null, last_step bigint not null, tstampinit bigint not null, primary key (user_id), foreign key (user_id) references user_db(id) on delete cascade);
+create table recovery_code (id serial not null, user_id int not null, code_hash varchar(64) not null, status varchar(20) not null, primary key (id), foreign key (user_id) references user_db(id) on delete cascade);
+create index recovery_code_user_idx on recovery_code (user_id);
+create table roles (id serial not null, name varchar(50) not null, description varchar(200) not null default '', primary key (id), unique (name));
+create table permissions (id serial not null, name varchar(50) not null, description varchar(200) not null default '', primary key (id), unique (name));
+create table role_permissions (role_id int not null, permission_id int not null, primary key (role_id, permission_id), foreign key (role_id) references roles(id) on delete cascade, foreign key (permission_id) references permissions(id) on delete cascade);
+create table user_roles (user_id int not null, role_id int not null, primary key (user_id, role_id), foreign key (user_id) references user_db(id) on delete cascade, foreign key (role_id) references roles(id) on delete cascade);
+create table api_key (id serial not null, user_id int not null, name varchar(100) not null, prefix varchar(16) not null, key_hash varchar(64) not null, scopes varchar(1000) not null, status varchar(20) not null, tstampinit bigint not null, tstampexp bigint not null, primary key (id), unique (key_hash), foreign key (user_id) references user_db(id) on delete cascade);
+create table oauth_client (id serial not null, client_id varchar(64) not null, name varchar(100) not null, secret_hash varchar(64) not null, scopes varchar(1000) not null, tstampinit bigint not null, tenant_id int not null default coalesce(nullif(current_setting('app.tenant_id', true), '')::int, 1), primary key (id), unique (client_id), foreign key (tenant_id) references tenants(id));
+create table password_reset (id serial not null, user_id int not null, token_hash varchar(64) not null, status varchar(20) not null, tstampinit bigint not null, tstampexp bigint not null, primary key (id), unique (token_hash), foreign key (user_id) references user_db(id) on delete cascade);
+create table email_change (id serial not null, user_id int not null, email varchar(50) not null, token_hash varchar(64) not null, status varchar(20) not null, tstampinit bigint not null, tstampexp bigint not null, primary key (id), unique (token_hash), foreign key (user_id) references user_db(id) on delete cascade);
+create table account_activation (id serial not null, user_id int not null, token_hash varchar(64) not null, status varchar(20) not null, tstampinit bigint not null, tstampexp bigint not null, primary key (id), unique (token_hash), foreign key (user_id) references user_db(id) on delete cascade);
+create table audit_log (id serial not null, tstamp bigint not null, actor_id int not null, impersonator_id int not null, client_id varchar(64) not null, action varchar(50) not null, target varchar(100) not null, before_data jsonb, after_data jsonb, ip varchar(64) not null, request_id varchar(64) not null, tenant_id int not null default coalesce(nullif(current_setting('app.tenant_id', true), '')::int, 1), primary key (id), foreign key (tenant_id) references tenants(id));
+create index audit_log_actor_idx on audit_log (tenant_id, actor_id, tstamp);
+create index audit_log_action_idx on audit_log (tenant_id, action, tstamp);
+create table ins_id (id serial not null, type varchar(20) not null, quantity int not null, status varchar(20) not null, tstampinit bigint, tstampend bigint, tenant_id int not null default coalesce(nullif(current_setting('app.tenant_id', true), '')::int, 1), primary key (id), foreign key (tenant_id) references tenants(id));
+create table insert_batch(id serial not null, id_ins_id int not null, pos int not null, tenant_id int not null default coalesce(nullif(current_setting('app.tenant_id', true), '')::int, 1), primary key(id), foreign key (id_ins_id) references ins_id(id), foreign key (tenant_id) references tenants(id));
+--Tenant transactions switch to role app_tenant, row level security then only shows and accepts rows of the tenant on app.tenant_id
+do $$ begin if not exists (select 1 from pg_roles where rolname = 'app_tenant') then create role app_tenant nologin; end if; end $$;
+grant select, insert, update, delete on all tables in schema public to app_tenant;
+grant usage, select on all sequences in schema public to app_tenant;
+alter table user_db enable row level security;
+create policy tenant_isolation on user_db using (tenant_id = nullif(current_setting('app.tenant_id', true), '')::int);
+alter table ins_id enable row level security;
+create policy tenant_isolation on ins_id using (tenant_id = nullif(current_setting('app.tenant_id', true), '')::int);
+alter table insert_batch enable row level security;
+create policy tenant_isolation on insert_batch using (tenant_id = nullif(current_setting('app.tenant_id', true), '')::int);
+alter table audit_log enable row level security;
+create policy tenant_isolation on audit_log using (tenant_id = nullif(current_setting('app.tenant_id', true), '')::int);
+insert into tenants (name, tstampinit) values ('default', extract(epoch from now())::bigint);
+--PWD abc (legacy SHA-512, upgraded to argon2id on first login)
 insert into user_db (email, role, password) values ('user@user.com', 'user', 'DDAF35A193617ABACC417349AE20413112E6FA4E89A97EA20A9EEEE64B55D39A2192992A274FC1A836BA3C23A3FEEBBD454D4423643CE80E2A9AC94FA54CA49F');
---PWD 123
+--PWD 123 (legacy SHA-512, upgraded to argon2id on first login)
 insert into user_db (email, role, password) values ('admin@admin.com', 'admin', '3C9909AFEC25354D551DAE21590BB26E38D53F2173B8D3DC3EEE4C047E7AB1C1EB8B85103E3BE7BA613B31BB5C9C36214DC9F14A42FD7A2FDB84856BCA5C44C2');
+insert into roles (name, description) values ('admin', 'Administrators'), ('user', 'Regular users');
+insert into permissions (name, description) values
+    ('users:read', 'List and view users'),
+    ('users:write', 'Create, update, delete and unlock users'),
+    ('users:impersonate', 'Act as another user, for support'),
+    ('roles:read', 'List roles, permissions and user roles'),
+    ('roles:write', 'Manage roles, permissions and user roles'),
+    ('batches:read', 'View insert batches'),
+    ('batches:write', 'Run insert batches'),
+    ('batches:clear', 'Remove every insert batch'),
+    ('clients:read', 'List OAuth clients'),
+    ('clients:write', 'Register and delete OAuth clients'),
+    ('tokens:introspect', 'Introspect access tokens'),
+    ('audit:read', 'Query the audit log'),
+    ('tenants:read', 'List tenants'),
+    ('tenants:write', 'Create and rename tenants');
+insert into role_permissions (role_id, permission_id) select r.id, p.id from roles r, permissions p where r.name = 'admin';
+insert into role_permissions (role_id, permission_id) select r.id, p.id from roles r, permissions p where r.name = 'user' and p.name in ('batches:read', 'batches:write');
@@ -9,6 +9,7 @@ require (
 	github.com/magiconair/properties v1.8.10
 	github.com/rs/cors v1.11.1
 	github.com/rs/zerolog v1.34.0
+	golang.org/x/crypto v0.39.0
 )
 
 require (
@@ -17,7 +18,6 @@ require (
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/crypto v0.39.0 // indirect
 	golang.org/x/sync v0.15.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
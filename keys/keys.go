@@ -0,0 +1,184 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/config"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key one key used to sign or verify JWT tokens
+type Key struct {
+	ID        string
+	Algorithm string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	public    crypto.PublicKey
+}
+
+// KeyRing the current signing key plus every key accepted on verification
+type KeyRing struct {
+	signing      *Key
+	verification map[string]*Key
+}
+
+// Sign signs the claims with the current signing key, setting its kid on the header
+func (ring *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ring.signing.method, claims)
+	token.Header["kid"] = ring.signing.ID
+	return token.SignedString(ring.signing.private)
+}
+
+// Keyfunc finds the verification key for a token by its kid, to be used on jwt.Parse
+func (ring *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ring.verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// Algorithms lists the algorithms accepted on verification
+func (ring *KeyRing) Algorithms() []string {
+	seen := map[string]bool{}
+	var algs []string
+	for _, v := range ring.verification {
+		if !seen[v.Algorithm] {
+			seen[v.Algorithm] = true
+			algs = append(algs, v.Algorithm)
+		}
+	}
+	return algs
+}
+
+// VerificationKeys lists every key accepted on verification, the signing key included
+func (ring *KeyRing) VerificationKeys() []*Key {
+	var list []*Key
+	list = append(list, ring.signing)
+	for _, v := range ring.verification {
+		if v.ID != ring.signing.ID {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func loadKeyRing(signingFile string, verificationFiles []string) (*KeyRing, error) {
+	logger, _ := utils.GetLoggerAndContext()
+	result := &KeyRing{verification: map[string]*Key{}}
+	if signingFile == "" {
+		logger.Warn().Msg("[keys] No jwt.signing.key configured, using an ephemeral Ed25519 key. Tokens will not survive a restart.")
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		if result.signing, err = newKey(private); err != nil {
+			return nil, err
+		}
+	} else {
+		parsed, err := readPEM(signingFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s: signing key must be a private key", signingFile)
+		}
+		if result.signing, err = newKey(signer); err != nil {
+			return nil, fmt.Errorf("%s: %w", signingFile, err)
+		}
+	}
+	result.verification[result.signing.ID] = result.signing
+
+	for _, file := range verificationFiles {
+		parsed, err := readPEM(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := newKey(parsed)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		result.verification[key.ID] = key
+		logger.Info().Msgf("[keys] Verification key %s (%s) loaded from %s", key.ID, key.Algorithm, file)
+	}
+	logger.Info().Msgf("[keys] Signing with key %s (%s)", result.signing.ID, result.signing.Algorithm)
+	return result, nil
+}
+
+// newKey builds a Key from a private or public RSA or Ed25519 key
+func newKey(parsed any) (*Key, error) {
+	key := &Key{}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.private = signer
+		key.public = signer.Public()
+	} else {
+		key.public = parsed
+	}
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must have at least 2048 bits")
+		}
+		key.Algorithm = jwt.SigningMethodRS256.Alg()
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Algorithm = jwt.SigningMethodEdDSA.Alg()
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key.public)
+	}
+	id, err := thumbprint(key.public)
+	if err != nil {
+		return nil, err
+	}
+	key.ID = id
+	return key, nil
+}
+
+// readPEM reads the first PEM block of a file as a PKCS#8/PKCS#1 private key or a PKIX public key
+func readPEM(file string) (any, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", file)
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("%s: unsupported PEM block %s", file, block.Type)
+}
+
+// Load Loads the signing and verification keys of the JWT settings
+func Load(cfg config.JWT) (*KeyRing, error) {
+	logger, _ := utils.GetLoggerAndContext()
+	loaded, err := loadKeyRing(cfg.SigningKey, cfg.VerificationKeys)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error while loading JWT keys!!")
+		return nil, err
+	}
+	return loaded, nil
+}
@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/config"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// Message one plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails, the implementation is chosen by the mail.mailer setting
+type Mailer interface {
+	Send(logContext *utils.LoggerContext, msg Message) error
+}
+
+// New Returns the mailer chosen on the mail settings
+func New(cfg config.Mail) Mailer {
+	switch cfg.Mailer {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}
+	case "memory":
+		return &MemoryMailer{}
+	default:
+		return &FileMailer{Dir: cfg.OutboxDir, From: cfg.From}
+	}
+}
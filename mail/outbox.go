@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+// FileMailer writes every email as an .eml file on Dir, to run locally without a mail server
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// Send Writes the message to the outbox directory
+func (m *FileMailer) Send(logContext *utils.LoggerContext, msg Message) error {
+	logger := zerolog.Ctx(*logContext)
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		logger.Error().Err(err).Msgf("[FileMailer.Send] Error creating outbox %s: %s", m.Dir, err)
+		return err
+	}
+	name := filepath.Join(m.Dir, fmt.Sprintf("%d.eml", time.Now().UnixNano()))
+	if err := os.WriteFile(name, format(m.From, msg), 0o600); err != nil {
+		logger.Error().Err(err).Msgf("[FileMailer.Send] Error writing %s: %s", name, err)
+		return err
+	}
+	logger.Info().Msgf("[FileMailer.Send] Email to %s written to %s", msg.To, name)
+	return nil
+}
+
+// MemoryMailer keeps every email in memory, for tests
+type MemoryMailer struct {
+	sync.Mutex
+	messages []Message
+}
+
+// Send Appends the message to the outbox
+func (m *MemoryMailer) Send(logContext *utils.LoggerContext, msg Message) error {
+	m.Lock()
+	defer m.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages Returns a copy of every email sent so far
+func (m *MemoryMailer) Messages() []Message {
+	m.Lock()
+	defer m.Unlock()
+	return append([]Message(nil), m.messages...)
+}
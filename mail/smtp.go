@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+// SMTPMailer sends emails through an SMTP server, authenticating only when Username is set
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Send Sends the message to the SMTP server
+func (m *SMTPMailer) Send(logContext *utils.LoggerContext, msg Message) error {
+	logger := zerolog.Ctx(*logContext)
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	if err := smtp.SendMail(addr, auth, m.From, []string{msg.To}, format(m.From, msg)); err != nil {
+		logger.Error().Err(err).Msgf("[SMTPMailer.Send] Error sending email to %s: %s", msg.To, err)
+		return err
+	}
+	logger.Info().Msgf("[SMTPMailer.Send] Email sent to %s", msg.To)
+	return nil
+}
+
+// format Builds the RFC 5322 message, header lines can't break so new lines are removed from them
+func format(from string, msg Message) []byte {
+	clean := strings.NewReplacer("\r", "", "\n", "")
+	var b strings.Builder
+	b.WriteString("From: " + clean.Replace(from) + "\r\n")
+	b.WriteString("To: " + clean.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + clean.Replace(msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
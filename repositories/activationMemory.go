@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryActivationStore ActivationStore kept in memory, for tests, with transactions of MemoryTransactor. Writes are
+// applied at once and undone on rollback
+type MemoryActivationStore struct {
+	sync.Mutex
+	activations map[int]AccountActivation
+	lastID      int
+}
+
+// NewMemoryActivationStore creates an empty MemoryActivationStore
+func NewMemoryActivationStore() *MemoryActivationStore {
+	return &MemoryActivationStore{activations: map[int]AccountActivation{}}
+}
+
+// InsertActivation stores a new activation token
+func (store *MemoryActivationStore) InsertActivation(logContext *u.LoggerContext, tx Transaction, activation *AccountActivation) error {
+	store.Lock()
+	defer store.Unlock()
+	store.lastID++
+	created := *activation
+	created.ID = store.lastID
+	keepEntry(tx, store, store.activations, created.ID)
+	store.activations[created.ID] = created
+	return nil
+}
+
+// GetActivationByHash gets the activation token with the hash of activation, nothing is locked
+func (store *MemoryActivationStore) GetActivationByHash(logContext *u.LoggerContext, tx Transaction, activation *AccountActivation) (*AccountActivation, error) {
+	store.Lock()
+	defer store.Unlock()
+	for _, v := range store.activations {
+		if v.TokenHash == activation.TokenHash {
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+// UseUserActivations marks every active activation token of the user of activation as used
+func (store *MemoryActivationStore) UseUserActivations(logContext *u.LoggerContext, tx Transaction, activation *AccountActivation) error {
+	store.Lock()
+	defer store.Unlock()
+	for id, v := range store.activations {
+		if v.UserID == activation.UserID && v.Status == ActivationActive {
+			keepEntry(tx, store, store.activations, id)
+			v.Status = ActivationUsed
+			store.activations[id] = v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// InsertActivation Stores a new activation token
+func (activation *AccountActivation) InsertActivation(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "user_id", Value: activation.UserID})
+	data = append(data, db.SqlValue{Name: "token_hash", Value: activation.TokenHash})
+	data = append(data, db.SqlValue{Name: "status", Value: activation.Status})
+	data = append(data, db.SqlValue{Name: "tstampinit", Value: activation.Tstampinit})
+	data = append(data, db.SqlValue{Name: "tstampexp", Value: activation.Tstampexp})
+	if err := db.Insert(logContext, txContext, tx, "account_activation", data); err != nil {
+		logger.Error().Err(err).Msgf("[InsertActivation] Cannot insert activation for user %d: %s", activation.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// GetActivationByHash Retrieves and locks an activation token by its hash
+func (activation *AccountActivation) GetActivationByHash(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*AccountActivation, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "token_hash", Value: activation.TokenHash})
+	query := `
+		select id, user_id, token_hash, status, tstampinit, tstampexp from account_activation
+		where token_hash = $1
+		for update
+	`
+	val, err := db.SelectOne[AccountActivation](logContext, txContext, tx, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetActivationByHash] Error retrieving activation: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// UseUserActivations Marks every active activation token of the user as used
+func (activation *AccountActivation) UseUserActivations(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "status", Value: ActivationUsed})
+	var filters db.SqlData
+	filters = append(filters, db.SqlValue{Name: "user_id", Value: activation.UserID})
+	filters = append(filters, db.SqlValue{Name: "status", Value: ActivationActive})
+	if err := db.Update(logContext, txContext, tx, "account_activation", data, filters); err != nil {
+		logger.Error().Err(err).Msgf("[UseUserActivations] Cannot update activations of user %d: %s", activation.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// PostgresActivationStore ActivationStore on table account_activation
+type PostgresActivationStore struct{}
+
+// NewPostgresActivationStore creates an ActivationStore on the database, only used on transactions of PostgresTransactor
+func NewPostgresActivationStore() *PostgresActivationStore {
+	return &PostgresActivationStore{}
+}
+
+// InsertActivation stores a new activation token
+func (store *PostgresActivationStore) InsertActivation(logContext *u.LoggerContext, tx Transaction, activation *AccountActivation) error {
+	txContext, pgTx := PostgresTx(tx)
+	return activation.InsertActivation(logContext, txContext, pgTx)
+}
+
+// GetActivationByHash gets and locks the activation token with the hash of activation
+func (store *PostgresActivationStore) GetActivationByHash(logContext *u.LoggerContext, tx Transaction, activation *AccountActivation) (*AccountActivation, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return activation.GetActivationByHash(logContext, txContext, pgTx)
+}
+
+// UseUserActivations marks every active activation token of the user of activation as used
+func (store *PostgresActivationStore) UseUserActivations(logContext *u.LoggerContext, tx Transaction, activation *AccountActivation) error {
+	txContext, pgTx := PostgresTx(tx)
+	return activation.UseUserActivations(logContext, txContext, pgTx)
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+
+// Account activation status values
+const (
+	ActivationActive = "active"
+	ActivationUsed   = "used"
+)
+
+// ActivationStore keeps the activation tokens of registered users, on table account_activation
+// (PostgresActivationStore) or in memory (MemoryActivationStore). Writes take the transaction of the caller
+type ActivationStore interface {
+	// InsertActivation stores a new activation token
+	InsertActivation(logContext *u.LoggerContext, tx Transaction, activation *AccountActivation) error
+	// GetActivationByHash gets the activation token with the hash of activation, locking it until the end of the transaction.
+	// nil if not found
+	GetActivationByHash(logContext *u.LoggerContext, tx Transaction, activation *AccountActivation) (*AccountActivation, error)
+	// UseUserActivations marks every active activation token of the user of activation as used
+	UseUserActivations(logContext *u.LoggerContext, tx Transaction, activation *AccountActivation) error
+}
+
+// AccountActivation table account_activation on database, the token that activates a registered user
+type AccountActivation struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	UserID     int    `json:"user_id,omitempty" db:"user_id,omitempty"`
+	TokenHash  string `json:"-" db:"token_hash,omitempty"`
+	Status     string `json:"status,omitempty" db:"status,omitempty"`
+	Tstampinit int64  `json:"tstampinit,omitempty" db:"tstampinit,omitempty"`
+	Tstampexp  int64  `json:"tstampexp,omitempty" db:"tstampexp,omitempty"`
+}
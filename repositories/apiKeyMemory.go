@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"sort"
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryApiKeyStore ApiKeyStore kept in memory, for tests, with transactions of MemoryTransactor. Writes are applied
+// at once and undone on rollback
+type MemoryApiKeyStore struct {
+	sync.Mutex
+	keys   map[int]ApiKey
+	lastID int
+}
+
+// NewMemoryApiKeyStore creates an empty MemoryApiKeyStore
+func NewMemoryApiKeyStore() *MemoryApiKeyStore {
+	return &MemoryApiKeyStore{keys: map[int]ApiKey{}}
+}
+
+// InsertApiKey stores a new API key, returning it with its id
+func (store *MemoryApiKeyStore) InsertApiKey(logContext *u.LoggerContext, tx Transaction, key *ApiKey) (*ApiKey, error) {
+	store.Lock()
+	defer store.Unlock()
+	store.lastID++
+	created := *key
+	created.ID = store.lastID
+	created.Key = ""
+	keepEntry(tx, store, store.keys, created.ID)
+	store.keys[created.ID] = created
+	return &created, nil
+}
+
+// ListApiKeys lists the API keys of the user of key, by id
+func (store *MemoryApiKeyStore) ListApiKeys(logContext *u.LoggerContext, key *ApiKey) ([]ApiKey, error) {
+	store.Lock()
+	defer store.Unlock()
+	var list []ApiKey
+	for _, v := range store.keys {
+		if v.UserID == key.UserID {
+			list = append(list, v)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// GetApiKeyByHash gets the API key with the hash of key
+func (store *MemoryApiKeyStore) GetApiKeyByHash(logContext *u.LoggerContext, key *ApiKey) (*ApiKey, error) {
+	store.Lock()
+	defer store.Unlock()
+	for _, v := range store.keys {
+		if v.KeyHash == key.KeyHash {
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetApiKeyForUpdate gets the API key of the id and user of key, nothing is locked
+func (store *MemoryApiKeyStore) GetApiKeyForUpdate(logContext *u.LoggerContext, tx Transaction, key *ApiKey) (*ApiKey, error) {
+	store.Lock()
+	defer store.Unlock()
+	if found, ok := store.keys[key.ID]; ok && found.UserID == key.UserID {
+		return &found, nil
+	}
+	return nil, nil
+}
+
+// RevokeApiKey revokes the API key of the id and user of key, revoking a missing key changes nothing
+func (store *MemoryApiKeyStore) RevokeApiKey(logContext *u.LoggerContext, tx Transaction, key *ApiKey) error {
+	store.Lock()
+	defer store.Unlock()
+	if found, ok := store.keys[key.ID]; ok && found.UserID == key.UserID {
+		keepEntry(tx, store, store.keys, key.ID)
+		found.Status = ApiKeyRevoked
+		store.keys[key.ID] = found
+	}
+	return nil
+}
+
+// RevokeUserApiKeys revokes every active API key of the user of key
+func (store *MemoryApiKeyStore) RevokeUserApiKeys(logContext *u.LoggerContext, tx Transaction, key *ApiKey) error {
+	store.Lock()
+	defer store.Unlock()
+	for id, v := range store.keys {
+		if v.UserID == key.UserID && v.Status == ApiKeyActive {
+			keepEntry(tx, store, store.keys, id)
+			v.Status = ApiKeyRevoked
+			store.keys[id] = v
+		}
+	}
+	return nil
+}
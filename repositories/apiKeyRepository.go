@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// InsertApiKey Stores a new API key
+func (key *ApiKey) InsertApiKey(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*ApiKey, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "user_id", Value: key.UserID})
+	data = append(data, db.SqlValue{Name: "name", Value: key.Name})
+	data = append(data, db.SqlValue{Name: "prefix", Value: key.Prefix})
+	data = append(data, db.SqlValue{Name: "key_hash", Value: key.KeyHash})
+	data = append(data, db.SqlValue{Name: "scopes", Value: key.Scopes})
+	data = append(data, db.SqlValue{Name: "status", Value: key.Status})
+	data = append(data, db.SqlValue{Name: "tstampinit", Value: key.Tstampinit})
+	data = append(data, db.SqlValue{Name: "tstampexp", Value: key.Tstampexp})
+	res, err := db.InsertReturningPostgres[ApiKey](logContext, txContext, tx, "api_key", data, "id")
+	if err != nil {
+		logger.Error().Err(err).Msgf("[InsertApiKey] Cannot insert API key for user %d: %s", key.UserID, err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListApiKeys Lists the API keys of an user
+func (key *ApiKey) ListApiKeys(logContext *u.LoggerContext, dbContext *db.DatabaseContext) ([]ApiKey, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "user_id", Value: key.UserID})
+	query := `
+		select id, user_id, name, prefix, key_hash, scopes, status, tstampinit, tstampexp from api_key
+		where user_id = $1
+		order by id
+	`
+	val, err := db.SelectAll[ApiKey](logContext, dbContext, nil, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ListApiKeys] Error listing API keys: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// GetApiKeyByHash Retrieves an API key by its hash
+func (key *ApiKey) GetApiKeyByHash(logContext *u.LoggerContext, dbContext *db.DatabaseContext) (*ApiKey, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "key_hash", Value: key.KeyHash})
+	query := `
+		select id, user_id, name, prefix, key_hash, scopes, status, tstampinit, tstampexp from api_key
+		where key_hash = $1
+	`
+	val, err := db.SelectOne[ApiKey](logContext, dbContext, nil, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetApiKeyByHash] Error retrieving API key: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// GetApiKeyForUpdate Retrieves an API key of an user by id, locking it until the end of the transaction. nil if not found
+func (key *ApiKey) GetApiKeyForUpdate(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*ApiKey, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "id", Value: key.ID})
+	data = append(data, db.SqlValue{Name: "user_id", Value: key.UserID})
+	query := `
+		select id, user_id, name, prefix, key_hash, scopes, status, tstampinit, tstampexp from api_key
+		where id = $1 and user_id = $2
+		for update
+	`
+	val, err := db.SelectOne[ApiKey](logContext, txContext, tx, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetApiKeyForUpdate] Error retrieving API key: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// RevokeApiKey Revokes an API key of an user
+func (key *ApiKey) RevokeApiKey(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "status", Value: ApiKeyRevoked})
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "id", Value: key.ID})
+	filter = append(filter, db.SqlValue{Name: "user_id", Value: key.UserID})
+	if err := db.Update(logContext, txContext, tx, "api_key", data, filter); err != nil {
+		logger.Error().Err(err).Msgf("[RevokeApiKey] Cannot revoke API key %d: %s", key.ID, err)
+		return err
+	}
+	return nil
+}
+
+// RevokeUserApiKeys Revokes every active API key of an user
+func (key *ApiKey) RevokeUserApiKeys(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "status", Value: ApiKeyRevoked})
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "user_id", Value: key.UserID})
+	filter = append(filter, db.SqlValue{Name: "status", Value: ApiKeyActive})
+	if err := db.Update(logContext, txContext, tx, "api_key", data, filter); err != nil {
+		logger.Error().Err(err).Msgf("[RevokeUserApiKeys] Cannot revoke API keys of user %d: %s", key.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// PostgresApiKeyStore ApiKeyStore on table api_key
+type PostgresApiKeyStore struct {
+	database *db.DB
+}
+
+// NewPostgresApiKeyStore creates an ApiKeyStore on the database
+func NewPostgresApiKeyStore(database *db.DB) *PostgresApiKeyStore {
+	return &PostgresApiKeyStore{database: database}
+}
+
+// InsertApiKey stores a new API key, returning it with its id
+func (store *PostgresApiKeyStore) InsertApiKey(logContext *u.LoggerContext, tx Transaction, key *ApiKey) (*ApiKey, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return key.InsertApiKey(logContext, txContext, pgTx)
+}
+
+// ListApiKeys lists the API keys of the user of key, by id
+func (store *PostgresApiKeyStore) ListApiKeys(logContext *u.LoggerContext, key *ApiKey) ([]ApiKey, error) {
+	return key.ListApiKeys(logContext, store.database.Context(*logContext))
+}
+
+// GetApiKeyByHash gets the API key with the hash of key
+func (store *PostgresApiKeyStore) GetApiKeyByHash(logContext *u.LoggerContext, key *ApiKey) (*ApiKey, error) {
+	return key.GetApiKeyByHash(logContext, store.database.Context(*logContext))
+}
+
+// GetApiKeyForUpdate gets and locks the API key of the id and user of key
+func (store *PostgresApiKeyStore) GetApiKeyForUpdate(logContext *u.LoggerContext, tx Transaction, key *ApiKey) (*ApiKey, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return key.GetApiKeyForUpdate(logContext, txContext, pgTx)
+}
+
+// RevokeApiKey revokes the API key of the id and user of key
+func (store *PostgresApiKeyStore) RevokeApiKey(logContext *u.LoggerContext, tx Transaction, key *ApiKey) error {
+	txContext, pgTx := PostgresTx(tx)
+	return key.RevokeApiKey(logContext, txContext, pgTx)
+}
+
+// RevokeUserApiKeys revokes every active API key of the user of key
+func (store *PostgresApiKeyStore) RevokeUserApiKeys(logContext *u.LoggerContext, tx Transaction, key *ApiKey) error {
+	txContext, pgTx := PostgresTx(tx)
+	return key.RevokeUserApiKeys(logContext, txContext, pgTx)
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+
+// API key status values
+const (
+	ApiKeyActive  = "active"
+	ApiKeyRevoked = "revoked"
+)
+
+// ApiKeyStore keeps the API keys of users, on table api_key (PostgresApiKeyStore) or in memory (MemoryApiKeyStore).
+// Writes take the transaction of the caller
+type ApiKeyStore interface {
+	// InsertApiKey stores a new API key, returning it with its id
+	InsertApiKey(logContext *u.LoggerContext, tx Transaction, key *ApiKey) (*ApiKey, error)
+	// ListApiKeys lists the API keys of the user of key, by id
+	ListApiKeys(logContext *u.LoggerContext, key *ApiKey) ([]ApiKey, error)
+	// GetApiKeyByHash gets the API key with the hash of key. nil if not found
+	GetApiKeyByHash(logContext *u.LoggerContext, key *ApiKey) (*ApiKey, error)
+	// GetApiKeyForUpdate gets the API key of the id and user of key, locking it in the transaction. nil if not found
+	GetApiKeyForUpdate(logContext *u.LoggerContext, tx Transaction, key *ApiKey) (*ApiKey, error)
+	// RevokeApiKey revokes the API key of the id and user of key
+	RevokeApiKey(logContext *u.LoggerContext, tx Transaction, key *ApiKey) error
+	// RevokeUserApiKeys revokes every active API key of the user of key
+	RevokeUserApiKeys(logContext *u.LoggerContext, tx Transaction, key *ApiKey) error
+}
+
+// ApiKey table api_key on database. Scopes are space separated permissions, Tstampexp is zero for keys that never expire
+type ApiKey struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	UserID     int    `json:"user_id,omitempty" db:"user_id,omitempty"`
+	Name       string `json:"name,omitempty" db:"name,omitempty"`
+	Prefix     string `json:"prefix,omitempty" db:"prefix,omitempty"`
+	KeyHash    string `json:"-" db:"key_hash,omitempty"`
+	Scopes     string `json:"scopes,omitempty" db:"scopes,omitempty"`
+	Status     string `json:"status,omitempty" db:"status,omitempty"`
+	Tstampinit int64  `json:"tstampinit,omitempty" db:"tstampinit,omitempty"`
+	Tstampexp  int64  `json:"tstampexp,omitempty" db:"tstampexp"`
+	Key        string `json:"key,omitempty" db:"-"`
+}
+
+// ApiKeyRequest body to create an API key, ExpiresInDays zero creates a key that never expires
+type ApiKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"`
+}
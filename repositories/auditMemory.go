@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryAuditStore AuditStore kept in memory, for tests, with transactions of MemoryTransactor. Entries are added at
+// once and removed on rollback
+type MemoryAuditStore struct {
+	sync.Mutex
+	entries map[int]AuditEntry
+	lastID  int
+}
+
+// NewMemoryAuditStore creates an empty MemoryAuditStore
+func NewMemoryAuditStore() *MemoryAuditStore {
+	return &MemoryAuditStore{entries: map[int]AuditEntry{}}
+}
+
+// InsertAuditEntry stores an audit entry, with the tenant of the transaction or the default one when it has none
+func (store *MemoryAuditStore) InsertAuditEntry(logContext *u.LoggerContext, tx Transaction, entry *AuditEntry) error {
+	store.Lock()
+	defer store.Unlock()
+	store.lastID++
+	created := *entry
+	created.ID = store.lastID
+	if created.TenantID == 0 {
+		created.TenantID = TenantOrDefault(tenantOf(tx))
+	}
+	keepEntry(tx, store, store.entries, created.ID)
+	store.entries[created.ID] = created
+	return nil
+}
+
+// ListAuditEntries lists the entries matching the filter, newest first, only of the tenant of the filter when it has one
+func (store *MemoryAuditStore) ListAuditEntries(logContext *u.LoggerContext, filter *AuditFilter) ([]AuditEntry, error) {
+	store.Lock()
+	defer store.Unlock()
+	var list []AuditEntry
+	for id := store.lastID; id > 0 && (filter.Limit <= 0 || len(list) < filter.Limit); id-- {
+		v, ok := store.entries[id]
+		if !ok ||
+			(filter.TenantID != 0 && v.TenantID != filter.TenantID) ||
+			(filter.ActorID != 0 && v.ActorID != filter.ActorID) ||
+			(filter.Action != "" && v.Action != filter.Action) ||
+			(filter.From != 0 && v.Tstamp < filter.From) ||
+			(filter.To != 0 && v.Tstamp > filter.To) {
+			continue
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
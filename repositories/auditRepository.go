@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"strconv"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// InsertAuditEntry Stores an audit entry, inside the transaction of the audited change
+func (entry *AuditEntry) InsertAuditEntry(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "tstamp", Value: entry.Tstamp})
+	data = append(data, db.SqlValue{Name: "actor_id", Value: entry.ActorID})
+	data = append(data, db.SqlValue{Name: "impersonator_id", Value: entry.ImpersonatorID})
+	data = append(data, db.SqlValue{Name: "client_id", Value: entry.ClientID})
+	data = append(data, db.SqlValue{Name: "action", Value: entry.Action})
+	data = append(data, db.SqlValue{Name: "target", Value: entry.Target})
+	data = append(data, db.SqlValue{Name: "before_data", Value: jsonOrNil(entry.Before)})
+	data = append(data, db.SqlValue{Name: "after_data", Value: jsonOrNil(entry.After)})
+	data = append(data, db.SqlValue{Name: "ip", Value: entry.IP})
+	data = append(data, db.SqlValue{Name: "request_id", Value: entry.RequestID})
+	if entry.TenantID != 0 { //Without a tenant the column default takes the tenant of the transaction
+		data = append(data, db.SqlValue{Name: "tenant_id", Value: entry.TenantID})
+	}
+	if err := db.Insert(logContext, txContext, tx, "audit_log", data); err != nil {
+		logger.Error().Err(err).Msgf("[InsertAuditEntry] Cannot insert audit entry %s on %s: %s", entry.Action, entry.Target, err)
+		return err
+	}
+	return nil
+}
+
+// ListAuditEntries Lists audit entries matching the filter, newest first, only of the tenant of the filter when it has one
+func (entry *AuditEntry) ListAuditEntries(logContext *u.LoggerContext, dbContext *db.DatabaseContext, filter *AuditFilter) ([]AuditEntry, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	query := `
+		select id, tstamp, actor_id, impersonator_id, client_id, action, target,
+			coalesce(before_data::text, 'null') as before_data, coalesce(after_data::text, 'null') as after_data, ip, request_id, tenant_id
+		from audit_log
+		where 1=1`
+	if filter.ActorID != 0 {
+		data = append(data, db.SqlValue{Name: "actor_id", Value: filter.ActorID})
+		query += " and actor_id = $" + strconv.Itoa(len(data))
+	}
+	if filter.Action != "" {
+		data = append(data, db.SqlValue{Name: "action", Value: filter.Action})
+		query += " and action = $" + strconv.Itoa(len(data))
+	}
+	if filter.From != 0 {
+		data = append(data, db.SqlValue{Name: "tstamp", Value: filter.From})
+		query += " and tstamp >= $" + strconv.Itoa(len(data))
+	}
+	if filter.To != 0 {
+		data = append(data, db.SqlValue{Name: "tstamp", Value: filter.To})
+		query += " and tstamp <= $" + strconv.Itoa(len(data))
+	}
+	data = append(data, db.SqlValue{Name: "limit", Value: filter.Limit})
+	query += " order by id desc limit $" + strconv.Itoa(len(data))
+	val, err := db.SelectAll[AuditEntry](logContext, db.TenantContext(dbContext, filter.TenantID), nil, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ListAuditEntries] Error listing audit entries: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// jsonOrNil Stores empty snapshots as NULL
+func jsonOrNil(value []byte) any {
+	if len(value) == 0 {
+		return nil
+	}
+	return value
+}
+
+// PostgresAuditStore AuditStore on table audit_log
+type PostgresAuditStore struct {
+	database *db.DB
+}
+
+// NewPostgresAuditStore creates an AuditStore on the database
+func NewPostgresAuditStore(database *db.DB) *PostgresAuditStore {
+	return &PostgresAuditStore{database: database}
+}
+
+// InsertAuditEntry stores an audit entry on the transaction
+func (store *PostgresAuditStore) InsertAuditEntry(logContext *u.LoggerContext, tx Transaction, entry *AuditEntry) error {
+	txContext, pgTx := PostgresTx(tx)
+	return entry.InsertAuditEntry(logContext, txContext, pgTx)
+}
+
+// ListAuditEntries lists the entries matching the filter, newest first
+func (store *PostgresAuditStore) ListAuditEntries(logContext *u.LoggerContext, filter *AuditFilter) ([]AuditEntry, error) {
+	return (&AuditEntry{}).ListAuditEntries(logContext, store.database.Context(*logContext), filter)
+}
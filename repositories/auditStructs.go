@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"encoding/json"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// AuditStore keeps the audit log, on table audit_log (PostgresAuditStore) or in memory (MemoryAuditStore). Entries are
+// written on the transaction of the audited change, so both commit or roll back together
+type AuditStore interface {
+	// InsertAuditEntry stores an audit entry. Without a tenant, the entry gets the tenant of the transaction
+	InsertAuditEntry(logContext *u.LoggerContext, tx Transaction, entry *AuditEntry) error
+	// ListAuditEntries lists the entries matching the filter, newest first, only of the tenant of the filter when it has one
+	ListAuditEntries(logContext *u.LoggerContext, filter *AuditFilter) ([]AuditEntry, error)
+}
+
+// AuditCaller who made a change and from where, taken from the request
+type AuditCaller struct {
+	ActorID        int
+	TenantID       int
+	ImpersonatorID int
+	ClientID       string
+	IP             string
+	RequestID      string
+}
+
+// AuditEntry table audit_log on database, Before and After are JSON snapshots of the target
+type AuditEntry struct {
+	ID             int             `json:"id,omitempty" db:"id,omitempty"`
+	Tstamp         int64           `json:"tstamp,omitempty" db:"tstamp,omitempty"`
+	ActorID        int             `json:"actor_id,omitempty" db:"actor_id,omitempty"`
+	ImpersonatorID int             `json:"impersonator_id,omitempty" db:"impersonator_id,omitempty"`
+	ClientID       string          `json:"client_id,omitempty" db:"client_id,omitempty"`
+	Action         string          `json:"action,omitempty" db:"action,omitempty"`
+	Target         string          `json:"target,omitempty" db:"target,omitempty"`
+	Before         json.RawMessage `json:"before,omitempty" db:"before_data,omitempty"`
+	After          json.RawMessage `json:"after,omitempty" db:"after_data,omitempty"`
+	IP             string          `json:"ip,omitempty" db:"ip,omitempty"`
+	RequestID      string          `json:"request_id,omitempty" db:"request_id,omitempty"`
+	TenantID       int             `json:"tenant_id,omitempty" db:"tenant_id,omitempty"`
+}
+
+// AuditFilter filters of the audit query, zero values are ignored. From and To are unix timestamps, inclusive
+type AuditFilter struct {
+	TenantID int
+	ActorID  int
+	Action   string
+	From     int64
+	To       int64
+	Limit    int
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryEmailChangeStore EmailChangeStore kept in memory, for tests, with transactions of MemoryTransactor. Writes are
+// applied at once and undone on rollback
+type MemoryEmailChangeStore struct {
+	sync.Mutex
+	changes map[int]EmailChange
+	lastID  int
+}
+
+// NewMemoryEmailChangeStore creates an empty MemoryEmailChangeStore
+func NewMemoryEmailChangeStore() *MemoryEmailChangeStore {
+	return &MemoryEmailChangeStore{changes: map[int]EmailChange{}}
+}
+
+// InsertEmailChange stores a new email change
+func (store *MemoryEmailChangeStore) InsertEmailChange(logContext *u.LoggerContext, tx Transaction, change *EmailChange) error {
+	store.Lock()
+	defer store.Unlock()
+	store.lastID++
+	created := *change
+	created.ID = store.lastID
+	keepEntry(tx, store, store.changes, created.ID)
+	store.changes[created.ID] = created
+	return nil
+}
+
+// GetEmailChangeByHash gets the email change with the hash of change, nothing is locked
+func (store *MemoryEmailChangeStore) GetEmailChangeByHash(logContext *u.LoggerContext, tx Transaction, change *EmailChange) (*EmailChange, error) {
+	store.Lock()
+	defer store.Unlock()
+	for _, v := range store.changes {
+		if v.TokenHash == change.TokenHash {
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+// UseUserEmailChanges marks every active email change of the user of change as used
+func (store *MemoryEmailChangeStore) UseUserEmailChanges(logContext *u.LoggerContext, tx Transaction, change *EmailChange) error {
+	store.Lock()
+	defer store.Unlock()
+	for id, v := range store.changes {
+		if v.UserID == change.UserID && v.Status == EmailChangeActive {
+			keepEntry(tx, store, store.changes, id)
+			v.Status = EmailChangeUsed
+			store.changes[id] = v
+		}
+	}
+	return nil
+}
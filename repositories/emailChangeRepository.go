@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// InsertEmailChange Stores a new email change request
+func (change *EmailChange) InsertEmailChange(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "user_id", Value: change.UserID})
+	data = append(data, db.SqlValue{Name: "email", Value: change.Email})
+	data = append(data, db.SqlValue{Name: "token_hash", Value: change.TokenHash})
+	data = append(data, db.SqlValue{Name: "status", Value: change.Status})
+	data = append(data, db.SqlValue{Name: "tstampinit", Value: change.Tstampinit})
+	data = append(data, db.SqlValue{Name: "tstampexp", Value: change.Tstampexp})
+	if err := db.Insert(logContext, txContext, tx, "email_change", data); err != nil {
+		logger.Error().Err(err).Msgf("[InsertEmailChange] Cannot insert email change for user %d: %s", change.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// GetEmailChangeByHash Retrieves and locks an email change by the hash of its token
+func (change *EmailChange) GetEmailChangeByHash(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*EmailChange, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "token_hash", Value: change.TokenHash})
+	query := `
+		select id, user_id, email, token_hash, status, tstampinit, tstampexp from email_change
+		where token_hash = $1
+		for update
+	`
+	val, err := db.SelectOne[EmailChange](logContext, txContext, tx, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetEmailChangeByHash] Error retrieving email change: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// UseUserEmailChanges Marks every active email change of the user as used
+func (change *EmailChange) UseUserEmailChanges(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "status", Value: EmailChangeUsed})
+	var filters db.SqlData
+	filters = append(filters, db.SqlValue{Name: "user_id", Value: change.UserID})
+	filters = append(filters, db.SqlValue{Name: "status", Value: EmailChangeActive})
+	if err := db.Update(logContext, txContext, tx, "email_change", data, filters); err != nil {
+		logger.Error().Err(err).Msgf("[UseUserEmailChanges] Cannot update email changes of user %d: %s", change.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// PostgresEmailChangeStore EmailChangeStore on table email_change
+type PostgresEmailChangeStore struct{}
+
+// NewPostgresEmailChangeStore creates an EmailChangeStore on the database, only used on transactions of PostgresTransactor
+func NewPostgresEmailChangeStore() *PostgresEmailChangeStore {
+	return &PostgresEmailChangeStore{}
+}
+
+// InsertEmailChange stores a new email change
+func (store *PostgresEmailChangeStore) InsertEmailChange(logContext *u.LoggerContext, tx Transaction, change *EmailChange) error {
+	txContext, pgTx := PostgresTx(tx)
+	return change.InsertEmailChange(logContext, txContext, pgTx)
+}
+
+// GetEmailChangeByHash gets and locks the email change with the hash of change
+func (store *PostgresEmailChangeStore) GetEmailChangeByHash(logContext *u.LoggerContext, tx Transaction, change *EmailChange) (*EmailChange, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return change.GetEmailChangeByHash(logContext, txContext, pgTx)
+}
+
+// UseUserEmailChanges marks every active email change of the user of change as used
+func (store *PostgresEmailChangeStore) UseUserEmailChanges(logContext *u.LoggerContext, tx Transaction, change *EmailChange) error {
+	txContext, pgTx := PostgresTx(tx)
+	return change.UseUserEmailChanges(logContext, txContext, pgTx)
+}
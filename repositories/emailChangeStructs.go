@@ -0,0 +1,39 @@
+package repositories
+
+import u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+
+// Email change status values
+const (
+	EmailChangeActive = "active"
+	EmailChangeUsed   = "used"
+)
+
+// EmailChangeStore keeps the email changes waiting for the token sent to the new email, on table email_change
+// (PostgresEmailChangeStore) or in memory (MemoryEmailChangeStore). Writes take the transaction of the caller
+type EmailChangeStore interface {
+	// InsertEmailChange stores a new email change
+	InsertEmailChange(logContext *u.LoggerContext, tx Transaction, change *EmailChange) error
+	// GetEmailChangeByHash gets the email change with the hash of change, locking it until the end of the transaction.
+	// nil if not found
+	GetEmailChangeByHash(logContext *u.LoggerContext, tx Transaction, change *EmailChange) (*EmailChange, error)
+	// UseUserEmailChanges marks every active email change of the user of change as used
+	UseUserEmailChanges(logContext *u.LoggerContext, tx Transaction, change *EmailChange) error
+}
+
+// EmailChange table email_change on database, a new email waiting for the token sent to it
+type EmailChange struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	UserID     int    `json:"user_id,omitempty" db:"user_id,omitempty"`
+	Email      string `json:"email,omitempty" db:"email,omitempty"`
+	TokenHash  string `json:"-" db:"token_hash,omitempty"`
+	Status     string `json:"status,omitempty" db:"status,omitempty"`
+	Tstampinit int64  `json:"tstampinit,omitempty" db:"tstampinit,omitempty"`
+	Tstampexp  int64  `json:"tstampexp,omitempty" db:"tstampexp,omitempty"`
+}
+
+// EmailChangeRequest body of /api/me/email (Email and Password) and /api/me/email/verify (Token)
+type EmailChangeRequest struct {
+	Email    string `json:"email,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
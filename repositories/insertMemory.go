@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryBatchStore BatchStore kept in memory, for tests, with transactions of MemoryTransactor. Writes are applied at
+// once and undone on rollback. A tenant transaction only sees and changes batches of its tenant, as row level security does
+type MemoryBatchStore struct {
+	sync.Mutex
+	inserts    map[int]Insert
+	items      map[int][]InsertBatch //batch id -> items
+	lastID     int
+	lastItemID int
+}
+
+// NewMemoryBatchStore creates an empty MemoryBatchStore
+func NewMemoryBatchStore() *MemoryBatchStore {
+	return &MemoryBatchStore{inserts: map[int]Insert{}, items: map[int][]InsertBatch{}}
+}
+
+// ListInserts gets one batch by ID with its items, only inside the tenant of insert when it has one
+func (store *MemoryBatchStore) ListInserts(logContext *utils.LoggerContext, insert *Insert) (*Insert, error) {
+	store.Lock()
+	defer store.Unlock()
+	found, ok := store.inserts[insert.ID]
+	if !ok || (insert.TenantID != 0 && found.TenantID != insert.TenantID) {
+		return nil, nil
+	}
+	found.ListVals = append([]InsertBatch(nil), store.items[found.ID]...)
+	return &found, nil
+}
+
+// InsertID creates a Running batch
+func (store *MemoryBatchStore) InsertID(logContext *utils.LoggerContext, tx Transaction, insert *Insert) (*Insert, error) {
+	store.Lock()
+	defer store.Unlock()
+	store.lastID++
+	created := Insert{
+		ID:         store.lastID,
+		Type:       insert.Type,
+		Quantity:   insert.Quantity,
+		Status:     "Running",
+		Tstampinit: time.Now().Unix(),
+		TenantID:   TenantOrDefault(tenantOf(tx)),
+	}
+	keepEntry(tx, store, store.inserts, created.ID)
+	store.inserts[created.ID] = created
+	return &created, nil
+}
+
+// UpdateInsertID sets the status and end of a batch
+func (store *MemoryBatchStore) UpdateInsertID(logContext *utils.LoggerContext, tx Transaction, insert *Insert) (int64, error) {
+	store.Lock()
+	defer store.Unlock()
+	tstamp := time.Now().Unix()
+	if found, ok := store.inserts[insert.ID]; ok && store.visible(tx, found) {
+		found.Status = insert.Status
+		found.Tstampend = &tstamp
+		keepEntry(tx, store, store.inserts, found.ID)
+		store.inserts[found.ID] = found
+	}
+	return tstamp, nil
+}
+
+// InsertOneBatch adds one item to a batch
+func (store *MemoryBatchStore) InsertOneBatch(logContext *utils.LoggerContext, tx Transaction, item *InsertBatch) error {
+	store.Lock()
+	defer store.Unlock()
+	store.lastItemID++
+	keepEntry(tx, store, store.items, item.ID_Ins_ID)
+	store.items[item.ID_Ins_ID] = append(store.items[item.ID_Ins_ID], InsertBatch{ID: store.lastItemID, ID_Ins_ID: item.ID_Ins_ID, Pos: item.Pos})
+	return nil
+}
+
+// CountBatches counts the batches visible on the transaction
+func (store *MemoryBatchStore) CountBatches(logContext *utils.LoggerContext, tx Transaction, insert *Insert) (int, error) {
+	store.Lock()
+	defer store.Unlock()
+	count := 0
+	for _, v := range store.inserts {
+		if store.visible(tx, v) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ClearBatches removes the batches visible on the transaction and their items
+func (store *MemoryBatchStore) ClearBatches(logContext *utils.LoggerContext, tx Transaction, insert *Insert) error {
+	store.Lock()
+	defer store.Unlock()
+	for id, v := range store.inserts {
+		if store.visible(tx, v) {
+			keepEntry(tx, store, store.inserts, id)
+			keepEntry(tx, store, store.items, id)
+			delete(store.inserts, id)
+			delete(store.items, id)
+		}
+	}
+	return nil
+}
+
+// visible a tenant transaction only sees batches of its tenant
+func (store *MemoryBatchStore) visible(tx Transaction, insert Insert) bool {
+	tenantID := tenantOf(tx)
+	return tenantID == 0 || insert.TenantID == tenantID
+}
@@ -8,41 +8,32 @@ import (
 	"time"
 )
 
-// ListInserts Retrieve one batch of inserts by id
-func (insert *Insert) ListInserts(logContext *utils.LoggerContext) (*Insert, error) {
+// inserts Rows of ins_id, batches of items
+var inserts = db.NewRepository[Insert]("ins_id", "id")
+
+// insertBatches Rows of insert_batch, items of a batch
+var insertBatches = db.NewRepository[InsertBatch]("insert_batch", "id")
+
+// ListInserts Retrieve one batch of inserts by id, only inside the tenant of insert when it has one
+func (insert *Insert) ListInserts(logContext *utils.LoggerContext, dbContext *db.DatabaseContext) (*Insert, error) {
 	logger := zerolog.Ctx(*logContext)
-	var data db.SqlData
-	paramsValue := db.SqlValue{Name: "id", Value: insert.ID}
-	data = append(data, paramsValue)
-	dbContext := db.GetDBContext()
-	query := `
-		select id, type, quantity, status, tstampinit, coalesce(tstampend,0) as tstampend from ins_id
-		where id = $1
-    `
-	insert, err := db.SelectOne[Insert](logContext, dbContext, nil, query, data)
+	dbContext = db.TenantContext(dbContext, insert.TenantID)
+	insert, err := inserts.Get(logContext, dbContext, nil, insert.ID)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[ListInserts] Error listing inserts: %s", err)
 		return nil, err
 	}
-	queryInsertBatch := `
-		select id, id_ins_id, pos from insert_batch
-		where id_ins_id = $1
-	`
 	if insert != nil {
-		var data db.SqlData
-		paramsValue := db.SqlValue{Name: "id_ins_id", Value: insert.ID}
-		data = append(data, paramsValue)
-
-		val2, err := db.SelectAll[InsertBatch](logContext, dbContext, nil, queryInsertBatch, data)
+		if insert.Tstampend == nil { //Running batches end at 0
+			insert.Tstampend = new(int64)
+		}
+		filters := db.SqlData{db.Filter("id_ins_id", db.Eq, insert.ID)}
+		val2, err := insertBatches.List(logContext, dbContext, nil, db.ListOptions{Filters: filters, Sort: []db.Sort{{Column: "pos"}}})
 		if err != nil {
 			logger.Error().Err(err).Msgf("[ListInserts] Cannot find children: %s", err)
 			return insert, nil
 		}
-		var result []InsertBatch
-		for _, v := range val2 {
-			result = append(result, v)
-		}
-		insert.ListVals = result
+		insert.ListVals = val2
 	}
 	return insert, nil
 }
@@ -50,14 +41,7 @@ func (insert *Insert) ListInserts(logContext *utils.LoggerContext) (*Insert, err
 // InsertOneBatch Inserts one item of the batch
 func (insert *InsertBatch) InsertOneBatch(logContext *utils.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
 	logger := zerolog.Ctx(*logContext)
-
-	var data db.SqlData
-	paramsValue := db.SqlValue{Name: "id_ins_id", Value: insert.ID_Ins_ID}
-	data = append(data, paramsValue)
-	paramsValue2 := db.SqlValue{Name: "pos", Value: insert.Pos}
-	data = append(data, paramsValue2)
-
-	err := db.Insert(logContext, txContext, tx, "insert_batch", data)
+	_, err := insertBatches.Create(logContext, txContext, tx, &InsertBatch{ID_Ins_ID: insert.ID_Ins_ID, Pos: insert.Pos})
 	if err != nil {
 		logger.Error().Err(err).Msgf("[InsertOneBatch] Cannot insert children for id %d: %s", insert.ID_Ins_ID, err)
 		return err
@@ -68,42 +52,21 @@ func (insert *InsertBatch) InsertOneBatch(logContext *utils.LoggerContext, txCon
 // InsertID Inserts one batch of items
 func (insert *Insert) InsertID(logContext *utils.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*Insert, error) {
 	logger := zerolog.Ctx(*logContext)
-	var data db.SqlData
-	paramsValue := db.SqlValue{Name: "quantity", Value: insert.Quantity}
-	data = append(data, paramsValue)
-	paramsValue2 := db.SqlValue{Name: "status", Value: "Running"}
-	data = append(data, paramsValue2)
-	paramsValue3 := db.SqlValue{Name: "type", Value: insert.Type}
-	data = append(data, paramsValue3)
-	tstamp := time.Now().Unix()
-	paramsValue4 := db.SqlValue{Name: "tstampinit", Value: tstamp}
-	data = append(data, paramsValue4)
-	insertReturn, err := db.InsertReturningPostgres[Insert](logContext, txContext, tx, "ins_id", data, "id")
-	if err != nil || insertReturn == nil {
+	created := &Insert{Type: insert.Type, Quantity: insert.Quantity, Status: "Running", Tstampinit: time.Now().Unix()}
+	insertReturn, err := inserts.Create(logContext, txContext, tx, created)
+	if err != nil {
 		logger.Error().Err(err).Msgf("[InsertID] Cannot insert new batch: %s", err)
 		return nil, err
 	}
-	insertReturn.Quantity = insert.Quantity
-	insertReturn.Status = "Running"
-	insertReturn.Type = insert.Type
-	insertReturn.Tstampinit = tstamp
 	return insertReturn, nil
 }
 
 // UpdateInsertID Finishes batch insertion
 func (insert *Insert) UpdateInsertID(logContext *utils.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (int64, error) {
 	logger := zerolog.Ctx(*logContext)
-	var data db.SqlData
-	paramsValue := db.SqlValue{Name: "status", Value: insert.Status}
-	data = append(data, paramsValue)
 	tstamp := time.Now().Unix()
-	paramsValue2 := db.SqlValue{Name: "tstampend", Value: tstamp}
-	data = append(data, paramsValue2)
-
-	var filters db.SqlData
-	filterValue := db.SqlValue{Name: "id", Value: insert.ID}
-	filters = append(filters, filterValue)
-	err := db.Update(logContext, txContext, tx, "ins_id", data, filters)
+	finished := &Insert{ID: insert.ID, Status: insert.Status, Tstampend: &tstamp}
+	err := inserts.Patch(logContext, txContext, tx, finished, "status", "tstampend")
 	if err != nil {
 		logger.Error().Err(err).Msgf("[UpdateInsertID] Cannot update batch: %s", err)
 		return 0, err
@@ -111,18 +74,76 @@ func (insert *Insert) UpdateInsertID(logContext *utils.LoggerContext, txContext
 	return tstamp, nil
 }
 
-// ClearBatches Removes all batches
+// CountBatches Counts all batches visible on the transaction
+func (insert *Insert) CountBatches(logContext *utils.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (int, error) {
+	logger := zerolog.Ctx(*logContext)
+	count, err := inserts.Count(logContext, txContext, tx, nil)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[CountBatches] Cannot count batches: %s", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// ClearBatches Removes all batches visible on the transaction, only the ones of its tenant on a tenant transaction
 func (insert *Insert) ClearBatches(logContext *utils.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
 	logger := zerolog.Ctx(*logContext)
-	_, err := (*tx).Exec(*txContext, "delete from insert_batch where id > 0")
+	ctx, cancel := db.QueryContext(txContext)
+	defer cancel()
+	_, err := (*tx).Exec(ctx, "delete from insert_batch where id > 0")
 	if err != nil {
 		logger.Error().Err(err).Msgf("[ClearBatches] Cannot remove children: %s", err)
 		return err
 	}
-	_, err2 := (*tx).Exec(*txContext, "delete from ins_id where id > 0")
+	_, err2 := (*tx).Exec(ctx, "delete from ins_id where id > 0")
 	if err2 != nil {
 		logger.Error().Err(err).Msgf("[ClearBatches] Cannot remove batches: %s", err)
 		return err2
 	}
 	return nil
 }
+
+// PostgresBatchStore BatchStore on tables ins_id and insert_batch
+type PostgresBatchStore struct {
+	database *db.DB
+}
+
+// NewPostgresBatchStore creates a BatchStore on the database
+func NewPostgresBatchStore(database *db.DB) *PostgresBatchStore {
+	return &PostgresBatchStore{database: database}
+}
+
+// ListInserts gets one batch by ID with its items, only inside the tenant of insert when it has one
+func (store *PostgresBatchStore) ListInserts(logContext *utils.LoggerContext, insert *Insert) (*Insert, error) {
+	return insert.ListInserts(logContext, store.database.Context(*logContext))
+}
+
+// InsertID creates a Running batch
+func (store *PostgresBatchStore) InsertID(logContext *utils.LoggerContext, tx Transaction, insert *Insert) (*Insert, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return insert.InsertID(logContext, txContext, pgTx)
+}
+
+// UpdateInsertID sets the status and end of a batch
+func (store *PostgresBatchStore) UpdateInsertID(logContext *utils.LoggerContext, tx Transaction, insert *Insert) (int64, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return insert.UpdateInsertID(logContext, txContext, pgTx)
+}
+
+// InsertOneBatch adds one item to a batch
+func (store *PostgresBatchStore) InsertOneBatch(logContext *utils.LoggerContext, tx Transaction, item *InsertBatch) error {
+	txContext, pgTx := PostgresTx(tx)
+	return item.InsertOneBatch(logContext, txContext, pgTx)
+}
+
+// CountBatches counts the batches visible on the transaction
+func (store *PostgresBatchStore) CountBatches(logContext *utils.LoggerContext, tx Transaction, insert *Insert) (int, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return insert.CountBatches(logContext, txContext, pgTx)
+}
+
+// ClearBatches removes the batches visible on the transaction
+func (store *PostgresBatchStore) ClearBatches(logContext *utils.LoggerContext, tx Transaction, insert *Insert) error {
+	txContext, pgTx := PostgresTx(tx)
+	return insert.ClearBatches(logContext, txContext, pgTx)
+}
@@ -1,34 +1,41 @@
 package repositories
 
 import (
-	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
 	"github.com/elnerribeiro/go-ws-db-auth-v2/utils"
-	"github.com/jackc/pgx/v5"
 )
 
 // InsertBatch table insert_batch on database
 type InsertBatch struct {
-	ID        int `json:"id,omitempty" db:"id,omitempty"`
+	ID        int `json:"id,omitempty" db:"id,omitempty,pk"`
 	ID_Ins_ID int `json:"id_ins_id,omitempty" db:"id_ins_id,omitempty"`
-	Pos       int `json:"pos,omitempty" db:"pos,omitempty"`
+	Pos       int `json:"pos,omitempty" db:"pos"`
 }
 
 // Insert table ins_id on database
 type Insert struct {
-	ID         int           `json:"id,omitempty" db:"id,omitempty"`
+	ID         int           `json:"id,omitempty" db:"id,omitempty,pk"`
 	Type       string        `json:"type,omitempty" db:"type,omitempty"`
-	Quantity   int           `json:"quantity,omitempty" db:"quantity,omitempty"`
+	Quantity   int           `json:"quantity,omitempty" db:"quantity"`
 	Status     string        `json:"status,omitempty" db:"status,omitempty"`
 	Tstampinit int64         `json:"tstampinit,omitempty" db:"tstampinit,omitempty"`
 	Tstampend  *int64        `json:"tstampend,omitempty" db:"tstampend,omitempty"`
+	TenantID   int           `json:"tenant_id,omitempty" db:"tenant_id,omitempty,readonly"`
 	ListVals   []InsertBatch `json:"list,omitempty" db:"-"`
 }
 
-// InsertInterface interface for batch insert tables
-type InsertInterface interface {
-	UpdateInsertID(logContext *utils.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (int64, error)
-	ClearBatches(logContext *utils.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error
-	InsertID(logContext *utils.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*Insert, error)
-	InsertOneBatch(logContext *utils.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error
-	ListInserts(logContext *utils.LoggerContext) (*Insert, error)
+// BatchStore keeps the insert batches, on tables ins_id and insert_batch (PostgresBatchStore) or in memory (MemoryBatchStore).
+// Writes take the transaction of the caller, a tenant transaction only sees and changes batches of its tenant
+type BatchStore interface {
+	// ListInserts gets one batch by ID with its items, only inside the tenant of insert when it has one. nil if not found
+	ListInserts(logContext *utils.LoggerContext, insert *Insert) (*Insert, error)
+	// InsertID creates a Running batch of insert.Type and insert.Quantity
+	InsertID(logContext *utils.LoggerContext, tx Transaction, insert *Insert) (*Insert, error)
+	// UpdateInsertID sets the status of a batch and its end timestamp, which is returned
+	UpdateInsertID(logContext *utils.LoggerContext, tx Transaction, insert *Insert) (int64, error)
+	// InsertOneBatch adds one item to a batch
+	InsertOneBatch(logContext *utils.LoggerContext, tx Transaction, item *InsertBatch) error
+	// CountBatches counts the batches visible on the transaction
+	CountBatches(logContext *utils.LoggerContext, tx Transaction, insert *Insert) (int, error)
+	// ClearBatches removes the batches visible on the transaction and their items
+	ClearBatches(logContext *utils.LoggerContext, tx Transaction, insert *Insert) error
 }
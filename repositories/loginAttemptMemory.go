@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryLoginAttemptStore LoginAttemptStore kept in memory, counters are lost on restart and not shared between instances
+type MemoryLoginAttemptStore struct {
+	sync.Mutex
+	attempts     map[string]*LoginAttempt
+	tstamppruned int64
+}
+
+// NewMemoryLoginAttemptStore creates an empty MemoryLoginAttemptStore
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: map[string]*LoginAttempt{}}
+}
+
+// GetAttempt returns the counters of a key, nil if there is none
+func (store *MemoryLoginAttemptStore) GetAttempt(logContext *u.LoggerContext, key string) (*LoginAttempt, error) {
+	store.Lock()
+	defer store.Unlock()
+	if attempt, ok := store.attempts[key]; ok {
+		copied := *attempt
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+// AddFailure counts one more failure at now, restarting the count if the last failure happened
+// before resetBefore and the key is not locked
+func (store *MemoryLoginAttemptStore) AddFailure(logContext *u.LoggerContext, key string, now int64, resetBefore int64) (*LoginAttempt, error) {
+	store.Lock()
+	defer store.Unlock()
+	attempt, ok := store.attempts[key]
+	if !ok {
+		store.prune(resetBefore, now)
+		attempt = &LoginAttempt{Key: key}
+		store.attempts[key] = attempt
+	}
+	if attempt.Tstamplast < resetBefore && attempt.Tstamplocked < now {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.Tstamplast = now
+	copied := *attempt
+	return &copied, nil
+}
+
+// LockUntil locks a key until the given timestamp
+func (store *MemoryLoginAttemptStore) LockUntil(logContext *u.LoggerContext, key string, until int64) error {
+	store.Lock()
+	defer store.Unlock()
+	if attempt, ok := store.attempts[key]; ok {
+		attempt.Tstamplocked = until
+	}
+	return nil
+}
+
+// ResetAttempts removes the counters and lock of a key
+func (store *MemoryLoginAttemptStore) ResetAttempts(logContext *u.LoggerContext, key string) error {
+	store.Lock()
+	defer store.Unlock()
+	delete(store.attempts, key)
+	return nil
+}
+
+// prune drops keys that would be reset anyway, at most once a minute, so the map does not grow forever
+func (store *MemoryLoginAttemptStore) prune(resetBefore int64, now int64) {
+	if now-store.tstamppruned < 60 {
+		return
+	}
+	store.tstamppruned = now
+	for key, attempt := range store.attempts {
+		if attempt.Tstamplast < resetBefore && attempt.Tstamplocked < now {
+			delete(store.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+// PostgresLoginAttemptStore LoginAttemptStore on table login_attempt, shared by every instance
+type PostgresLoginAttemptStore struct {
+	database *db.DB
+}
+
+// NewPostgresLoginAttemptStore creates a LoginAttemptStore on the database
+func NewPostgresLoginAttemptStore(database *db.DB) *PostgresLoginAttemptStore {
+	return &PostgresLoginAttemptStore{database: database}
+}
+
+// GetAttempt returns the counters of a key, nil if there is none
+func (store *PostgresLoginAttemptStore) GetAttempt(logContext *u.LoggerContext, key string) (*LoginAttempt, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "key", Value: key})
+	query := `
+		select key, failures, tstamplast, tstamplocked from login_attempt
+		where key = $1
+	`
+	val, err := db.SelectOne[LoginAttempt](logContext, store.database.Context(*logContext), nil, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetAttempt] Error retrieving login attempts: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// AddFailure counts one more failure at now, restarting the count if the last failure happened
+// before resetBefore and the key is not locked
+func (store *PostgresLoginAttemptStore) AddFailure(logContext *u.LoggerContext, key string, now int64, resetBefore int64) (*LoginAttempt, error) {
+	logger := zerolog.Ctx(*logContext)
+	tx, txContext, err := store.database.GetTransaction(*logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[AddFailure] Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer db.Rollback(logContext, txContext, tx)
+	query := `
+		insert into login_attempt (key, failures, tstamplast, tstamplocked) values ($1, 1, $2, 0)
+		on conflict (key) do update set
+			failures = case when login_attempt.tstamplast < $3 and login_attempt.tstamplocked < $2 then 1 else login_attempt.failures + 1 end,
+			tstamplast = $2
+		returning key, failures, tstamplast, tstamplocked
+	`
+	attempt := &LoginAttempt{}
+	ctx, cancel := db.QueryContext(txContext)
+	defer cancel()
+	err = (*tx).QueryRow(ctx, query, key, now, resetBefore).Scan(&attempt.Key, &attempt.Failures, &attempt.Tstamplast, &attempt.Tstamplocked)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[AddFailure] Error counting login failure: %s", err)
+		return nil, err
+	}
+	if err := db.Commit(logContext, txContext, tx); err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// LockUntil locks a key until the given timestamp
+func (store *PostgresLoginAttemptStore) LockUntil(logContext *u.LoggerContext, key string, until int64) error {
+	logger := zerolog.Ctx(*logContext)
+	tx, txContext, err := store.database.GetTransaction(*logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[LockUntil] Error starting transaction: %s", err)
+		return err
+	}
+	defer db.Rollback(logContext, txContext, tx)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "tstamplocked", Value: until})
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "key", Value: key})
+	if err := db.Update(logContext, txContext, tx, "login_attempt", data, filter); err != nil {
+		logger.Error().Err(err).Msgf("[LockUntil] Error locking login: %s", err)
+		return err
+	}
+	return db.Commit(logContext, txContext, tx)
+}
+
+// ResetAttempts removes the counters and lock of a key
+func (store *PostgresLoginAttemptStore) ResetAttempts(logContext *u.LoggerContext, key string) error {
+	logger := zerolog.Ctx(*logContext)
+	tx, txContext, err := store.database.GetTransaction(*logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ResetAttempts] Error starting transaction: %s", err)
+		return err
+	}
+	defer db.Rollback(logContext, txContext, tx)
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "key", Value: key})
+	if err := db.Delete(logContext, txContext, tx, "login_attempt", filter); err != nil {
+		logger.Error().Err(err).Msgf("[ResetAttempts] Error removing login attempts: %s", err)
+		return err
+	}
+	return db.Commit(logContext, txContext, tx)
+}
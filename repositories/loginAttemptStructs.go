@@ -0,0 +1,24 @@
+package repositories
+
+import u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+
+// LoginAttempt table login_attempt on database, failed logins of one key (an email or a client ip)
+type LoginAttempt struct {
+	Key          string `json:"key,omitempty" db:"key,omitempty"`
+	Failures     int    `json:"failures,omitempty" db:"failures,omitempty"`
+	Tstamplast   int64  `json:"tstamplast,omitempty" db:"tstamplast,omitempty"`
+	Tstamplocked int64  `json:"tstamplocked,omitempty" db:"tstamplocked,omitempty"`
+}
+
+// LoginAttemptStore keeps failed login counters
+type LoginAttemptStore interface {
+	// GetAttempt returns the counters of a key, nil if there is none
+	GetAttempt(logContext *u.LoggerContext, key string) (*LoginAttempt, error)
+	// AddFailure counts one more failure at now, restarting the count if the last failure happened
+	// before resetBefore and the key is not locked
+	AddFailure(logContext *u.LoggerContext, key string, now int64, resetBefore int64) (*LoginAttempt, error)
+	// LockUntil locks a key until the given timestamp
+	LockUntil(logContext *u.LoggerContext, key string, until int64) error
+	// ResetAttempts removes the counters and lock of a key
+	ResetAttempts(logContext *u.LoggerContext, key string) error
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"sort"
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryOAuthClientStore OAuthClientStore kept in memory, for tests, with transactions of MemoryTransactor. Writes are
+// applied at once and undone on rollback
+type MemoryOAuthClientStore struct {
+	sync.Mutex
+	clients map[int]OAuthClient
+	lastID  int
+}
+
+// NewMemoryOAuthClientStore creates an empty MemoryOAuthClientStore
+func NewMemoryOAuthClientStore() *MemoryOAuthClientStore {
+	return &MemoryOAuthClientStore{clients: map[int]OAuthClient{}}
+}
+
+// InsertOAuthClient stores a new OAuth client, returning it with its id
+func (store *MemoryOAuthClientStore) InsertOAuthClient(logContext *u.LoggerContext, tx Transaction, client *OAuthClient) (*OAuthClient, error) {
+	store.Lock()
+	defer store.Unlock()
+	store.lastID++
+	created := *client
+	created.ID = store.lastID
+	created.Secret = ""
+	keepEntry(tx, store, store.clients, created.ID)
+	store.clients[created.ID] = created
+	return &created, nil
+}
+
+// ListOAuthClients lists the OAuth clients of the tenant of client, by id
+func (store *MemoryOAuthClientStore) ListOAuthClients(logContext *u.LoggerContext, client *OAuthClient) ([]OAuthClient, error) {
+	store.Lock()
+	defer store.Unlock()
+	var list []OAuthClient
+	for _, v := range store.clients {
+		if v.TenantID == client.TenantID {
+			list = append(list, v)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// GetOAuthClientByClientID gets the OAuth client with the client_id of client
+func (store *MemoryOAuthClientStore) GetOAuthClientByClientID(logContext *u.LoggerContext, client *OAuthClient) (*OAuthClient, error) {
+	store.Lock()
+	defer store.Unlock()
+	for _, v := range store.clients {
+		if v.ClientID == client.ClientID {
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetOAuthClientForUpdate gets the OAuth client of the id and tenant of client, nothing is locked
+func (store *MemoryOAuthClientStore) GetOAuthClientForUpdate(logContext *u.LoggerContext, tx Transaction, client *OAuthClient) (*OAuthClient, error) {
+	store.Lock()
+	defer store.Unlock()
+	if found, ok := store.clients[client.ID]; ok && found.TenantID == client.TenantID {
+		return &found, nil
+	}
+	return nil, nil
+}
+
+// DeleteOAuthClient deletes the OAuth client of the id and tenant of client
+func (store *MemoryOAuthClientStore) DeleteOAuthClient(logContext *u.LoggerContext, tx Transaction, client *OAuthClient) error {
+	store.Lock()
+	defer store.Unlock()
+	if found, ok := store.clients[client.ID]; ok && found.TenantID == client.TenantID {
+		keepEntry(tx, store, store.clients, client.ID)
+		delete(store.clients, client.ID)
+	}
+	return nil
+}
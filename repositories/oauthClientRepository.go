@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// InsertOAuthClient Stores a new OAuth client
+func (client *OAuthClient) InsertOAuthClient(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*OAuthClient, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "client_id", Value: client.ClientID})
+	data = append(data, db.SqlValue{Name: "name", Value: client.Name})
+	data = append(data, db.SqlValue{Name: "secret_hash", Value: client.SecretHash})
+	data = append(data, db.SqlValue{Name: "scopes", Value: client.Scopes})
+	data = append(data, db.SqlValue{Name: "tstampinit", Value: client.Tstampinit})
+	data = append(data, db.SqlValue{Name: "tenant_id", Value: client.TenantID})
+	res, err := db.InsertReturningPostgres[OAuthClient](logContext, txContext, tx, "oauth_client", data, "id")
+	if err != nil {
+		logger.Error().Err(err).Msgf("[InsertOAuthClient] Cannot insert OAuth client %s: %s", client.Name, err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListOAuthClients Lists all OAuth clients of the tenant of client
+func (client *OAuthClient) ListOAuthClients(logContext *u.LoggerContext, dbContext *db.DatabaseContext) ([]OAuthClient, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "tenant_id", Value: client.TenantID})
+	query := `
+		select id, client_id, name, secret_hash, scopes, tstampinit, tenant_id from oauth_client
+		where tenant_id = $1
+		order by id
+	`
+	val, err := db.SelectAll[OAuthClient](logContext, dbContext, nil, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ListOAuthClients] Error listing OAuth clients: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// GetOAuthClientByClientID Retrieves an OAuth client by its client_id, nil if not found
+func (client *OAuthClient) GetOAuthClientByClientID(logContext *u.LoggerContext, dbContext *db.DatabaseContext) (*OAuthClient, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "client_id", Value: client.ClientID})
+	query := `
+		select id, client_id, name, secret_hash, scopes, tstampinit, tenant_id from oauth_client
+		where client_id = $1
+	`
+	val, err := db.SelectOne[OAuthClient](logContext, dbContext, nil, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetOAuthClientByClientID] Error retrieving OAuth client: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// GetOAuthClientForUpdate Retrieves an OAuth client of the tenant of client by id, locking it until the end of the
+// transaction. nil if not found
+func (client *OAuthClient) GetOAuthClientForUpdate(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*OAuthClient, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "id", Value: client.ID})
+	data = append(data, db.SqlValue{Name: "tenant_id", Value: client.TenantID})
+	query := `
+		select id, client_id, name, secret_hash, scopes, tstampinit, tenant_id from oauth_client
+		where id = $1 and tenant_id = $2
+		for update
+	`
+	val, err := db.SelectOne[OAuthClient](logContext, txContext, tx, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetOAuthClientForUpdate] Error retrieving OAuth client: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// DeleteOAuthClient Deletes an OAuth client of the tenant of client
+func (client *OAuthClient) DeleteOAuthClient(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "id", Value: client.ID})
+	filter = append(filter, db.SqlValue{Name: "tenant_id", Value: client.TenantID})
+	if err := db.Delete(logContext, txContext, tx, "oauth_client", filter); err != nil {
+		logger.Error().Err(err).Msgf("[DeleteOAuthClient] Cannot delete OAuth client %d: %s", client.ID, err)
+		return err
+	}
+	return nil
+}
+
+// PostgresOAuthClientStore OAuthClientStore on table oauth_client
+type PostgresOAuthClientStore struct {
+	database *db.DB
+}
+
+// NewPostgresOAuthClientStore creates an OAuthClientStore on the database
+func NewPostgresOAuthClientStore(database *db.DB) *PostgresOAuthClientStore {
+	return &PostgresOAuthClientStore{database: database}
+}
+
+// InsertOAuthClient stores a new OAuth client, returning it with its id
+func (store *PostgresOAuthClientStore) InsertOAuthClient(logContext *u.LoggerContext, tx Transaction, client *OAuthClient) (*OAuthClient, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return client.InsertOAuthClient(logContext, txContext, pgTx)
+}
+
+// ListOAuthClients lists the OAuth clients of the tenant of client, by id
+func (store *PostgresOAuthClientStore) ListOAuthClients(logContext *u.LoggerContext, client *OAuthClient) ([]OAuthClient, error) {
+	return client.ListOAuthClients(logContext, store.database.Context(*logContext))
+}
+
+// GetOAuthClientByClientID gets the OAuth client with the client_id of client
+func (store *PostgresOAuthClientStore) GetOAuthClientByClientID(logContext *u.LoggerContext, client *OAuthClient) (*OAuthClient, error) {
+	return client.GetOAuthClientByClientID(logContext, store.database.Context(*logContext))
+}
+
+// GetOAuthClientForUpdate gets and locks the OAuth client of the id and tenant of client
+func (store *PostgresOAuthClientStore) GetOAuthClientForUpdate(logContext *u.LoggerContext, tx Transaction, client *OAuthClient) (*OAuthClient, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return client.GetOAuthClientForUpdate(logContext, txContext, pgTx)
+}
+
+// DeleteOAuthClient deletes the OAuth client of the id and tenant of client
+func (store *PostgresOAuthClientStore) DeleteOAuthClient(logContext *u.LoggerContext, tx Transaction, client *OAuthClient) error {
+	txContext, pgTx := PostgresTx(tx)
+	return client.DeleteOAuthClient(logContext, txContext, pgTx)
+}
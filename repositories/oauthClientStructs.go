@@ -0,0 +1,40 @@
+package repositories
+
+import u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+
+// ClientRole role set on tokens issued to OAuth clients, which have no user
+const ClientRole = "client"
+
+// OAuthClientStore keeps the OAuth clients of the tenants, on table oauth_client (PostgresOAuthClientStore) or in
+// memory (MemoryOAuthClientStore). Writes take the transaction of the caller
+type OAuthClientStore interface {
+	// InsertOAuthClient stores a new OAuth client, returning it with its id
+	InsertOAuthClient(logContext *u.LoggerContext, tx Transaction, client *OAuthClient) (*OAuthClient, error)
+	// ListOAuthClients lists the OAuth clients of the tenant of client, by id
+	ListOAuthClients(logContext *u.LoggerContext, client *OAuthClient) ([]OAuthClient, error)
+	// GetOAuthClientByClientID gets the OAuth client with the client_id of client. nil if not found
+	GetOAuthClientByClientID(logContext *u.LoggerContext, client *OAuthClient) (*OAuthClient, error)
+	// GetOAuthClientForUpdate gets the OAuth client of the id and tenant of client, locking it in the transaction. nil if
+	// not found
+	GetOAuthClientForUpdate(logContext *u.LoggerContext, tx Transaction, client *OAuthClient) (*OAuthClient, error)
+	// DeleteOAuthClient deletes the OAuth client of the id and tenant of client
+	DeleteOAuthClient(logContext *u.LoggerContext, tx Transaction, client *OAuthClient) error
+}
+
+// OAuthClient table oauth_client on database, a service calling the API as itself. Scopes are space separated permissions
+type OAuthClient struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	ClientID   string `json:"client_id,omitempty" db:"client_id,omitempty"`
+	Name       string `json:"name,omitempty" db:"name,omitempty"`
+	SecretHash string `json:"-" db:"secret_hash,omitempty"`
+	Scopes     string `json:"scopes,omitempty" db:"scopes,omitempty"`
+	Tstampinit int64  `json:"tstampinit,omitempty" db:"tstampinit,omitempty"`
+	TenantID   int    `json:"tenant_id,omitempty" db:"tenant_id,omitempty"`
+	Secret     string `json:"client_secret,omitempty" db:"-"`
+}
+
+// OAuthClientRequest body to register an OAuth client
+type OAuthClientRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryPasswordResetStore PasswordResetStore kept in memory, for tests, with transactions of MemoryTransactor. Writes
+// are applied at once and undone on rollback
+type MemoryPasswordResetStore struct {
+	sync.Mutex
+	resets map[int]PasswordReset
+	lastID int
+}
+
+// NewMemoryPasswordResetStore creates an empty MemoryPasswordResetStore
+func NewMemoryPasswordResetStore() *MemoryPasswordResetStore {
+	return &MemoryPasswordResetStore{resets: map[int]PasswordReset{}}
+}
+
+// InsertPasswordReset stores a new password reset token
+func (store *MemoryPasswordResetStore) InsertPasswordReset(logContext *u.LoggerContext, tx Transaction, reset *PasswordReset) error {
+	store.Lock()
+	defer store.Unlock()
+	store.lastID++
+	created := *reset
+	created.ID = store.lastID
+	keepEntry(tx, store, store.resets, created.ID)
+	store.resets[created.ID] = created
+	return nil
+}
+
+// GetPasswordResetByHash gets the password reset token with the hash of reset, nothing is locked
+func (store *MemoryPasswordResetStore) GetPasswordResetByHash(logContext *u.LoggerContext, tx Transaction, reset *PasswordReset) (*PasswordReset, error) {
+	store.Lock()
+	defer store.Unlock()
+	for _, v := range store.resets {
+		if v.TokenHash == reset.TokenHash {
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+// UseUserPasswordResets marks every active password reset token of the user of reset as used
+func (store *MemoryPasswordResetStore) UseUserPasswordResets(logContext *u.LoggerContext, tx Transaction, reset *PasswordReset) error {
+	store.Lock()
+	defer store.Unlock()
+	for id, v := range store.resets {
+		if v.UserID == reset.UserID && v.Status == PasswordResetActive {
+			keepEntry(tx, store, store.resets, id)
+			v.Status = PasswordResetUsed
+			store.resets[id] = v
+		}
+	}
+	return nil
+}
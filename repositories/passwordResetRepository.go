@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// InsertPasswordReset Stores a new password reset token
+func (reset *PasswordReset) InsertPasswordReset(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "user_id", Value: reset.UserID})
+	data = append(data, db.SqlValue{Name: "token_hash", Value: reset.TokenHash})
+	data = append(data, db.SqlValue{Name: "status", Value: reset.Status})
+	data = append(data, db.SqlValue{Name: "tstampinit", Value: reset.Tstampinit})
+	data = append(data, db.SqlValue{Name: "tstampexp", Value: reset.Tstampexp})
+	if err := db.Insert(logContext, txContext, tx, "password_reset", data); err != nil {
+		logger.Error().Err(err).Msgf("[InsertPasswordReset] Cannot insert password reset for user %d: %s", reset.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// GetPasswordResetByHash Retrieves and locks a password reset token by its hash
+func (reset *PasswordReset) GetPasswordResetByHash(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*PasswordReset, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "token_hash", Value: reset.TokenHash})
+	query := `
+		select id, user_id, token_hash, status, tstampinit, tstampexp from password_reset
+		where token_hash = $1
+		for update
+	`
+	val, err := db.SelectOne[PasswordReset](logContext, txContext, tx, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetPasswordResetByHash] Error retrieving password reset: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// UseUserPasswordResets Marks every active password reset token of the user as used
+func (reset *PasswordReset) UseUserPasswordResets(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "status", Value: PasswordResetUsed})
+	var filters db.SqlData
+	filters = append(filters, db.SqlValue{Name: "user_id", Value: reset.UserID})
+	filters = append(filters, db.SqlValue{Name: "status", Value: PasswordResetActive})
+	if err := db.Update(logContext, txContext, tx, "password_reset", data, filters); err != nil {
+		logger.Error().Err(err).Msgf("[UseUserPasswordResets] Cannot update password resets of user %d: %s", reset.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// PostgresPasswordResetStore PasswordResetStore on table password_reset
+type PostgresPasswordResetStore struct{}
+
+// NewPostgresPasswordResetStore creates a PasswordResetStore on the database, only used on transactions of PostgresTransactor
+func NewPostgresPasswordResetStore() *PostgresPasswordResetStore {
+	return &PostgresPasswordResetStore{}
+}
+
+// InsertPasswordReset stores a new password reset token
+func (store *PostgresPasswordResetStore) InsertPasswordReset(logContext *u.LoggerContext, tx Transaction, reset *PasswordReset) error {
+	txContext, pgTx := PostgresTx(tx)
+	return reset.InsertPasswordReset(logContext, txContext, pgTx)
+}
+
+// GetPasswordResetByHash gets and locks the password reset token with the hash of reset
+func (store *PostgresPasswordResetStore) GetPasswordResetByHash(logContext *u.LoggerContext, tx Transaction, reset *PasswordReset) (*PasswordReset, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return reset.GetPasswordResetByHash(logContext, txContext, pgTx)
+}
+
+// UseUserPasswordResets marks every active password reset token of the user of reset as used
+func (store *PostgresPasswordResetStore) UseUserPasswordResets(logContext *u.LoggerContext, tx Transaction, reset *PasswordReset) error {
+	txContext, pgTx := PostgresTx(tx)
+	return reset.UseUserPasswordResets(logContext, txContext, pgTx)
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+
+// Password reset status values
+const (
+	PasswordResetActive = "active"
+	PasswordResetUsed   = "used"
+)
+
+// PasswordResetStore keeps the password reset tokens of users, on table password_reset (PostgresPasswordResetStore)
+// or in memory (MemoryPasswordResetStore). Writes take the transaction of the caller
+type PasswordResetStore interface {
+	// InsertPasswordReset stores a new password reset token
+	InsertPasswordReset(logContext *u.LoggerContext, tx Transaction, reset *PasswordReset) error
+	// GetPasswordResetByHash gets the password reset token with the hash of reset, locking it until the end of the transaction.
+	// nil if not found
+	GetPasswordResetByHash(logContext *u.LoggerContext, tx Transaction, reset *PasswordReset) (*PasswordReset, error)
+	// UseUserPasswordResets marks every active password reset token of the user of reset as used
+	UseUserPasswordResets(logContext *u.LoggerContext, tx Transaction, reset *PasswordReset) error
+}
+
+// PasswordReset table password_reset on database, only the hash of the token sent by email is stored
+type PasswordReset struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	UserID     int    `json:"user_id,omitempty" db:"user_id,omitempty"`
+	TokenHash  string `json:"-" db:"token_hash,omitempty"`
+	Status     string `json:"status,omitempty" db:"status,omitempty"`
+	Tstampinit int64  `json:"tstampinit,omitempty" db:"tstampinit,omitempty"`
+	Tstampexp  int64  `json:"tstampexp,omitempty" db:"tstampexp,omitempty"`
+}
+
+// PasswordResetRequest body of /api/password/forgot (Email and optionally TenantID) and /api/password/reset (Token and Password)
+type PasswordResetRequest struct {
+	Email    string `json:"email,omitempty"`
+	TenantID int    `json:"tenant_id,omitempty"`
+	Token    string `json:"token,omitempty"`
+	Password string `json:"password,omitempty"`
+}
@@ -0,0 +1,261 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryRBACStore RBACStore kept in memory, for tests, with transactions of MemoryTransactor. Writes are applied at
+// once and undone on rollback. The role on user_db of each user is read from the UserStore
+type MemoryRBACStore struct {
+	sync.Mutex
+	users            UserStore
+	roles            map[int]Role  //without permissions, see rolePermissions
+	rolePermissions  map[int][]int //role id -> permission ids
+	permissions      map[int]Permission
+	userRoles        map[int][]int //user id -> granted role ids
+	lastRoleID       int
+	lastPermissionID int
+}
+
+// NewMemoryRBACStore creates an empty MemoryRBACStore, reading the role on user_db of users from the UserStore
+func NewMemoryRBACStore(users UserStore) *MemoryRBACStore {
+	return &MemoryRBACStore{users: users, roles: map[int]Role{}, rolePermissions: map[int][]int{}, permissions: map[int]Permission{}, userRoles: map[int][]int{}}
+}
+
+// ListRoles lists every role with its permissions, by name
+func (store *MemoryRBACStore) ListRoles(logContext *u.LoggerContext) ([]Role, error) {
+	store.Lock()
+	defer store.Unlock()
+	list := []Role{}
+	for id := range store.roles {
+		list = append(list, store.role(id))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// GetRoleByID gets a role by ID with its permissions, nothing is locked
+func (store *MemoryRBACStore) GetRoleByID(logContext *u.LoggerContext, tx Transaction, role *Role) (*Role, error) {
+	store.Lock()
+	defer store.Unlock()
+	if _, ok := store.roles[role.ID]; !ok {
+		return nil, nil
+	}
+	found := store.role(role.ID)
+	return &found, nil
+}
+
+// UpsertRole inserts or updates a role, replacing its permissions
+func (store *MemoryRBACStore) UpsertRole(logContext *u.LoggerContext, tx Transaction, role *Role) (*Role, error) {
+	store.Lock()
+	defer store.Unlock()
+	var permissions []int
+	for _, name := range role.Permissions {
+		id := store.permissionID(name)
+		if id == 0 {
+			return nil, ErrUnknownPermission
+		}
+		permissions = append(permissions, id)
+	}
+	if role.ID == 0 {
+		store.lastRoleID++
+		role.ID = store.lastRoleID
+	}
+	keepEntry(tx, store, store.roles, role.ID)
+	keepEntry(tx, store, store.rolePermissions, role.ID)
+	store.roles[role.ID] = Role{ID: role.ID, Name: role.Name, Description: role.Description}
+	store.rolePermissions[role.ID] = permissions
+	return role, nil
+}
+
+// DeleteRole deletes a role, removing it from every user
+func (store *MemoryRBACStore) DeleteRole(logContext *u.LoggerContext, tx Transaction, role *Role) error {
+	store.Lock()
+	defer store.Unlock()
+	for userID, roles := range store.userRoles {
+		if slices.Contains(roles, role.ID) {
+			keepEntry(tx, store, store.userRoles, userID)
+			store.userRoles[userID] = slices.DeleteFunc(slices.Clone(roles), func(id int) bool { return id == role.ID })
+		}
+	}
+	keepEntry(tx, store, store.roles, role.ID)
+	keepEntry(tx, store, store.rolePermissions, role.ID)
+	delete(store.roles, role.ID)
+	delete(store.rolePermissions, role.ID)
+	return nil
+}
+
+// ListRoleUsers lists the ids of the users holding the role, on user_db or granted
+func (store *MemoryRBACStore) ListRoleUsers(logContext *u.LoggerContext, tx Transaction, role *Role) ([]int, error) {
+	users, err := store.users.ListUsers(logContext, &User{})
+	if err != nil {
+		return nil, err
+	}
+	store.Lock()
+	defer store.Unlock()
+	current, ok := store.roles[role.ID]
+	if !ok {
+		return nil, nil
+	}
+	var ids []int
+	for _, v := range users {
+		if v.Role == current.Name || slices.Contains(store.userRoles[v.ID], role.ID) {
+			ids = append(ids, v.ID)
+		}
+	}
+	return ids, nil
+}
+
+// ListPermissions lists every permission, by name
+func (store *MemoryRBACStore) ListPermissions(logContext *u.LoggerContext) ([]Permission, error) {
+	store.Lock()
+	defer store.Unlock()
+	var list []Permission
+	for _, v := range store.permissions {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// GetPermissionByID gets a permission by ID, nothing is locked
+func (store *MemoryRBACStore) GetPermissionByID(logContext *u.LoggerContext, tx Transaction, permission *Permission) (*Permission, error) {
+	store.Lock()
+	defer store.Unlock()
+	if found, ok := store.permissions[permission.ID]; ok {
+		return &found, nil
+	}
+	return nil, nil
+}
+
+// UpsertPermission inserts or updates a permission, updating a missing one changes nothing
+func (store *MemoryRBACStore) UpsertPermission(logContext *u.LoggerContext, tx Transaction, permission *Permission) (*Permission, error) {
+	store.Lock()
+	defer store.Unlock()
+	if permission.ID == 0 {
+		store.lastPermissionID++
+		permission.ID = store.lastPermissionID
+	} else if _, ok := store.permissions[permission.ID]; !ok {
+		return permission, nil
+	}
+	keepEntry(tx, store, store.permissions, permission.ID)
+	store.permissions[permission.ID] = *permission
+	return permission, nil
+}
+
+// ListUserRoles lists the roles granted to the user, by name
+func (store *MemoryRBACStore) ListUserRoles(logContext *u.LoggerContext, tx Transaction, user *User) ([]string, error) {
+	store.Lock()
+	defer store.Unlock()
+	list := []string{}
+	for _, id := range store.userRoles[user.ID] {
+		list = append(list, store.roles[id].Name)
+	}
+	slices.Sort(list)
+	return list, nil
+}
+
+// SetUserRoles replaces the roles granted to the user
+func (store *MemoryRBACStore) SetUserRoles(logContext *u.LoggerContext, tx Transaction, user *User, roles []string) error {
+	store.Lock()
+	defer store.Unlock()
+	var ids []int
+	for _, name := range roles {
+		id := store.roleID(name)
+		if id == 0 {
+			return ErrUnknownRole
+		}
+		ids = append(ids, id)
+	}
+	keepEntry(tx, store, store.userRoles, user.ID)
+	store.userRoles[user.ID] = ids
+	return nil
+}
+
+// GetEffectiveRoles lists every role of the user, the one on user_db plus the granted ones
+func (store *MemoryRBACStore) GetEffectiveRoles(logContext *u.LoggerContext, user *User) ([]string, error) {
+	role, err := store.userRole(logContext, user)
+	if err != nil {
+		return nil, err
+	}
+	store.Lock()
+	defer store.Unlock()
+	list := []string{}
+	if role != "" {
+		list = append(list, role)
+	}
+	for _, id := range store.userRoles[user.ID] {
+		list = append(list, store.roles[id].Name)
+	}
+	slices.Sort(list)
+	return slices.Compact(list), nil
+}
+
+// GetEffectivePermissions lists every permission granted to the user by any of its roles, by name
+func (store *MemoryRBACStore) GetEffectivePermissions(logContext *u.LoggerContext, user *User) ([]string, error) {
+	role, err := store.userRole(logContext, user)
+	if err != nil {
+		return nil, err
+	}
+	store.Lock()
+	defer store.Unlock()
+	roles := slices.Clone(store.userRoles[user.ID])
+	if id := store.roleID(role); id != 0 {
+		roles = append(roles, id)
+	}
+	list := []string{}
+	for _, id := range roles {
+		list = append(list, store.role(id).Permissions...)
+	}
+	slices.Sort(list)
+	return slices.Compact(list), nil
+}
+
+// userRole the role on user_db of the user, empty if the user does not exist
+func (store *MemoryRBACStore) userRole(logContext *u.LoggerContext, user *User) (string, error) {
+	account, err := store.users.GetUserByID(logContext, &User{ID: user.ID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return account.Role, nil
+}
+
+// role the role with the names of its permissions, by name. Must be called with the store locked
+func (store *MemoryRBACStore) role(id int) Role {
+	role := store.roles[id]
+	role.Permissions = []string{}
+	for _, permission := range store.rolePermissions[id] {
+		role.Permissions = append(role.Permissions, store.permissions[permission].Name)
+	}
+	slices.Sort(role.Permissions)
+	return role
+}
+
+// roleID the id of the role with the name, 0 if none. Must be called with the store locked
+func (store *MemoryRBACStore) roleID(name string) int {
+	for id, v := range store.roles {
+		if v.Name == name {
+			return id
+		}
+	}
+	return 0
+}
+
+// permissionID the id of the permission with the name, 0 if none. Must be called with the store locked
+func (store *MemoryRBACStore) permissionID(name string) int {
+	for id, v := range store.permissions {
+		if v.Name == name {
+			return id
+		}
+	}
+	return 0
+}
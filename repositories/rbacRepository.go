@@ -0,0 +1,351 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// ListRoles Lists all roles with their permissions
+func (role *Role) ListRoles(logContext *u.LoggerContext, dbContext *db.DatabaseContext) ([]Role, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	query := `
+		select r.id, r.name, r.description,
+			coalesce(array_agg(p.name order by p.name) filter (where p.name is not null), '{}') as permissions
+		from roles r
+		left join role_permissions rp on rp.role_id = r.id
+		left join permissions p on p.id = rp.permission_id
+		group by r.id, r.name, r.description
+		order by r.name
+	`
+	val, err := db.SelectAll[Role](logContext, dbContext, nil, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ListRoles] Error listing roles: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// UpsertRole Inserts or updates a role, replacing its permissions
+func (role *Role) UpsertRole(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*Role, error) {
+	logger := zerolog.Ctx(*logContext)
+	ctx, cancel := db.QueryContext(txContext)
+	defer cancel()
+	if role.ID == 0 {
+		err := (*tx).QueryRow(ctx, "insert into roles (name, description) values ($1, $2) returning id", role.Name, role.Description).Scan(&role.ID)
+		if err != nil {
+			logger.Error().Err(err).Msgf("[UpsertRole] Error inserting role: %s", err)
+			return nil, err
+		}
+	} else {
+		var data db.SqlData
+		data = append(data, db.SqlValue{Name: "name", Value: role.Name})
+		data = append(data, db.SqlValue{Name: "description", Value: role.Description})
+		var filter db.SqlData
+		filter = append(filter, db.SqlValue{Name: "id", Value: role.ID})
+		if err := db.Update(logContext, txContext, tx, "roles", data, filter); err != nil {
+			logger.Error().Err(err).Msgf("[UpsertRole] Error updating role: %s", err)
+			return nil, err
+		}
+	}
+
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "role_id", Value: role.ID})
+	if err := db.Delete(logContext, txContext, tx, "role_permissions", filter); err != nil {
+		logger.Error().Err(err).Msgf("[UpsertRole] Error removing role permissions: %s", err)
+		return nil, err
+	}
+	query := `
+		insert into role_permissions (role_id, permission_id)
+		select $1, id from permissions where name = any($2)
+	`
+	tag, err := (*tx).Exec(ctx, query, role.ID, role.Permissions)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UpsertRole] Error inserting role permissions: %s", err)
+		return nil, err
+	}
+	if int(tag.RowsAffected()) != len(role.Permissions) {
+		logger.Error().Msgf("[UpsertRole] Unknown permission on %v", role.Permissions)
+		return nil, ErrUnknownPermission
+	}
+	return role, nil
+}
+
+// DeleteRole Deletes a role, removing it from every user
+func (role *Role) DeleteRole(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "role_id", Value: role.ID})
+	for _, table := range []string{"role_permissions", "user_roles"} {
+		if err := db.Delete(logContext, txContext, tx, table, filter); err != nil {
+			logger.Error().Err(err).Msgf("[DeleteRole] Error removing role %d from %s: %s", role.ID, table, err)
+			return err
+		}
+	}
+	var filterRole db.SqlData
+	filterRole = append(filterRole, db.SqlValue{Name: "id", Value: role.ID})
+	return db.Delete(logContext, txContext, tx, "roles", filterRole)
+}
+
+// GetRoleByID Get a role by ID with its permissions, locking it until the end of the transaction
+func (role *Role) GetRoleByID(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*Role, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "id", Value: role.ID})
+	query := `
+		select r.id, r.name, r.description,
+			coalesce((select array_agg(p.name order by p.name) from role_permissions rp
+				join permissions p on p.id = rp.permission_id where rp.role_id = r.id), '{}') as permissions
+		from roles r
+		where r.id = $1
+		for update
+	`
+	val, err := db.SelectOne[Role](logContext, txContext, tx, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetRoleByID] Error retrieving role: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// ListRoleUsers Lists the ids of users holding the role, on user_db or user_roles
+func (role *Role) ListRoleUsers(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) ([]int, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "id", Value: role.ID})
+	query := `
+		select u.id from user_db u join roles r on r.name = u.role where r.id = $1
+		union
+		select user_id as id from user_roles where role_id = $1
+	`
+	val, err := db.SelectAll[idRow](logContext, txContext, tx, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ListRoleUsers] Error listing users of role %d: %s", role.ID, err)
+		return nil, err
+	}
+	var ids []int
+	for _, v := range val {
+		ids = append(ids, v.ID)
+	}
+	return ids, nil
+}
+
+// ListPermissions Lists all permissions
+func (permission *Permission) ListPermissions(logContext *u.LoggerContext, dbContext *db.DatabaseContext) ([]Permission, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	val, err := db.SelectAll[Permission](logContext, dbContext, nil, "select id, name, description from permissions order by name", data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ListPermissions] Error listing permissions: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// GetPermissionByID Get a permission by ID, locking it until the end of the transaction
+func (permission *Permission) GetPermissionByID(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*Permission, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "id", Value: permission.ID})
+	val, err := db.SelectOne[Permission](logContext, txContext, tx, "select id, name, description from permissions where id = $1 for update", data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetPermissionByID] Error retrieving permission: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// UpsertPermission Inserts or updates a permission
+func (permission *Permission) UpsertPermission(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*Permission, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "name", Value: permission.Name})
+	data = append(data, db.SqlValue{Name: "description", Value: permission.Description})
+	if permission.ID == 0 {
+		res, err := db.InsertReturningPostgres[Permission](logContext, txContext, tx, "permissions", data, "id")
+		if err != nil {
+			logger.Error().Err(err).Msgf("[UpsertPermission] Error inserting permission: %s", err)
+			return nil, err
+		}
+		return res, nil
+	}
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "id", Value: permission.ID})
+	if err := db.Update(logContext, txContext, tx, "permissions", data, filter); err != nil {
+		logger.Error().Err(err).Msgf("[UpsertPermission] Error updating permission: %s", err)
+		return nil, err
+	}
+	return permission, nil
+}
+
+// ListUserRoles Lists the roles granted to the user on user_roles, on the transaction when given one
+func (user *User) ListUserRoles(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) ([]string, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "user_id", Value: user.ID})
+	query := `
+		select r.name from roles r join user_roles ur on ur.role_id = r.id
+		where ur.user_id = $1
+		order by r.name
+	`
+	val, err := db.SelectAll[nameRow](logContext, txContext, tx, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ListUserRoles] Error listing roles of user %d: %s", user.ID, err)
+		return nil, err
+	}
+	return names(val), nil
+}
+
+// SetUserRoles Replaces the roles granted to the user on user_roles
+func (user *User) SetUserRoles(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx, roles []string) error {
+	logger := zerolog.Ctx(*logContext)
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "user_id", Value: user.ID})
+	if err := db.Delete(logContext, txContext, tx, "user_roles", filter); err != nil {
+		logger.Error().Err(err).Msgf("[SetUserRoles] Error removing roles of user %d: %s", user.ID, err)
+		return err
+	}
+	query := `
+		insert into user_roles (user_id, role_id)
+		select $1, id from roles where name = any($2)
+	`
+	ctx, cancel := db.QueryContext(txContext)
+	defer cancel()
+	tag, err := (*tx).Exec(ctx, query, user.ID, roles)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[SetUserRoles] Error inserting roles of user %d: %s", user.ID, err)
+		return err
+	}
+	if int(tag.RowsAffected()) != len(roles) {
+		logger.Error().Msgf("[SetUserRoles] Unknown role on %v", roles)
+		return ErrUnknownRole
+	}
+	return nil
+}
+
+// GetEffectiveRoles Lists every role of the user, the one on user_db plus the ones on user_roles
+func (user *User) GetEffectiveRoles(logContext *u.LoggerContext, dbContext *db.DatabaseContext) ([]string, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "id", Value: user.ID})
+	query := `
+		select role as name from user_db where id = $1
+		union
+		select r.name from roles r join user_roles ur on ur.role_id = r.id where ur.user_id = $1
+	`
+	val, err := db.SelectAll[nameRow](logContext, dbContext, nil, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetEffectiveRoles] Error listing roles of user %d: %s", user.ID, err)
+		return nil, err
+	}
+	return names(val), nil
+}
+
+// GetEffectivePermissions Lists every permission granted to the user by any of its roles
+func (user *User) GetEffectivePermissions(logContext *u.LoggerContext, dbContext *db.DatabaseContext) ([]string, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "id", Value: user.ID})
+	query := `
+		select distinct p.name from permissions p
+		join role_permissions rp on rp.permission_id = p.id
+		join roles r on r.id = rp.role_id
+		where r.name = (select role from user_db where id = $1)
+			or r.id in (select role_id from user_roles where user_id = $1)
+		order by p.name
+	`
+	val, err := db.SelectAll[nameRow](logContext, dbContext, nil, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetEffectivePermissions] Error listing permissions of user %d: %s", user.ID, err)
+		return nil, err
+	}
+	return names(val), nil
+}
+
+func names(rows []nameRow) []string {
+	list := []string{}
+	for _, v := range rows {
+		list = append(list, v.Name)
+	}
+	return list
+}
+
+// PostgresRBACStore RBACStore on tables roles, permissions, role_permissions and user_roles
+type PostgresRBACStore struct {
+	database *db.DB
+}
+
+// NewPostgresRBACStore creates an RBACStore on the database
+func NewPostgresRBACStore(database *db.DB) *PostgresRBACStore {
+	return &PostgresRBACStore{database: database}
+}
+
+// ListRoles lists every role with its permissions
+func (store *PostgresRBACStore) ListRoles(logContext *u.LoggerContext) ([]Role, error) {
+	return (&Role{}).ListRoles(logContext, store.database.Context(*logContext))
+}
+
+// GetRoleByID gets and locks a role by ID with its permissions
+func (store *PostgresRBACStore) GetRoleByID(logContext *u.LoggerContext, tx Transaction, role *Role) (*Role, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return role.GetRoleByID(logContext, txContext, pgTx)
+}
+
+// UpsertRole inserts or updates a role, replacing its permissions
+func (store *PostgresRBACStore) UpsertRole(logContext *u.LoggerContext, tx Transaction, role *Role) (*Role, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return role.UpsertRole(logContext, txContext, pgTx)
+}
+
+// DeleteRole deletes a role, removing it from every user
+func (store *PostgresRBACStore) DeleteRole(logContext *u.LoggerContext, tx Transaction, role *Role) error {
+	txContext, pgTx := PostgresTx(tx)
+	return role.DeleteRole(logContext, txContext, pgTx)
+}
+
+// ListRoleUsers lists the ids of the users holding the role
+func (store *PostgresRBACStore) ListRoleUsers(logContext *u.LoggerContext, tx Transaction, role *Role) ([]int, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return role.ListRoleUsers(logContext, txContext, pgTx)
+}
+
+// ListPermissions lists every permission
+func (store *PostgresRBACStore) ListPermissions(logContext *u.LoggerContext) ([]Permission, error) {
+	return (&Permission{}).ListPermissions(logContext, store.database.Context(*logContext))
+}
+
+// GetPermissionByID gets and locks a permission by ID
+func (store *PostgresRBACStore) GetPermissionByID(logContext *u.LoggerContext, tx Transaction, permission *Permission) (*Permission, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return permission.GetPermissionByID(logContext, txContext, pgTx)
+}
+
+// UpsertPermission inserts or updates a permission
+func (store *PostgresRBACStore) UpsertPermission(logContext *u.LoggerContext, tx Transaction, permission *Permission) (*Permission, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return permission.UpsertPermission(logContext, txContext, pgTx)
+}
+
+// ListUserRoles lists the roles granted to the user, on the pool without a transaction
+func (store *PostgresRBACStore) ListUserRoles(logContext *u.LoggerContext, tx Transaction, user *User) ([]string, error) {
+	txContext, pgTx := postgresContext(logContext, store.database, tx)
+	return user.ListUserRoles(logContext, txContext, pgTx)
+}
+
+// SetUserRoles replaces the roles granted to the user
+func (store *PostgresRBACStore) SetUserRoles(logContext *u.LoggerContext, tx Transaction, user *User, roles []string) error {
+	txContext, pgTx := PostgresTx(tx)
+	return user.SetUserRoles(logContext, txContext, pgTx, roles)
+}
+
+// GetEffectiveRoles lists every role of the user
+func (store *PostgresRBACStore) GetEffectiveRoles(logContext *u.LoggerContext, user *User) ([]string, error) {
+	return user.GetEffectiveRoles(logContext, store.database.Context(*logContext))
+}
+
+// GetEffectivePermissions lists every permission granted to the user
+func (store *PostgresRBACStore) GetEffectivePermissions(logContext *u.LoggerContext, user *User) ([]string, error) {
+	return user.GetEffectivePermissions(logContext, store.database.Context(*logContext))
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"errors"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// ErrUnknownPermission a permission name does not exist on table permissions
+var ErrUnknownPermission = errors.New("unknown permission")
+
+// ErrUnknownRole a role name does not exist on table roles
+var ErrUnknownRole = errors.New("unknown role")
+
+// RBACStore keeps the roles, their permissions and the roles granted to users, on tables roles, permissions,
+// role_permissions and user_roles (PostgresRBACStore) or in memory (MemoryRBACStore). Writes take the transaction of the caller
+type RBACStore interface {
+	// ListRoles lists every role with its permissions
+	ListRoles(logContext *u.LoggerContext) ([]Role, error)
+	// GetRoleByID gets a role by ID with its permissions, locking it until the end of the transaction. nil if not found
+	GetRoleByID(logContext *u.LoggerContext, tx Transaction, role *Role) (*Role, error)
+	// UpsertRole inserts the role when it has no ID, otherwise updates it, replacing its permissions. ErrUnknownPermission
+	// if one of them does not exist
+	UpsertRole(logContext *u.LoggerContext, tx Transaction, role *Role) (*Role, error)
+	// DeleteRole deletes a role, removing it from every user
+	DeleteRole(logContext *u.LoggerContext, tx Transaction, role *Role) error
+	// ListRoleUsers lists the ids of the users holding the role, on user_db or granted
+	ListRoleUsers(logContext *u.LoggerContext, tx Transaction, role *Role) ([]int, error)
+	// ListPermissions lists every permission
+	ListPermissions(logContext *u.LoggerContext) ([]Permission, error)
+	// GetPermissionByID gets a permission by ID, locking it in the transaction
+	GetPermissionByID(logContext *u.LoggerContext, tx Transaction, permission *Permission) (*Permission, error)
+	// UpsertPermission inserts the permission when it has no ID, otherwise updates it
+	UpsertPermission(logContext *u.LoggerContext, tx Transaction, permission *Permission) (*Permission, error)
+	// ListUserRoles lists the roles granted to the user besides the role on user_db, on the transaction when given one
+	ListUserRoles(logContext *u.LoggerContext, tx Transaction, user *User) ([]string, error)
+	// SetUserRoles replaces the roles granted to the user. ErrUnknownRole if one of them does not exist
+	SetUserRoles(logContext *u.LoggerContext, tx Transaction, user *User, roles []string) error
+	// GetEffectiveRoles lists every role of the user, the one on user_db plus the granted ones
+	GetEffectiveRoles(logContext *u.LoggerContext, user *User) ([]string, error)
+	// GetEffectivePermissions lists every permission granted to the user by any of its roles
+	GetEffectivePermissions(logContext *u.LoggerContext, user *User) ([]string, error)
+}
+
+// Role table roles on database, with the names of its permissions
+type Role struct {
+	ID          int      `json:"id,omitempty" db:"id,omitempty"`
+	Name        string   `json:"name,omitempty" db:"name,omitempty"`
+	Description string   `json:"description,omitempty" db:"description,omitempty"`
+	Permissions []string `json:"permissions" db:"permissions"`
+}
+
+// Permission table permissions on database
+type Permission struct {
+	ID          int    `json:"id,omitempty" db:"id,omitempty"`
+	Name        string `json:"name,omitempty" db:"name,omitempty"`
+	Description string `json:"description,omitempty" db:"description,omitempty"`
+}
+
+// UserRoles roles granted to an user on table user_roles, besides the role on user_db
+type UserRoles struct {
+	UserID int      `json:"user_id,omitempty"`
+	Roles  []string `json:"roles"`
+}
+
+// nameRow one name column
+type nameRow struct {
+	Name string `db:"name"`
+}
+
+// idRow one id column
+type idRow struct {
+	ID int `db:"id"`
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// InsertRefreshToken Stores a new refresh token
+func (token *RefreshToken) InsertRefreshToken(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "user_id", Value: token.UserID})
+	data = append(data, db.SqlValue{Name: "family", Value: token.Family})
+	data = append(data, db.SqlValue{Name: "token_hash", Value: token.TokenHash})
+	data = append(data, db.SqlValue{Name: "status", Value: token.Status})
+	data = append(data, db.SqlValue{Name: "tstampinit", Value: token.Tstampinit})
+	data = append(data, db.SqlValue{Name: "tstampexp", Value: token.Tstampexp})
+	if err := db.Insert(logContext, txContext, tx, "refresh_token", data); err != nil {
+		logger.Error().Err(err).Msgf("[InsertRefreshToken] Cannot insert refresh token for user %d: %s", token.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// GetRefreshTokenByHash Retrieves and locks a refresh token by its hash
+func (token *RefreshToken) GetRefreshTokenByHash(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*RefreshToken, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "token_hash", Value: token.TokenHash})
+	query := `
+		select id, user_id, family, token_hash, status, tstampinit, tstampexp from refresh_token
+		where token_hash = $1
+		for update
+	`
+	val, err := db.SelectOne[RefreshToken](logContext, txContext, tx, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetRefreshTokenByHash] Error retrieving refresh token: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// UpdateRefreshTokenStatus Changes the status of one refresh token
+func (token *RefreshToken) UpdateRefreshTokenStatus(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "status", Value: token.Status})
+	var filters db.SqlData
+	filters = append(filters, db.SqlValue{Name: "id", Value: token.ID})
+	if err := db.Update(logContext, txContext, tx, "refresh_token", data, filters); err != nil {
+		logger.Error().Err(err).Msgf("[UpdateRefreshTokenStatus] Cannot update refresh token %d: %s", token.ID, err)
+		return err
+	}
+	return nil
+}
+
+// RevokeFamily Revokes every refresh token of the same family
+func (token *RefreshToken) RevokeFamily(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "status", Value: RefreshTokenRevoked})
+	var filters db.SqlData
+	filters = append(filters, db.SqlValue{Name: "family", Value: token.Family})
+	if err := db.Update(logContext, txContext, tx, "refresh_token", data, filters); err != nil {
+		logger.Error().Err(err).Msgf("[RevokeFamily] Cannot revoke refresh token family: %s", err)
+		return err
+	}
+	return nil
+}
+
+// RevokeUserRefreshTokens Revokes every refresh token of the user
+func (token *RefreshToken) RevokeUserRefreshTokens(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "status", Value: RefreshTokenRevoked})
+	var filters db.SqlData
+	filters = append(filters, db.SqlValue{Name: "user_id", Value: token.UserID})
+	if err := db.Update(logContext, txContext, tx, "refresh_token", data, filters); err != nil {
+		logger.Error().Err(err).Msgf("[RevokeUserRefreshTokens] Cannot revoke refresh tokens of user %d: %s", token.UserID, err)
+		return err
+	}
+	return nil
+}
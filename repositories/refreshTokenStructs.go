@@ -0,0 +1,20 @@
+package repositories
+
+// Refresh token status values
+const (
+	RefreshTokenActive  = "active"
+	RefreshTokenRotated = "rotated"
+	RefreshTokenRevoked = "revoked"
+)
+
+// RefreshToken table refresh_token on database
+type RefreshToken struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	UserID     int    `json:"user_id,omitempty" db:"user_id,omitempty"`
+	Family     string `json:"family,omitempty" db:"family,omitempty"`
+	TokenHash  string `json:"-" db:"token_hash,omitempty"`
+	Status     string `json:"status,omitempty" db:"status,omitempty"`
+	Tstampinit int64  `json:"tstampinit,omitempty" db:"tstampinit,omitempty"`
+	Tstampexp  int64  `json:"tstampexp,omitempty" db:"tstampexp,omitempty"`
+	Token      string `json:"refresh_token,omitempty" db:"-"`
+}
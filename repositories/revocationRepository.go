@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// InsertRevokedToken Revokes one access token
+func (revoked *RevokedToken) InsertRevokedToken(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "jti", Value: revoked.Jti})
+	data = append(data, db.SqlValue{Name: "user_id", Value: revoked.UserID})
+	data = append(data, db.SqlValue{Name: "tstampexp", Value: revoked.Tstampexp})
+	if err := db.Insert(logContext, txContext, tx, "revoked_token", data); err != nil {
+		logger.Error().Err(err).Msgf("[InsertRevokedToken] Cannot revoke token for user %d: %s", revoked.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// ListRevokedTokens Lists revoked tokens that are not expired yet
+func (revoked *RevokedToken) ListRevokedTokens(logContext *u.LoggerContext, dbContext *db.DatabaseContext, now int64) ([]RevokedToken, error) {
+	logger := zerolog.Ctx(*logContext)
+	data := db.SqlData{db.Filter("tstampexp", db.Ge, now)}
+	val, err := db.SelectWhere[RevokedToken](logContext, dbContext, nil, "select jti, user_id, tstampexp from revoked_token", data, "")
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ListRevokedTokens] Error listing revoked tokens: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// UpsertRevokedUser Revokes every token issued to the user up to Tstamprevoked
+func (revoked *RevokedUser) UpsertRevokedUser(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	query := `
+		insert into revoked_user (user_id, tstamprevoked) values ($1, $2)
+		on conflict (user_id) do update set tstamprevoked = excluded.tstamprevoked
+	`
+	ctx, cancel := db.QueryContext(txContext)
+	defer cancel()
+	_, err := (*tx).Exec(ctx, query, revoked.UserID, revoked.Tstamprevoked)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UpsertRevokedUser] Cannot revoke tokens of user %d: %s", revoked.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// ListRevokedUsers Lists users revoked after the given timestamp
+func (revoked *RevokedUser) ListRevokedUsers(logContext *u.LoggerContext, dbContext *db.DatabaseContext, since int64) ([]RevokedUser, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "tstamprevoked", Value: since})
+	query := `
+		select user_id, tstamprevoked from revoked_user
+		where tstamprevoked >= $1
+	`
+	val, err := db.SelectAll[RevokedUser](logContext, dbContext, nil, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ListRevokedUsers] Error listing revoked users: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
@@ -0,0 +1,14 @@
+package repositories
+
+// RevokedToken table revoked_token on database, one access token revoked by its jti
+type RevokedToken struct {
+	Jti       string `json:"jti,omitempty" db:"jti,omitempty"`
+	UserID    int    `json:"user_id,omitempty" db:"user_id,omitempty"`
+	Tstampexp int64  `json:"tstampexp,omitempty" db:"tstampexp,omitempty"`
+}
+
+// RevokedUser table revoked_user on database, every access token of the user issued up to Tstamprevoked is revoked
+type RevokedUser struct {
+	UserID        int   `json:"user_id,omitempty" db:"user_id,omitempty"`
+	Tstamprevoked int64 `json:"tstamprevoked,omitempty" db:"tstamprevoked,omitempty"`
+}
@@ -0,0 +1,22 @@
+package repositories
+
+// NewMemoryStores creates every store in memory, empty but for the default tenant, for tests
+func NewMemoryStores() Stores {
+	users := NewMemoryUserStore()
+	return Stores{
+		Transactor:     NewMemoryTransactor(),
+		Users:          users,
+		Batches:        NewMemoryBatchStore(),
+		Tokens:         NewMemoryTokenStore(),
+		RBAC:           NewMemoryRBACStore(users),
+		Audit:          NewMemoryAuditStore(),
+		Mfa:            NewMemoryMfaStore(),
+		LoginAttempts:  NewMemoryLoginAttemptStore(),
+		ApiKeys:        NewMemoryApiKeyStore(),
+		OAuthClients:   NewMemoryOAuthClientStore(),
+		Tenants:        NewMemoryTenantStore(),
+		Activations:    NewMemoryActivationStore(),
+		PasswordResets: NewMemoryPasswordResetStore(),
+		EmailChanges:   NewMemoryEmailChangeStore(),
+	}
+}
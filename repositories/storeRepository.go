@@ -0,0 +1,23 @@
+package repositories
+
+import "github.com/elnerribeiro/go-ws-db-auth-v2/db"
+
+// NewPostgresStores creates every store on the database
+func NewPostgresStores(database *db.DB) Stores {
+	return Stores{
+		Transactor:     NewPostgresTransactor(database),
+		Users:          NewPostgresUserStore(database),
+		Batches:        NewPostgresBatchStore(database),
+		Tokens:         NewPostgresTokenStore(database),
+		RBAC:           NewPostgresRBACStore(database),
+		Audit:          NewPostgresAuditStore(database),
+		Mfa:            NewPostgresMfaStore(database),
+		LoginAttempts:  NewPostgresLoginAttemptStore(database),
+		ApiKeys:        NewPostgresApiKeyStore(database),
+		OAuthClients:   NewPostgresOAuthClientStore(database),
+		Tenants:        NewPostgresTenantStore(database),
+		Activations:    NewPostgresActivationStore(),
+		PasswordResets: NewPostgresPasswordResetStore(),
+		EmailChanges:   NewPostgresEmailChangeStore(),
+	}
+}
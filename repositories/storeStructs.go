@@ -0,0 +1,20 @@
+package repositories
+
+// Stores every store of the services with the Transactor of their transactions, on the database (NewPostgresStores)
+// or in memory (NewMemoryStores)
+type Stores struct {
+	Transactor     Transactor
+	Users          UserStore
+	Batches        BatchStore
+	Tokens         TokenStore
+	RBAC           RBACStore
+	Audit          AuditStore
+	Mfa            MfaStore
+	LoginAttempts  LoginAttemptStore
+	ApiKeys        ApiKeyStore
+	OAuthClients   OAuthClientStore
+	Tenants        TenantStore
+	Activations    ActivationStore
+	PasswordResets PasswordResetStore
+	EmailChanges   EmailChangeStore
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"sort"
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryTenantStore TenantStore kept in memory, for tests, with transactions of MemoryTransactor. Writes are applied
+// at once and undone on rollback
+type MemoryTenantStore struct {
+	sync.Mutex
+	tenants map[int]Tenant
+	lastID  int
+}
+
+// NewMemoryTenantStore creates a MemoryTenantStore with only the default tenant, as dbinit creates it
+func NewMemoryTenantStore() *MemoryTenantStore {
+	return &MemoryTenantStore{tenants: map[int]Tenant{DefaultTenant: {ID: DefaultTenant, Name: "default"}}, lastID: DefaultTenant}
+}
+
+// ListTenants lists every tenant, by id
+func (store *MemoryTenantStore) ListTenants(logContext *u.LoggerContext) ([]Tenant, error) {
+	store.Lock()
+	defer store.Unlock()
+	var list []Tenant
+	for _, v := range store.tenants {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// GetTenantByID gets the tenant with the id of tenant
+func (store *MemoryTenantStore) GetTenantByID(logContext *u.LoggerContext, tenant *Tenant) (*Tenant, error) {
+	store.Lock()
+	defer store.Unlock()
+	if found, ok := store.tenants[tenant.ID]; ok {
+		return &found, nil
+	}
+	return nil, nil
+}
+
+// UpsertTenant inserts a tenant without id or renames the one with its id, renaming a missing one changes nothing
+func (store *MemoryTenantStore) UpsertTenant(logContext *u.LoggerContext, tx Transaction, tenant *Tenant) (*Tenant, error) {
+	store.Lock()
+	defer store.Unlock()
+	if tenant.ID == 0 {
+		store.lastID++
+		created := *tenant
+		created.ID = store.lastID
+		keepEntry(tx, store, store.tenants, created.ID)
+		store.tenants[created.ID] = created
+		return &created, nil
+	}
+	if found, ok := store.tenants[tenant.ID]; ok {
+		keepEntry(tx, store, store.tenants, tenant.ID)
+		found.Name = tenant.Name
+		store.tenants[tenant.ID] = found
+	}
+	return tenant, nil
+}
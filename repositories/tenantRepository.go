@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// ListTenants Lists all tenants
+func (tenant *Tenant) ListTenants(logContext *u.LoggerContext, dbContext *db.DatabaseContext) ([]Tenant, error) {
+	logger := zerolog.Ctx(*logContext)
+	val, err := db.SelectAll[Tenant](logContext, dbContext, nil, "select id, name, tstampinit from tenants order by id", nil)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ListTenants] Error listing tenants: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// GetTenantByID Retrieves a tenant by id, nil if not found
+func (tenant *Tenant) GetTenantByID(logContext *u.LoggerContext, dbContext *db.DatabaseContext) (*Tenant, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "id", Value: tenant.ID})
+	val, err := db.SelectOne[Tenant](logContext, dbContext, nil, "select id, name, tstampinit from tenants where id = $1", data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetTenantByID] Error retrieving tenant %d: %s", tenant.ID, err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// UpsertTenant Inserts or renames a tenant
+func (tenant *Tenant) UpsertTenant(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*Tenant, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "name", Value: tenant.Name})
+	if tenant.ID == 0 {
+		data = append(data, db.SqlValue{Name: "tstampinit", Value: tenant.Tstampinit})
+		res, err := db.InsertReturningPostgres[Tenant](logContext, txContext, tx, "tenants", data, "id")
+		if err != nil {
+			logger.Error().Err(err).Msgf("[UpsertTenant] Error inserting tenant %s: %s", tenant.Name, err)
+			return nil, err
+		}
+		return res, nil
+	}
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "id", Value: tenant.ID})
+	if err := db.Update(logContext, txContext, tx, "tenants", data, filter); err != nil {
+		logger.Error().Err(err).Msgf("[UpsertTenant] Error updating tenant %d: %s", tenant.ID, err)
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// PostgresTenantStore TenantStore on table tenants
+type PostgresTenantStore struct {
+	database *db.DB
+}
+
+// NewPostgresTenantStore creates a TenantStore on the database
+func NewPostgresTenantStore(database *db.DB) *PostgresTenantStore {
+	return &PostgresTenantStore{database: database}
+}
+
+// ListTenants lists every tenant, by id
+func (store *PostgresTenantStore) ListTenants(logContext *u.LoggerContext) ([]Tenant, error) {
+	return (&Tenant{}).ListTenants(logContext, store.database.Context(*logContext))
+}
+
+// GetTenantByID gets the tenant with the id of tenant
+func (store *PostgresTenantStore) GetTenantByID(logContext *u.LoggerContext, tenant *Tenant) (*Tenant, error) {
+	return tenant.GetTenantByID(logContext, store.database.Context(*logContext))
+}
+
+// UpsertTenant inserts a tenant without id or renames the one with its id
+func (store *PostgresTenantStore) UpsertTenant(logContext *u.LoggerContext, tx Transaction, tenant *Tenant) (*Tenant, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return tenant.UpsertTenant(logContext, txContext, pgTx)
+}
@@ -0,0 +1,32 @@
+package repositories
+
+import u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+
+// DefaultTenant tenant of users, batches and clients created before multi-tenancy, and of requests that name no tenant
+const DefaultTenant = 1
+
+// TenantStore keeps the tenants, on table tenants (PostgresTenantStore) or in memory (MemoryTenantStore). Writes take
+// the transaction of the caller
+type TenantStore interface {
+	// ListTenants lists every tenant, by id
+	ListTenants(logContext *u.LoggerContext) ([]Tenant, error)
+	// GetTenantByID gets the tenant with the id of tenant. nil if not found
+	GetTenantByID(logContext *u.LoggerContext, tenant *Tenant) (*Tenant, error)
+	// UpsertTenant inserts a tenant without id or renames the one with its id
+	UpsertTenant(logContext *u.LoggerContext, tx Transaction, tenant *Tenant) (*Tenant, error)
+}
+
+// Tenant table tenants on database
+type Tenant struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	Name       string `json:"name,omitempty" db:"name,omitempty"`
+	Tstampinit int64  `json:"tstampinit,omitempty" db:"tstampinit,omitempty"`
+}
+
+// TenantOrDefault Returns the tenant, or DefaultTenant when it is 0
+func TenantOrDefault(tenantID int) int {
+	if tenantID == 0 {
+		return DefaultTenant
+	}
+	return tenantID
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryTokenStore TokenStore kept in memory, for tests, with transactions of MemoryTransactor. Writes are applied at
+// once and undone on rollback
+type MemoryTokenStore struct {
+	sync.Mutex
+	refreshTokens map[int]RefreshToken
+	revokedTokens map[string]RevokedToken
+	revokedUsers  map[int]RevokedUser
+	lastID        int
+}
+
+// NewMemoryTokenStore creates an empty MemoryTokenStore
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{refreshTokens: map[int]RefreshToken{}, revokedTokens: map[string]RevokedToken{}, revokedUsers: map[int]RevokedUser{}}
+}
+
+// InsertRefreshToken stores a new refresh token
+func (store *MemoryTokenStore) InsertRefreshToken(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error {
+	store.Lock()
+	defer store.Unlock()
+	store.lastID++
+	created := *token
+	created.ID = store.lastID
+	created.Token = ""
+	keepEntry(tx, store, store.refreshTokens, created.ID)
+	store.refreshTokens[created.ID] = created
+	return nil
+}
+
+// GetRefreshTokenByHash gets the refresh token with the hash of token, nothing is locked
+func (store *MemoryTokenStore) GetRefreshTokenByHash(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) (*RefreshToken, error) {
+	store.Lock()
+	defer store.Unlock()
+	for _, v := range store.refreshTokens {
+		if v.TokenHash == token.TokenHash {
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpdateRefreshTokenStatus changes the status of one refresh token
+func (store *MemoryTokenStore) UpdateRefreshTokenStatus(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error {
+	store.setRefreshStatus(tx, token.Status, func(v RefreshToken) bool { return v.ID == token.ID })
+	return nil
+}
+
+// RevokeFamily revokes every refresh token of the family of token
+func (store *MemoryTokenStore) RevokeFamily(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error {
+	store.setRefreshStatus(tx, RefreshTokenRevoked, func(v RefreshToken) bool { return v.Family == token.Family })
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of the user of token
+func (store *MemoryTokenStore) RevokeUserRefreshTokens(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error {
+	store.setRefreshStatus(tx, RefreshTokenRevoked, func(v RefreshToken) bool { return v.UserID == token.UserID })
+	return nil
+}
+
+// InsertRevokedToken revokes one access token
+func (store *MemoryTokenStore) InsertRevokedToken(logContext *u.LoggerContext, tx Transaction, revoked *RevokedToken) error {
+	store.Lock()
+	defer store.Unlock()
+	keepEntry(tx, store, store.revokedTokens, revoked.Jti)
+	store.revokedTokens[revoked.Jti] = *revoked
+	return nil
+}
+
+// ListRevokedTokens lists the revoked access tokens not expired at now
+func (store *MemoryTokenStore) ListRevokedTokens(logContext *u.LoggerContext, now int64) ([]RevokedToken, error) {
+	store.Lock()
+	defer store.Unlock()
+	var list []RevokedToken
+	for _, v := range store.revokedTokens {
+		if v.Tstampexp >= now {
+			list = append(list, v)
+		}
+	}
+	return list, nil
+}
+
+// UpsertRevokedUser revokes every access token issued to the user up to revoked.Tstamprevoked
+func (store *MemoryTokenStore) UpsertRevokedUser(logContext *u.LoggerContext, tx Transaction, revoked *RevokedUser) error {
+	store.Lock()
+	defer store.Unlock()
+	keepEntry(tx, store, store.revokedUsers, revoked.UserID)
+	store.revokedUsers[revoked.UserID] = *revoked
+	return nil
+}
+
+// ListRevokedUsers lists the users revoked at or after since
+func (store *MemoryTokenStore) ListRevokedUsers(logContext *u.LoggerContext, since int64) ([]RevokedUser, error) {
+	store.Lock()
+	defer store.Unlock()
+	var list []RevokedUser
+	for _, v := range store.revokedUsers {
+		if v.Tstamprevoked >= since {
+			list = append(list, v)
+		}
+	}
+	return list, nil
+}
+
+// setRefreshStatus sets the status of the refresh tokens matching match
+func (store *MemoryTokenStore) setRefreshStatus(tx Transaction, status string, match func(v RefreshToken) bool) {
+	store.Lock()
+	defer store.Unlock()
+	for id, v := range store.refreshTokens {
+		if match(v) {
+			keepEntry(tx, store, store.refreshTokens, id)
+			v.Status = status
+			store.refreshTokens[id] = v
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// PostgresTokenStore TokenStore on tables refresh_token, revoked_token and revoked_user
+type PostgresTokenStore struct {
+	database *db.DB
+}
+
+// NewPostgresTokenStore creates a TokenStore on the database
+func NewPostgresTokenStore(database *db.DB) *PostgresTokenStore {
+	return &PostgresTokenStore{database: database}
+}
+
+// InsertRefreshToken stores a new refresh token
+func (store *PostgresTokenStore) InsertRefreshToken(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error {
+	txContext, pgTx := PostgresTx(tx)
+	return token.InsertRefreshToken(logContext, txContext, pgTx)
+}
+
+// GetRefreshTokenByHash gets and locks the refresh token with the hash of token
+func (store *PostgresTokenStore) GetRefreshTokenByHash(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) (*RefreshToken, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return token.GetRefreshTokenByHash(logContext, txContext, pgTx)
+}
+
+// UpdateRefreshTokenStatus changes the status of one refresh token
+func (store *PostgresTokenStore) UpdateRefreshTokenStatus(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error {
+	txContext, pgTx := PostgresTx(tx)
+	return token.UpdateRefreshTokenStatus(logContext, txContext, pgTx)
+}
+
+// RevokeFamily revokes every refresh token of the family of token
+func (store *PostgresTokenStore) RevokeFamily(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error {
+	txContext, pgTx := PostgresTx(tx)
+	return token.RevokeFamily(logContext, txContext, pgTx)
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of the user of token
+func (store *PostgresTokenStore) RevokeUserRefreshTokens(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error {
+	txContext, pgTx := PostgresTx(tx)
+	return token.RevokeUserRefreshTokens(logContext, txContext, pgTx)
+}
+
+// InsertRevokedToken revokes one access token
+func (store *PostgresTokenStore) InsertRevokedToken(logContext *u.LoggerContext, tx Transaction, revoked *RevokedToken) error {
+	txContext, pgTx := PostgresTx(tx)
+	return revoked.InsertRevokedToken(logContext, txContext, pgTx)
+}
+
+// ListRevokedTokens lists the revoked access tokens not expired at now
+func (store *PostgresTokenStore) ListRevokedTokens(logContext *u.LoggerContext, now int64) ([]RevokedToken, error) {
+	return (&RevokedToken{}).ListRevokedTokens(logContext, store.database.Context(*logContext), now)
+}
+
+// UpsertRevokedUser revokes every access token issued to the user up to revoked.Tstamprevoked
+func (store *PostgresTokenStore) UpsertRevokedUser(logContext *u.LoggerContext, tx Transaction, revoked *RevokedUser) error {
+	txContext, pgTx := PostgresTx(tx)
+	return revoked.UpsertRevokedUser(logContext, txContext, pgTx)
+}
+
+// ListRevokedUsers lists the users revoked at or after since
+func (store *PostgresTokenStore) ListRevokedUsers(logContext *u.LoggerContext, since int64) ([]RevokedUser, error) {
+	return (&RevokedUser{}).ListRevokedUsers(logContext, store.database.Context(*logContext), since)
+}
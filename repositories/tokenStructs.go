@@ -0,0 +1,27 @@
+package repositories
+
+import u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+
+// TokenStore keeps the refresh tokens and the revoked access tokens, on tables refresh_token, revoked_token and
+// revoked_user (PostgresTokenStore) or in memory (MemoryTokenStore). Writes take the transaction of the caller
+type TokenStore interface {
+	// InsertRefreshToken stores a new refresh token
+	InsertRefreshToken(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error
+	// GetRefreshTokenByHash gets the refresh token with the hash of token, locking it until the end of the
+	// transaction. nil if not found
+	GetRefreshTokenByHash(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) (*RefreshToken, error)
+	// UpdateRefreshTokenStatus changes the status of one refresh token
+	UpdateRefreshTokenStatus(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error
+	// RevokeFamily revokes every refresh token of the family of token
+	RevokeFamily(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error
+	// RevokeUserRefreshTokens revokes every refresh token of the user of token
+	RevokeUserRefreshTokens(logContext *u.LoggerContext, tx Transaction, token *RefreshToken) error
+	// InsertRevokedToken revokes one access token
+	InsertRevokedToken(logContext *u.LoggerContext, tx Transaction, revoked *RevokedToken) error
+	// ListRevokedTokens lists the revoked access tokens not expired at now
+	ListRevokedTokens(logContext *u.LoggerContext, now int64) ([]RevokedToken, error)
+	// UpsertRevokedUser revokes every access token issued to the user up to revoked.Tstamprevoked
+	UpsertRevokedUser(logContext *u.LoggerContext, tx Transaction, revoked *RevokedUser) error
+	// ListRevokedUsers lists the users revoked at or after since
+	ListRevokedUsers(logContext *u.LoggerContext, since int64) ([]RevokedUser, error)
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryMfaStore MfaStore kept in memory, for tests, with transactions of MemoryTransactor. Writes are applied at
+// once and undone on rollback
+type MemoryMfaStore struct {
+	sync.Mutex
+	totps  map[int]UserTotp //user id -> enrollment
+	codes  map[int]RecoveryCode
+	lastID int
+}
+
+// NewMemoryMfaStore creates an empty MemoryMfaStore
+func NewMemoryMfaStore() *MemoryMfaStore {
+	return &MemoryMfaStore{totps: map[int]UserTotp{}, codes: map[int]RecoveryCode{}}
+}
+
+// GetUserTotp gets the TOTP enrollment of the user, nothing is locked
+func (store *MemoryMfaStore) GetUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) (*UserTotp, error) {
+	store.Lock()
+	defer store.Unlock()
+	if found, ok := store.totps[totp.UserID]; ok {
+		return &found, nil
+	}
+	return nil, nil
+}
+
+// UpsertUserTotp stores a TOTP enrollment, replacing the previous one
+func (store *MemoryMfaStore) UpsertUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) error {
+	store.Lock()
+	defer store.Unlock()
+	keepEntry(tx, store, store.totps, totp.UserID)
+	store.totps[totp.UserID] = *totp
+	return nil
+}
+
+// UpdateUserTotp updates the status and last used step of a TOTP enrollment
+func (store *MemoryMfaStore) UpdateUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) error {
+	store.Lock()
+	defer store.Unlock()
+	if found, ok := store.totps[totp.UserID]; ok {
+		keepEntry(tx, store, store.totps, totp.UserID)
+		found.Status = totp.Status
+		found.LastStep = totp.LastStep
+		store.totps[totp.UserID] = found
+	}
+	return nil
+}
+
+// DeleteUserTotp removes the TOTP enrollment and the recovery codes of the user
+func (store *MemoryMfaStore) DeleteUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) error {
+	store.Lock()
+	defer store.Unlock()
+	store.deleteCodes(tx, totp.UserID)
+	keepEntry(tx, store, store.totps, totp.UserID)
+	delete(store.totps, totp.UserID)
+	return nil
+}
+
+// InsertRecoveryCode stores one recovery code
+func (store *MemoryMfaStore) InsertRecoveryCode(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) error {
+	store.Lock()
+	defer store.Unlock()
+	store.lastID++
+	created := *code
+	created.ID = store.lastID
+	keepEntry(tx, store, store.codes, created.ID)
+	store.codes[created.ID] = created
+	return nil
+}
+
+// GetRecoveryCode gets an active recovery code of the user by its hash, nothing is locked
+func (store *MemoryMfaStore) GetRecoveryCode(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) (*RecoveryCode, error) {
+	store.Lock()
+	defer store.Unlock()
+	for _, v := range store.codes {
+		if v.UserID == code.UserID && v.CodeHash == code.CodeHash && v.Status == RecoveryCodeActive {
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+// UseRecoveryCode marks a recovery code as used
+func (store *MemoryMfaStore) UseRecoveryCode(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) error {
+	store.Lock()
+	defer store.Unlock()
+	if found, ok := store.codes[code.ID]; ok {
+		keepEntry(tx, store, store.codes, code.ID)
+		found.Status = RecoveryCodeUsed
+		store.codes[code.ID] = found
+	}
+	return nil
+}
+
+// DeleteRecoveryCodes removes every recovery code of the user
+func (store *MemoryMfaStore) DeleteRecoveryCodes(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) error {
+	store.Lock()
+	defer store.Unlock()
+	store.deleteCodes(tx, code.UserID)
+	return nil
+}
+
+// deleteCodes removes every recovery code of the user. Must be called with the store locked
+func (store *MemoryMfaStore) deleteCodes(tx Transaction, userID int) {
+	for id, v := range store.codes {
+		if v.UserID == userID {
+			keepEntry(tx, store, store.codes, id)
+			delete(store.codes, id)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// GetUserTotp Retrieves the TOTP enrollment of an user, locking it when inside a transaction
+func (totp *UserTotp) GetUserTotp(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*UserTotp, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "user_id", Value: totp.UserID})
+	query := `
+		select user_id, secret, status, last_step, tstampinit from user_totp
+		where user_id = $1
+	`
+	if tx != nil {
+		query += " for update"
+	}
+	val, err := db.SelectOne[UserTotp](logContext, txContext, tx, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetUserTotp] Error retrieving TOTP of user %d: %s", totp.UserID, err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// UpsertUserTotp Stores a TOTP enrollment, replacing the previous one
+func (totp *UserTotp) UpsertUserTotp(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	query := `
+		insert into user_totp (user_id, secret, status, last_step, tstampinit) values ($1, $2, $3, $4, $5)
+		on conflict (user_id) do update set secret = excluded.secret, status = excluded.status,
+			last_step = excluded.last_step, tstampinit = excluded.tstampinit
+	`
+	ctx, cancel := db.QueryContext(txContext)
+	defer cancel()
+	_, err := (*tx).Exec(ctx, query, totp.UserID, totp.Secret, totp.Status, totp.LastStep, totp.Tstampinit)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UpsertUserTotp] Error storing TOTP of user %d: %s", totp.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// UpdateUserTotp Updates status and last used step of a TOTP enrollment
+func (totp *UserTotp) UpdateUserTotp(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "status", Value: totp.Status})
+	data = append(data, db.SqlValue{Name: "last_step", Value: totp.LastStep})
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "user_id", Value: totp.UserID})
+	if err := db.Update(logContext, txContext, tx, "user_totp", data, filter); err != nil {
+		logger.Error().Err(err).Msgf("[UpdateUserTotp] Error updating TOTP of user %d: %s", totp.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteUserTotp Removes the TOTP enrollment and the recovery codes of an user
+func (totp *UserTotp) DeleteUserTotp(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "user_id", Value: totp.UserID})
+	if err := db.Delete(logContext, txContext, tx, "recovery_code", filter); err != nil {
+		logger.Error().Err(err).Msgf("[DeleteUserTotp] Error removing recovery codes of user %d: %s", totp.UserID, err)
+		return err
+	}
+	if err := db.Delete(logContext, txContext, tx, "user_totp", filter); err != nil {
+		logger.Error().Err(err).Msgf("[DeleteUserTotp] Error removing TOTP of user %d: %s", totp.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// InsertRecoveryCode Stores one recovery code
+func (code *RecoveryCode) InsertRecoveryCode(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "user_id", Value: code.UserID})
+	data = append(data, db.SqlValue{Name: "code_hash", Value: code.CodeHash})
+	data = append(data, db.SqlValue{Name: "status", Value: code.Status})
+	if err := db.Insert(logContext, txContext, tx, "recovery_code", data); err != nil {
+		logger.Error().Err(err).Msgf("[InsertRecoveryCode] Error storing recovery code of user %d: %s", code.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// GetRecoveryCode Retrieves and locks an active recovery code of an user by its hash
+func (code *RecoveryCode) GetRecoveryCode(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*RecoveryCode, error) {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "user_id", Value: code.UserID})
+	data = append(data, db.SqlValue{Name: "code_hash", Value: code.CodeHash})
+	data = append(data, db.SqlValue{Name: "status", Value: RecoveryCodeActive})
+	query := `
+		select id, user_id, code_hash, status from recovery_code
+		where user_id = $1 and code_hash = $2 and status = $3
+		for update
+	`
+	val, err := db.SelectOne[RecoveryCode](logContext, txContext, tx, query, data)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetRecoveryCode] Error retrieving recovery code: %s", err)
+		return nil, err
+	}
+	return val, nil
+}
+
+// UseRecoveryCode Marks a recovery code as used
+func (code *RecoveryCode) UseRecoveryCode(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var data db.SqlData
+	data = append(data, db.SqlValue{Name: "status", Value: RecoveryCodeUsed})
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "id", Value: code.ID})
+	if err := db.Update(logContext, txContext, tx, "recovery_code", data, filter); err != nil {
+		logger.Error().Err(err).Msgf("[UseRecoveryCode] Error updating recovery code %d: %s", code.ID, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteRecoveryCodes Removes every recovery code of an user
+func (code *RecoveryCode) DeleteRecoveryCodes(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	var filter db.SqlData
+	filter = append(filter, db.SqlValue{Name: "user_id", Value: code.UserID})
+	if err := db.Delete(logContext, txContext, tx, "recovery_code", filter); err != nil {
+		logger.Error().Err(err).Msgf("[DeleteRecoveryCodes] Error removing recovery codes of user %d: %s", code.UserID, err)
+		return err
+	}
+	return nil
+}
+
+// PostgresMfaStore MfaStore on tables user_totp and recovery_code
+type PostgresMfaStore struct {
+	database *db.DB
+}
+
+// NewPostgresMfaStore creates an MfaStore on the database
+func NewPostgresMfaStore(database *db.DB) *PostgresMfaStore {
+	return &PostgresMfaStore{database: database}
+}
+
+// GetUserTotp gets the TOTP enrollment of the user, locking it when inside a transaction
+func (store *PostgresMfaStore) GetUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) (*UserTotp, error) {
+	txContext, pgTx := postgresContext(logContext, store.database, tx)
+	return totp.GetUserTotp(logContext, txContext, pgTx)
+}
+
+// UpsertUserTotp stores a TOTP enrollment, replacing the previous one
+func (store *PostgresMfaStore) UpsertUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) error {
+	txContext, pgTx := PostgresTx(tx)
+	return totp.UpsertUserTotp(logContext, txContext, pgTx)
+}
+
+// UpdateUserTotp updates the status and last used step of a TOTP enrollment
+func (store *PostgresMfaStore) UpdateUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) error {
+	txContext, pgTx := PostgresTx(tx)
+	return totp.UpdateUserTotp(logContext, txContext, pgTx)
+}
+
+// DeleteUserTotp removes the TOTP enrollment and the recovery codes of the user
+func (store *PostgresMfaStore) DeleteUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) error {
+	txContext, pgTx := PostgresTx(tx)
+	return totp.DeleteUserTotp(logContext, txContext, pgTx)
+}
+
+// InsertRecoveryCode stores one recovery code
+func (store *PostgresMfaStore) InsertRecoveryCode(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) error {
+	txContext, pgTx := PostgresTx(tx)
+	return code.InsertRecoveryCode(logContext, txContext, pgTx)
+}
+
+// GetRecoveryCode gets and locks an active recovery code of the user by its hash
+func (store *PostgresMfaStore) GetRecoveryCode(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) (*RecoveryCode, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return code.GetRecoveryCode(logContext, txContext, pgTx)
+}
+
+// UseRecoveryCode marks a recovery code as used
+func (store *PostgresMfaStore) UseRecoveryCode(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) error {
+	txContext, pgTx := PostgresTx(tx)
+	return code.UseRecoveryCode(logContext, txContext, pgTx)
+}
+
+// DeleteRecoveryCodes removes every recovery code of the user
+func (store *PostgresMfaStore) DeleteRecoveryCodes(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) error {
+	txContext, pgTx := PostgresTx(tx)
+	return code.DeleteRecoveryCodes(logContext, txContext, pgTx)
+}
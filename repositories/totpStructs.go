@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// TOTP status values
+const (
+	TotpPending = "pending"
+	TotpActive  = "active"
+)
+
+// Recovery code status values
+const (
+	RecoveryCodeActive = "active"
+	RecoveryCodeUsed   = "used"
+)
+
+// MfaAudience audience of MFA challenge tokens, never accepted as access tokens
+const MfaAudience = "mfa"
+
+// MfaStore keeps the TOTP enrollments and recovery codes of users, on tables user_totp and recovery_code
+// (PostgresMfaStore) or in memory (MemoryMfaStore). Writes take the transaction of the caller
+type MfaStore interface {
+	// GetUserTotp gets the TOTP enrollment of the user of totp, locking it when inside a transaction. nil if none
+	GetUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) (*UserTotp, error)
+	// UpsertUserTotp stores a TOTP enrollment, replacing the previous one
+	UpsertUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) error
+	// UpdateUserTotp updates the status and last used step of a TOTP enrollment
+	UpdateUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) error
+	// DeleteUserTotp removes the TOTP enrollment and the recovery codes of the user of totp
+	DeleteUserTotp(logContext *u.LoggerContext, tx Transaction, totp *UserTotp) error
+	// InsertRecoveryCode stores one recovery code
+	InsertRecoveryCode(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) error
+	// GetRecoveryCode gets and locks the active recovery code of the user with the hash of code. nil if none
+	GetRecoveryCode(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) (*RecoveryCode, error)
+	// UseRecoveryCode marks a recovery code as used
+	UseRecoveryCode(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) error
+	// DeleteRecoveryCodes removes every recovery code of the user of code
+	DeleteRecoveryCodes(logContext *u.LoggerContext, tx Transaction, code *RecoveryCode) error
+}
+
+// UserTotp table user_totp on database
+type UserTotp struct {
+	UserID     int    `json:"user_id,omitempty" db:"user_id,omitempty"`
+	Secret     string `json:"-" db:"secret,omitempty"`
+	Status     string `json:"status,omitempty" db:"status,omitempty"`
+	LastStep   int64  `json:"-" db:"last_step,omitempty"`
+	Tstampinit int64  `json:"tstampinit,omitempty" db:"tstampinit,omitempty"`
+}
+
+// RecoveryCode table recovery_code on database
+type RecoveryCode struct {
+	ID       int    `json:"id,omitempty" db:"id,omitempty"`
+	UserID   int    `json:"user_id,omitempty" db:"user_id,omitempty"`
+	CodeHash string `json:"-" db:"code_hash,omitempty"`
+	Status   string `json:"status,omitempty" db:"status,omitempty"`
+}
+
+// TotpEnrollment data returned when enrolling TOTP, shown only once
+type TotpEnrollment struct {
+	URI           string   `json:"uri"`
+	Secret        string   `json:"secret"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MfaRequest body of the MFA calls, code may be a TOTP code or a recovery code
+type MfaRequest struct {
+	MfaToken string `json:"mfa_token,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+// MfaChallenge JWT returned by the first login step of users with MFA enabled
+type MfaChallenge struct {
+	UserID int
+	Email  string
+	jwt.RegisteredClaims
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// MemoryTransactor Transactor of the memory stores. Writes are applied at once and undone by Rollback, so they are
+// seen by other transactions before the commit, and nothing is locked
+type MemoryTransactor struct{}
+
+// MemoryTransaction a Transaction of MemoryTransactor, keeping how to undo each write made on it
+type MemoryTransaction struct {
+	sync.Mutex
+	tenantID int
+	undo     []func()
+	done     bool
+}
+
+// NewMemoryTransactor creates a Transactor of the memory stores
+func NewMemoryTransactor() *MemoryTransactor {
+	return &MemoryTransactor{}
+}
+
+// Begin starts a transaction on every tenant
+func (transactor *MemoryTransactor) Begin(logContext *u.LoggerContext) (Transaction, error) {
+	return &MemoryTransaction{}, nil
+}
+
+// BeginTenant starts a transaction limited to the tenant
+func (transactor *MemoryTransactor) BeginTenant(logContext *u.LoggerContext, tenantID int) (Transaction, error) {
+	return &MemoryTransaction{tenantID: tenantID}, nil
+}
+
+// TenantID the tenant the transaction is limited to, 0 when it works on every tenant
+func (t *MemoryTransaction) TenantID() int {
+	return t.tenantID
+}
+
+// Commit keeps the writes made on the transaction, it never fails
+func (t *MemoryTransaction) Commit(logContext *u.LoggerContext) error {
+	t.Lock()
+	defer t.Unlock()
+	t.done = true
+	t.undo = nil
+	return nil
+}
+
+// Rollback undoes the writes made on the transaction, newest first, unless already committed
+func (t *MemoryTransaction) Rollback(logContext *u.LoggerContext) {
+	t.Lock()
+	undo := t.undo
+	if t.done {
+		undo = nil
+	}
+	t.done = true
+	t.undo = nil
+	t.Unlock()
+	for i := len(undo) - 1; i >= 0; i-- { //Without the transaction locked, undoing locks the stores
+		undo[i]()
+	}
+}
+
+// onRollback registers how to undo a write made on the transaction
+func (t *MemoryTransaction) onRollback(undo func()) {
+	t.Lock()
+	defer t.Unlock()
+	if !t.done {
+		t.undo = append(t.undo, undo)
+	}
+}
+
+// keepEntry Registers on tx how to restore the entry of key on m as it is now, about to be changed. Must be called
+// with the store locked, lock is the lock of the store. Writes outside memory transactions can't be undone
+func keepEntry[K comparable, V any](tx Transaction, lock sync.Locker, m map[K]V, key K) {
+	t, ok := tx.(*MemoryTransaction)
+	if !ok {
+		return
+	}
+	old, found := m[key]
+	t.onRollback(func() {
+		lock.Lock()
+		defer lock.Unlock()
+		if found {
+			m[key] = old
+		} else {
+			delete(m, key)
+		}
+	})
+}
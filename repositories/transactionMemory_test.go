@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+func TestMemoryTransactionRollback(t *testing.T) {
+	_, logContext := u.GetLoggerAndContext()
+	stores := NewMemoryStores()
+	tx, _ := stores.Transactor.Begin(logContext)
+	kept, err := stores.Users.Upsert(logContext, tx, &User{Email: "kept@example.com", Name: "Kept"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit(logContext)
+	tx.Rollback(logContext) //as deferred after the commit, changes nothing
+
+	tx, _ = stores.Transactor.Begin(logContext)
+	if _, err := stores.Users.Upsert(logContext, tx, &User{ID: kept.ID, Name: "Changed"}); err != nil {
+		t.Fatal(err)
+	}
+	added, err := stores.Users.Upsert(logContext, tx, &User{Email: "added@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Tokens.UpsertRevokedUser(logContext, tx, &RevokedUser{UserID: kept.ID, Tstamprevoked: 1}); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback(logContext)
+
+	if found, err := stores.Users.GetUserByID(logContext, &User{ID: kept.ID}); err != nil || found.Name != "Kept" {
+		t.Errorf("kept user after rollback: %+v, %v", found, err)
+	}
+	if _, err := stores.Users.GetUserByID(logContext, &User{ID: added.ID}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("added user after rollback: %v, want sql.ErrNoRows", err)
+	}
+	if revoked, _ := stores.Tokens.ListRevokedUsers(logContext, 0); len(revoked) != 0 {
+		t.Errorf("revoked users after rollback: %+v", revoked)
+	}
+}
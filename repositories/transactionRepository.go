@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// PostgresTransactor Transactor on the database, tenant transactions are the ones of db.GetTenantTransaction
+type PostgresTransactor struct {
+	database *db.DB
+}
+
+// postgresTransaction a Transaction of PostgresTransactor, a pgx transaction and its context
+type postgresTransaction struct {
+	txContext *db.DatabaseContext
+	tx        *pgx.Tx
+	tenantID  int
+}
+
+// NewPostgresTransactor creates a Transactor on the database
+func NewPostgresTransactor(database *db.DB) *PostgresTransactor {
+	return &PostgresTransactor{database: database}
+}
+
+// Begin starts a database transaction on every tenant
+func (transactor *PostgresTransactor) Begin(logContext *u.LoggerContext) (Transaction, error) {
+	return transactor.BeginTenant(logContext, 0)
+}
+
+// BeginTenant starts a database transaction limited to the tenant by row level security
+func (transactor *PostgresTransactor) BeginTenant(logContext *u.LoggerContext, tenantID int) (Transaction, error) {
+	logger := zerolog.Ctx(*logContext)
+	tx, txContext, err := transactor.database.GetTenantTransaction(*logContext, tenantID)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[BeginTenant] Error starting transaction: %s", err)
+		return nil, err
+	}
+	return &postgresTransaction{txContext: txContext, tx: tx, tenantID: tenantID}, nil
+}
+
+// TenantID the tenant the transaction is limited to, 0 when it works on every tenant
+func (t *postgresTransaction) TenantID() int {
+	return t.tenantID
+}
+
+// Commit commits the transaction
+func (t *postgresTransaction) Commit(logContext *u.LoggerContext) error {
+	return db.Commit(logContext, t.txContext, t.tx)
+}
+
+// Rollback rolls the transaction back, unless already committed
+func (t *postgresTransaction) Rollback(logContext *u.LoggerContext) {
+	db.Rollback(logContext, t.txContext, t.tx)
+}
+
+// PostgresTx Returns the context and pgx transaction of a transaction of PostgresTransactor, for the repositories
+// working on pgx directly. Both are nil on other transactions
+func PostgresTx(tx Transaction) (*db.DatabaseContext, *pgx.Tx) {
+	if t, ok := tx.(*postgresTransaction); ok {
+		return t.txContext, t.tx
+	}
+	return nil, nil
+}
+
+// postgresContext the context and pgx transaction of tx, or the context of the pool of the database without one
+func postgresContext(logContext *u.LoggerContext, database *db.DB, tx Transaction) (*db.DatabaseContext, *pgx.Tx) {
+	if tx == nil {
+		return database.Context(*logContext), nil
+	}
+	return PostgresTx(tx)
+}
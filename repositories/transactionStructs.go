@@ -0,0 +1,31 @@
+package repositories
+
+import u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+
+// Transaction a unit of work of the stores, their writes on it commit or roll back together. A tenant transaction
+// only sees and changes rows of its tenant. Rollback after Commit does nothing, so it can always be deferred
+type Transaction interface {
+	// TenantID the tenant the transaction is limited to, 0 when it works on every tenant
+	TenantID() int
+	// Commit commits the writes made on the transaction. On error nothing was committed, as when the context of the
+	// request was cancelled, and the caller must not report success
+	Commit(logContext *u.LoggerContext) error
+	// Rollback discards the writes made on the transaction, unless already committed
+	Rollback(logContext *u.LoggerContext)
+}
+
+// Transactor starts the transactions of the stores, on the database (PostgresTransactor) or in memory (MemoryTransactor)
+type Transactor interface {
+	// Begin starts a transaction on every tenant
+	Begin(logContext *u.LoggerContext) (Transaction, error)
+	// BeginTenant starts a transaction limited to the tenant, on every tenant when tenantID is 0
+	BeginTenant(logContext *u.LoggerContext, tenantID int) (Transaction, error)
+}
+
+// tenantOf the tenant of the transaction, 0 without one
+func tenantOf(tx Transaction) int {
+	if tx == nil {
+		return 0
+	}
+	return tx.TenantID()
+}
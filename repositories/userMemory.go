@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// ErrEmailTaken the email already belongs to another user of the tenant, as the unique key of user_db
+var ErrEmailTaken = errors.New("email already taken on the tenant")
+
+// MemoryUserStore UserStore kept in memory, for tests, with transactions of MemoryTransactor. Writes are applied at
+// once and undone on rollback. A tenant transaction only changes users of its tenant, as row level security does
+type MemoryUserStore struct {
+	sync.Mutex
+	users  map[int]User
+	lastID int
+}
+
+// NewMemoryUserStore creates an empty MemoryUserStore
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: map[int]User{}}
+}
+
+// ListUsers lists the users of the tenant of user, every user when it has no tenant
+func (store *MemoryUserStore) ListUsers(logContext *u.LoggerContext, user *User) ([]User, error) {
+	store.Lock()
+	defer store.Unlock()
+	var list []User
+	for _, v := range store.users {
+		if user.TenantID == 0 || v.TenantID == user.TenantID {
+			v.Password = ""
+			list = append(list, v)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// GetUserByID gets an user by ID, only inside the tenant of user when it has one
+func (store *MemoryUserStore) GetUserByID(logContext *u.LoggerContext, user *User) (*User, error) {
+	store.Lock()
+	defer store.Unlock()
+	account, ok := store.users[user.ID]
+	if !ok || (user.TenantID != 0 && account.TenantID != user.TenantID) {
+		return nil, sql.ErrNoRows
+	}
+	account.Password = ""
+	return &account, nil
+}
+
+// GetUserForUpdate gets an user by ID if the transaction can see it. Writes are applied at once, so nothing is locked
+func (store *MemoryUserStore) GetUserForUpdate(logContext *u.LoggerContext, tx Transaction, user *User) (*User, error) {
+	store.Lock()
+	defer store.Unlock()
+	account, ok := store.users[user.ID]
+	if !ok || !store.visible(tx, account) {
+		return nil, sql.ErrNoRows
+	}
+	account.Password = ""
+	return &account, nil
+}
+
+// GetUserByEmail gets an user by email inside the tenant of user or the default tenant
+func (store *MemoryUserStore) GetUserByEmail(logContext *u.LoggerContext, user *User, password bool) (*User, error) {
+	store.Lock()
+	defer store.Unlock()
+	account := store.findEmail(TenantOrDefault(user.TenantID), user.Email)
+	if account == nil {
+		return nil, sql.ErrNoRows
+	}
+	found := *account
+	if !password {
+		found.Password = ""
+	}
+	return &found, nil
+}
+
+// Upsert inserts or updates an user, hashing the password
+func (store *MemoryUserStore) Upsert(logContext *u.LoggerContext, tx Transaction, user *User) (*User, error) {
+	if user.Password != "" { //Never store the password as sent by the client
+		hash, err := u.HashPassword(user.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = hash
+	}
+	store.Lock()
+	defer store.Unlock()
+	if user.ID == 0 {
+		account := *user
+		if account.TenantID == 0 { //As the default of tenant_id: the tenant of the transaction or the default tenant
+			account.TenantID = TenantOrDefault(tenantOf(tx))
+		}
+		if !store.visible(tx, account) {
+			return nil, errors.New("user not on the tenant of the transaction")
+		}
+		if account.Status == "" {
+			account.Status = UserActive
+		}
+		if store.findEmail(account.TenantID, account.Email) != nil {
+			return nil, ErrEmailTaken
+		}
+		store.lastID++
+		account.ID = store.lastID
+		keepEntry(tx, store, store.users, account.ID)
+		store.users[account.ID] = account
+		user.ID = account.ID
+		user.Password = ""
+		return user, nil
+	}
+
+	account, ok := store.users[user.ID]
+	if ok && store.visible(tx, account) { //Updating a missing user changes nothing
+		if user.Email != "" && !strings.EqualFold(user.Email, account.Email) {
+			if store.findEmail(account.TenantID, user.Email) != nil {
+				return nil, ErrEmailTaken
+			}
+			account.Email = user.Email
+		}
+		if user.Name != "" {
+			account.Name = user.Name
+		}
+		if user.Role != "" {
+			account.Role = user.Role
+		}
+		if user.Status != "" {
+			account.Status = user.Status
+		}
+		if user.Password != "" {
+			account.Password = user.Password
+		}
+		keepEntry(tx, store, store.users, account.ID)
+		store.users[account.ID] = account
+	}
+	user.Password = ""
+	return user, nil
+}
+
+// UpdatePassword hashes and stores a new password for the user
+func (store *MemoryUserStore) UpdatePassword(logContext *u.LoggerContext, tx Transaction, user *User, password string) error {
+	hash, err := u.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return store.update(tx, user.ID, func(account *User) error {
+		account.Password = hash
+		return nil
+	})
+}
+
+// UpdateEmail changes the email of the user
+func (store *MemoryUserStore) UpdateEmail(logContext *u.LoggerContext, tx Transaction, user *User) error {
+	return store.update(tx, user.ID, func(account *User) error {
+		if other := store.findEmail(account.TenantID, user.Email); other != nil && other.ID != account.ID {
+			return ErrEmailTaken
+		}
+		account.Email = user.Email
+		return nil
+	})
+}
+
+// Delete deletes the user
+func (store *MemoryUserStore) Delete(logContext *u.LoggerContext, tx Transaction, user *User) error {
+	store.Lock()
+	defer store.Unlock()
+	if account, ok := store.users[user.ID]; ok && store.visible(tx, account) {
+		keepEntry(tx, store, store.users, user.ID)
+		delete(store.users, user.ID)
+	}
+	return nil
+}
+
+// update applies change to the user, if it exists and the transaction can see it
+func (store *MemoryUserStore) update(tx Transaction, id int, change func(account *User) error) error {
+	store.Lock()
+	defer store.Unlock()
+	account, ok := store.users[id]
+	if !ok || !store.visible(tx, account) {
+		return nil
+	}
+	if err := change(&account); err != nil {
+		return err
+	}
+	keepEntry(tx, store, store.users, id)
+	store.users[id] = account
+	return nil
+}
+
+// visible a tenant transaction only sees users of its tenant
+func (store *MemoryUserStore) visible(tx Transaction, account User) bool {
+	tenantID := tenantOf(tx)
+	return tenantID == 0 || account.TenantID == tenantID
+}
+
+// findEmail finds an user of the tenant by email, ignoring case. Must be called with the store locked
+func (store *MemoryUserStore) findEmail(tenantID int, email string) *User {
+	for _, v := range store.users {
+		if v.TenantID == tenantID && strings.EqualFold(v.Email, email) {
+			return &v
+		}
+	}
+	return nil
+}
@@ -2,17 +2,21 @@ package repositories
 
 import (
 	"database/sql"
+	"strings"
+
 	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
 	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
 	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 )
 
-// ListUsers Lists all users
-func (user *User) ListUsers(logContext *u.LoggerContext) ([]User, error) {
+// users Rows of user_db
+var users = db.NewRepository[User]("user_db", "id")
+
+// ListUsers Lists all users of the tenant of user, every user when it has no tenant
+func (user *User) ListUsers(logContext *u.LoggerContext, dbContext *db.DatabaseContext) ([]User, error) {
 	logger := zerolog.Ctx(*logContext)
-	var data db.SqlData
-	val, err := db.SelectAll[User](logContext, nil, nil, "select id, email, password as password, role from user_db", data)
+	val, err := users.List(logContext, db.TenantContext(dbContext, user.TenantID), nil, db.ListOptions{Sort: []db.Sort{{Column: "id"}}})
 	if err != nil {
 		logger.Error().Err(err).Msgf("[ListUsers] Error listing users: %s", err)
 		return nil, err
@@ -36,10 +40,16 @@ func (user *User) ListUsers(logContext *u.LoggerContext) ([]User, error) {
 func (user *User) Upsert(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*User, error) {
 	logger := zerolog.Ctx(*logContext)
 
-	var data *db.SqlData
-	data = user.UserToData()
+	if user.Password != "" { //Never store the password as sent by the client
+		hash, err := u.HashPassword(user.Password)
+		if err != nil {
+			logger.Error().Err(err).Msgf("[Upsert] Error hashing password: %s", err)
+			return nil, err
+		}
+		user.Password = hash
+	}
 	if user.ID == 0 {
-		res, err := db.InsertReturningPostgres[User](logContext, txContext, tx, "user_db", *data, "id")
+		res, err := users.Create(logContext, txContext, tx, user)
 		if err != nil {
 			logger.Error().Err(err).Msgf("[Upsert] Error inserting user: %s", err)
 			return nil, err
@@ -49,106 +59,150 @@ func (user *User) Upsert(logContext *u.LoggerContext, txContext *db.DatabaseCont
 		return user, nil
 	}
 
-	var filter db.SqlData
-	paramsValueFilter := db.SqlValue{Name: "id", Value: user.ID}
-	filter = append(filter, paramsValueFilter)
-	err2 := db.Update(logContext, txContext, tx, "user_db", *data, filter)
-	if err2 != nil {
-		logger.Error().Err(err2).Msgf("[Upsert] Error updating user: %s", err2)
-		return nil, err2
+	if err := users.Patch(logContext, txContext, tx, user); err != nil {
+		logger.Error().Err(err).Msgf("[Upsert] Error updating user: %s", err)
+		return nil, err
 	}
 	user.Password = ""
 	return user, nil
 }
 
+// UpdatePassword Hashes and stores a new password for the user
+func (user *User) UpdatePassword(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx, password string) error {
+	logger := zerolog.Ctx(*logContext)
+	hash, err := u.HashPassword(password)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UpdatePassword] Error hashing password: %s", err)
+		return err
+	}
+	if err := users.Patch(logContext, txContext, tx, &User{ID: user.ID, Password: hash}, "password"); err != nil {
+		logger.Error().Err(err).Msgf("[UpdatePassword] Error updating password of user %d: %s", user.ID, err)
+		return err
+	}
+	return nil
+}
+
+// UpdateEmail Changes the email of the user
+func (user *User) UpdateEmail(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
+	logger := zerolog.Ctx(*logContext)
+	if err := users.Patch(logContext, txContext, tx, &User{ID: user.ID, Email: user.Email}, "email"); err != nil {
+		logger.Error().Err(err).Msgf("[UpdateEmail] Error updating email of user %d: %s", user.ID, err)
+		return err
+	}
+	return nil
+}
+
 // Delete Deletes an user
 func (user *User) Delete(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) error {
-	var filter db.SqlData
-	paramsValue := db.SqlValue{Name: "id", Value: user.ID}
-	filter = append(filter, paramsValue)
-	err := db.Delete(logContext, txContext, tx, "user_db", filter)
-	return err
+	return users.Delete(logContext, txContext, tx, user.ID)
 }
 
-// UserToDados Fills a map containing the struct User
-func (user *User) UserToData() *db.SqlData {
-	var paramsQuery db.SqlData
-
-	if user.ID != 0 {
-		paramsValue := db.SqlValue{Name: "id", Value: user.ID}
-		paramsQuery = append(paramsQuery, paramsValue)
-	}
-	if user.Email != "" {
-		paramsValue := db.SqlValue{Name: "email", Value: user.Email}
-		paramsQuery = append(paramsQuery, paramsValue)
-	}
-	if user.Role != "" {
-		paramsValue := db.SqlValue{Name: "role", Value: user.Role}
-		paramsQuery = append(paramsQuery, paramsValue)
+// GetUserByID Get an user by ID, only inside the tenant of user when it has one
+func (user *User) GetUserByID(logContext *u.LoggerContext, dbContext *db.DatabaseContext) (*User, error) {
+	logger := zerolog.Ctx(*logContext)
+	account, err := users.Get(logContext, db.TenantContext(dbContext, user.TenantID), nil, user.ID)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[GetUserByID] Error retrieving user: %s", err)
+		return nil, err
 	}
-	if user.Password != "" {
-		paramsValue := db.SqlValue{Name: "password", Value: user.Password}
-		paramsQuery = append(paramsQuery, paramsValue)
+	if account == nil || account.Email == "" { //User not found!
+		return nil, sql.ErrNoRows
 	}
-	return &paramsQuery
+	account.Password = ""
+	return account, nil
 }
 
-// GetUserByID Get an user by ID
-func (user *User) GetUserByID(logContext *u.LoggerContext) (*User, error) {
+// GetUserForUpdate Get an user by ID on the transaction, locking it until its end
+func (user *User) GetUserForUpdate(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*User, error) {
 	logger := zerolog.Ctx(*logContext)
-	var data db.SqlData
-	paramsValue := db.SqlValue{Name: "id", Value: user.ID}
-	data = append(data, paramsValue)
-	query := `
-		select id, email, password as password, role from user_db
-		where id = $1
-	`
-	val, err := getUser(logContext, data, query)
+	filters := db.SqlData{db.Filter("id", db.Eq, user.ID)}
+	val, err := users.List(logContext, txContext, tx, db.ListOptions{Filters: filters, Limit: 1, ForUpdate: true})
 	if err != nil {
-		logger.Error().Err(err).Msgf("[GetUserByID] Error retrieving user: %s", err)
+		logger.Error().Err(err).Msgf("[GetUserForUpdate] Error retrieving user: %s", err)
 		return nil, err
 	}
-	account := val.(*User)
-	if account.Email == "" { //User not found!
+	if len(val) == 0 { //User not found!
 		return nil, sql.ErrNoRows
 	}
+	account := &val[0]
 	account.Password = ""
 	return account, nil
 }
 
-// GetUserByEmail Get an user by email
-func (user *User) GetUserByEmail(logContext *u.LoggerContext, password bool) (*User, error) {
+// GetUserByEmail Get an user by email, inside the tenant of user or the default tenant. Emails are unique per tenant
+func (user *User) GetUserByEmail(logContext *u.LoggerContext, dbContext *db.DatabaseContext, password bool) (*User, error) {
 	logger := zerolog.Ctx(*logContext)
-	var data db.SqlData
-	paramsValue := db.SqlValue{Name: "email", Value: user.Email}
-	data = append(data, paramsValue)
-	query := `
-		select id, email, password as password, role from user_db
-		where email = lower($1)
-	`
-	val, err := getUser(logContext, data, query)
+	filters := db.SqlData{
+		db.Filter("email", db.Eq, strings.ToLower(user.Email)),
+		db.Filter("tenant_id", db.Eq, TenantOrDefault(user.TenantID)),
+	}
+	val, err := users.List(logContext, dbContext, nil, db.ListOptions{Filters: filters, Limit: 1})
 	if err != nil {
 		logger.Error().Err(err).Msgf("[GetUserByEmail] Error retrieving user: %s", err)
 		return nil, err
 	}
-	account := val.(*User)
-	if account.Email == "" { //User not found!
+	if len(val) == 0 || val[0].Email == "" { //User not found!
 		logger.Error().Msgf("[GetUserByEmail] Error retrieving user: %s", user.Email)
 		return nil, sql.ErrNoRows
 	}
-
+	account := &val[0]
 	if !password {
 		account.Password = ""
 	}
 	return account, nil
 }
 
-func getUser(logContext *u.LoggerContext, data db.SqlData, query string) (interface{}, error) {
-	logger := zerolog.Ctx(*logContext)
-	val, err := db.SelectOne[User](logContext, nil, nil, query, data)
-	if err != nil {
-		logger.Error().Err(err).Msgf("[getUser] Error selecting user: %s", err)
-		return nil, err
-	}
-	return val, nil
+// PostgresUserStore UserStore on table user_db
+type PostgresUserStore struct {
+	database *db.DB
+}
+
+// NewPostgresUserStore creates a UserStore on the database
+func NewPostgresUserStore(database *db.DB) *PostgresUserStore {
+	return &PostgresUserStore{database: database}
+}
+
+// ListUsers lists the users of the tenant of user, every user when it has no tenant
+func (store *PostgresUserStore) ListUsers(logContext *u.LoggerContext, user *User) ([]User, error) {
+	return user.ListUsers(logContext, store.database.Context(*logContext))
+}
+
+// GetUserByID gets an user by ID, only inside the tenant of user when it has one
+func (store *PostgresUserStore) GetUserByID(logContext *u.LoggerContext, user *User) (*User, error) {
+	return user.GetUserByID(logContext, store.database.Context(*logContext))
+}
+
+// GetUserForUpdate gets an user by ID on the transaction, locking it until its end
+func (store *PostgresUserStore) GetUserForUpdate(logContext *u.LoggerContext, tx Transaction, user *User) (*User, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return user.GetUserForUpdate(logContext, txContext, pgTx)
+}
+
+// GetUserByEmail gets an user by email inside the tenant of user or the default tenant
+func (store *PostgresUserStore) GetUserByEmail(logContext *u.LoggerContext, user *User, password bool) (*User, error) {
+	return user.GetUserByEmail(logContext, store.database.Context(*logContext), password)
+}
+
+// Upsert inserts or updates an user
+func (store *PostgresUserStore) Upsert(logContext *u.LoggerContext, tx Transaction, user *User) (*User, error) {
+	txContext, pgTx := PostgresTx(tx)
+	return user.Upsert(logContext, txContext, pgTx)
+}
+
+// UpdatePassword hashes and stores a new password for the user
+func (store *PostgresUserStore) UpdatePassword(logContext *u.LoggerContext, tx Transaction, user *User, password string) error {
+	txContext, pgTx := PostgresTx(tx)
+	return user.UpdatePassword(logContext, txContext, pgTx, password)
+}
+
+// UpdateEmail changes the email of the user
+func (store *PostgresUserStore) UpdateEmail(logContext *u.LoggerContext, tx Transaction, user *User) error {
+	txContext, pgTx := PostgresTx(tx)
+	return user.UpdateEmail(logContext, txContext, pgTx)
+}
+
+// Delete deletes the user
+func (store *PostgresUserStore) Delete(logContext *u.LoggerContext, tx Transaction, user *User) error {
+	txContext, pgTx := PostgresTx(tx)
+	return user.Delete(logContext, txContext, pgTx)
 }
@@ -1,37 +1,87 @@
 package repositories
 
 import (
-	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
 	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
 	jwt "github.com/golang-jwt/jwt/v5"
-	"github.com/jackc/pgx/v5"
 )
 
-// Token JWT Token
+// Token JWT Token, Role is the role on user_db while Roles and Permissions are every role and permission of the user.
+// Tokens of OAuth clients have no UserID, ClientID is set instead and Permissions are the granted scopes.
+// Impersonation tokens have Act, the admin acting as the user. TenantID is the tenant of the user or client
 type Token struct {
-	UserID int
-	Role   string
+	UserID      int
+	TenantID    int `json:",omitempty"`
+	Role        string
+	Roles       []string `json:",omitempty"`
+	Permissions []string `json:",omitempty"`
+	ClientID    string   `json:",omitempty"`
+	Act         *Actor   `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Actor the act claim of RFC 8693, who is really behind an impersonation token
+type Actor struct {
+	Sub    string `json:"sub"`
+	UserID int
+}
+
 // ContextKey Key to use on a context
 type ContextKey string
 
-// UserRepository Repository for table usuario
-type UserRepository interface {
-	ListUsers(logContext *u.LoggerContext) ([]User, error)
-	Upsert(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx) (*User, error)
-	Delete(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx)
-	UserToData(data *db.SqlData) *db.SqlData
-	GetUserByID(logContext *u.LoggerContext) (*User, error)
-	GetUserByEmail(logContext *u.LoggerContext, password bool) (*User, error)
+// UserStore keeps the users, on table user_db (PostgresUserStore) or in memory (MemoryUserStore).
+// Writes take the transaction of the caller, a tenant transaction only changes users of its tenant
+type UserStore interface {
+	// ListUsers lists the users of the tenant of user, every user when it has no tenant. Passwords are never returned
+	ListUsers(logContext *u.LoggerContext, user *User) ([]User, error)
+	// GetUserByID gets an user by ID, only inside the tenant of user when it has one. sql.ErrNoRows if not found
+	GetUserByID(logContext *u.LoggerContext, user *User) (*User, error)
+	// GetUserForUpdate gets an user by ID on the transaction, locking it until its end. sql.ErrNoRows if not found
+	// or not on the tenant of the transaction
+	GetUserForUpdate(logContext *u.LoggerContext, tx Transaction, user *User) (*User, error)
+	// GetUserByEmail gets an user by email inside the tenant of user or the default tenant, with the password hash
+	// only if password is true. sql.ErrNoRows if not found
+	GetUserByEmail(logContext *u.LoggerContext, user *User, password bool) (*User, error)
+	// Upsert inserts the user when it has no ID, otherwise updates its non empty fields. The password is stored hashed
+	Upsert(logContext *u.LoggerContext, tx Transaction, user *User) (*User, error)
+	// UpdatePassword hashes and stores a new password for the user
+	UpdatePassword(logContext *u.LoggerContext, tx Transaction, user *User, password string) error
+	// UpdateEmail changes the email of the user
+	UpdateEmail(logContext *u.LoggerContext, tx Transaction, user *User) error
+	// Delete deletes the user
+	Delete(logContext *u.LoggerContext, tx Transaction, user *User) error
 }
 
+// User status values, only active users can log in
+const (
+	UserPending  = "pending"
+	UserActive   = "active"
+	UserDisabled = "disabled"
+)
+
 // User table usuario on database
 type User struct {
-	ID       int    `json:"id,omitempty" db:"id,omitempty"`
-	Email    string `json:"email,omitempty" db:"email,omitempty"`
-	Password string `json:"password,omitempty" db:"password,omitempty"`
-	Token    string `json:"token,omitempty" db:"-"`
-	Role     string `json:"role,omitempty" db:"role,omitempty"`
+	ID           int    `json:"id,omitempty" db:"id,omitempty,pk"`
+	Email        string `json:"email,omitempty" db:"email,omitempty"`
+	Name         string `json:"name,omitempty" db:"name,omitempty"`
+	Password     string `json:"password,omitempty" db:"password,omitempty"`
+	Token        string `json:"token,omitempty" db:"-"`
+	RefreshToken string `json:"refresh_token,omitempty" db:"-"`
+	Role         string `json:"role,omitempty" db:"role,omitempty"`
+	Status       string `json:"status,omitempty" db:"status,omitempty"`
+	TenantID     int    `json:"tenant_id,omitempty" db:"tenant_id,omitempty,readonly"`
+}
+
+// PasswordChangeRequest body of /api/me/password
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Password        string `json:"password"`
+}
+
+// RegisterRequest body of /api/register (Email, Password, Name and optionally TenantID) and /api/register/activate (Token)
+type RegisterRequest struct {
+	Email    string `json:"email,omitempty"`
+	Password string `json:"password,omitempty"`
+	Name     string `json:"name,omitempty"`
+	TenantID int    `json:"tenant_id,omitempty"`
+	Token    string `json:"token,omitempty"`
 }
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+)
+
+const apiKeyPrefix = "gwk_"
+
+// apiKeyJtiPrefix jti of the claims of an API key, followed by its id
+const apiKeyJtiPrefix = "apikey:"
+
+// ErrScopeNotGranted an API key scope is not a permission of its owner
+var ErrScopeNotGranted = errors.New("scope not granted to the user")
+
+// ErrInvalidApiKey the API key does not exist, was revoked or expired
+var ErrInvalidApiKey = errors.New("invalid API key")
+
+// CreateApiKey Creates an API key for the user limited to the given scopes, which must be permissions of the user,
+// recording it on the audit log. The key is returned only here, only its hash is stored.
+func (s *Services) CreateApiKey(logContext *u.LoggerContext, caller *repo.AuditCaller, userID int, permissions []string, request *repo.ApiKeyRequest) (*repo.ApiKey, error) {
+	logger := zerolog.Ctx(*logContext)
+	for _, scope := range request.Scopes {
+		if !slices.Contains(permissions, scope) {
+			logger.Error().Msgf("[CreateApiKey] Scope %s not granted to user %d", scope, userID)
+			return nil, ErrScopeNotGranted
+		}
+	}
+	secret, err := randomToken()
+	if err != nil {
+		logger.Error().Err(err).Msgf("[CreateApiKey] Error generating key: %s", err)
+		return nil, err
+	}
+	key := apiKeyPrefix + secret
+	now := time.Now()
+	apiKey := &repo.ApiKey{
+		UserID:     userID,
+		Name:       request.Name,
+		Prefix:     key[:len(apiKeyPrefix)+6],
+		KeyHash:    hashToken(key),
+		Scopes:     strings.Join(request.Scopes, " "),
+		Status:     repo.ApiKeyActive,
+		Tstampinit: now.Unix(),
+	}
+	if request.ExpiresInDays > 0 {
+		apiKey.Tstampexp = now.AddDate(0, 0, request.ExpiresInDays).Unix()
+	}
+
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[CreateApiKey] Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer tx.Rollback(logContext)
+	val, err := s.apiKeys.InsertApiKey(logContext, tx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(logContext, tx, caller, "apikey.create", "apikey:"+strconv.Itoa(val.ID), nil, val); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	logger.Info().Msgf("[CreateApiKey] API key %d created for user %d", val.ID, userID)
+	val.Key = key
+	return val, nil
+}
+
+// ListApiKeys Lists the API keys of the user
+func (s *Services) ListApiKeys(logContext *u.LoggerContext, userID int) ([]repo.ApiKey, error) {
+	return s.apiKeys.ListApiKeys(logContext, &repo.ApiKey{UserID: userID})
+}
+
+// RevokeApiKey Revokes one API key of the user, recording it on the audit log
+func (s *Services) RevokeApiKey(logContext *u.LoggerContext, caller *repo.AuditCaller, key *repo.ApiKey) error {
+	logger := zerolog.Ctx(*logContext)
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[RevokeApiKey] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+	current, err := s.apiKeys.GetApiKeyForUpdate(logContext, tx, key)
+	if err != nil {
+		return err
+	}
+	if current == nil { //Not a key of the user, nothing to revoke
+		return nil
+	}
+	if err := s.apiKeys.RevokeApiKey(logContext, tx, key); err != nil {
+		return err
+	}
+	revoked := *current
+	revoked.Status = repo.ApiKeyRevoked
+	if err := s.audit(logContext, tx, caller, "apikey.revoke", "apikey:"+strconv.Itoa(key.ID), current, &revoked); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+	logger.Info().Msgf("[RevokeApiKey] API key %d revoked by user %d", key.ID, key.UserID)
+	return nil
+}
+
+// AuthenticateApiKey Validates an API key, returning the claims of its owner as if it were an access token, issued
+// when the key was created and with a jti of its own, so revocations apply to them as to access tokens.
+// Permissions are the key scopes still granted to the owner, so role changes apply immediately.
+func (s *Services) AuthenticateApiKey(logContext *u.LoggerContext, key string) (*repo.Token, error) {
+	logger := zerolog.Ctx(*logContext)
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidApiKey
+	}
+	apiKey, err := s.apiKeys.GetApiKeyByHash(logContext, &repo.ApiKey{KeyHash: hashToken(key)})
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil || apiKey.Status != repo.ApiKeyActive || (apiKey.Tstampexp != 0 && apiKey.Tstampexp < time.Now().Unix()) {
+		return nil, ErrInvalidApiKey
+	}
+	account, err := s.users.GetUserByID(logContext, &repo.User{ID: apiKey.UserID})
+	if err != nil {
+		logger.Error().Err(err).Msgf("[AuthenticateApiKey] Owner of API key %d not found: %s", apiKey.ID, err)
+		return nil, ErrInvalidApiKey
+	}
+	if account.Status != repo.UserActive {
+		logger.Error().Msgf("[AuthenticateApiKey] Owner of API key %d is %s", apiKey.ID, account.Status)
+		return nil, ErrInvalidApiKey
+	}
+	roles, err := s.rbac.GetEffectiveRoles(logContext, account)
+	if err != nil {
+		return nil, err
+	}
+	granted, err := s.rbac.GetEffectivePermissions(logContext, account)
+	if err != nil {
+		return nil, err
+	}
+	permissions := []string{}
+	for _, scope := range strings.Fields(apiKey.Scopes) {
+		if slices.Contains(granted, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+	claims := &repo.Token{UserID: account.ID, TenantID: account.TenantID, Role: account.Role, Roles: roles, Permissions: permissions}
+	claims.ID = apiKeyJtiPrefix + strconv.Itoa(apiKey.ID)
+	claims.IssuedAt = jwt.NewNumericDate(time.Unix(apiKey.Tstampinit, 0))
+	if apiKey.Tstampexp != 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Unix(apiKey.Tstampexp, 0))
+	}
+	return claims, nil
+}
+
+// apiKeyOf The id of the API key behind claims returned by AuthenticateApiKey, ok false for access tokens
+func apiKeyOf(token *repo.Token) (id int, ok bool) {
+	value, found := strings.CutPrefix(token.ID, apiKeyJtiPrefix)
+	if !found {
+		return 0, false
+	}
+	id, err := strconv.Atoi(value)
+	return id, err == nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"encoding/json"
+	"time"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+const maxAuditEntries = 500
+
+// ListAuditEntries Lists the audit log, newest first, at most maxAuditEntries per call
+func (s *Services) ListAuditEntries(logContext *u.LoggerContext, filter *repo.AuditFilter) ([]repo.AuditEntry, error) {
+	if filter.Limit <= 0 || filter.Limit > maxAuditEntries {
+		filter.Limit = maxAuditEntries
+	}
+	return s.auditLog.ListAuditEntries(logContext, filter)
+}
+
+// audit Records a change made by the caller on the transaction of the change, so both commit or roll back together.
+// before and after are snapshots of the target, nil when it didn't exist before or doesn't exist after.
+func (s *Services) audit(logContext *u.LoggerContext, tx repo.Transaction, caller *repo.AuditCaller, action string, target string, before any, after any) error {
+	logger := zerolog.Ctx(*logContext)
+	entry := &repo.AuditEntry{Tstamp: time.Now().Unix(), Action: action, Target: target}
+	if caller != nil {
+		entry.ActorID = caller.ActorID
+		entry.TenantID = caller.TenantID
+		entry.ImpersonatorID = caller.ImpersonatorID
+		entry.ClientID = caller.ClientID
+		entry.IP = caller.IP
+		entry.RequestID = caller.RequestID
+	}
+	var err error
+	if entry.Before, err = snapshot(before); err != nil {
+		logger.Error().Err(err).Msgf("[audit] Error encoding %s snapshot: %s", action, err)
+		return err
+	}
+	if entry.After, err = snapshot(after); err != nil {
+		logger.Error().Err(err).Msgf("[audit] Error encoding %s snapshot: %s", action, err)
+		return err
+	}
+	return s.auditLog.InsertAuditEntry(logContext, tx, entry)
+}
+
+// onBehalf The caller of a public route acts as the account it proved to own, by a token sent to its email
+func onBehalf(caller *repo.AuditCaller, account *repo.User) *repo.AuditCaller {
+	acting := onAccount(caller, account)
+	if acting != caller {
+		acting.ActorID = account.ID
+	}
+	return acting
+}
+
+// onAccount The caller of a public route, anonymous, is recorded on the tenant of the account it changed
+func onAccount(caller *repo.AuditCaller, account *repo.User) *repo.AuditCaller {
+	if caller == nil || caller.ActorID != 0 {
+		return caller
+	}
+	acting := *caller
+	acting.TenantID = account.TenantID
+	return &acting
+}
+
+// snapshot Encodes a value as JSON, nil stays nil
+func snapshot(value any) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+const impersonationDuration = 15 * time.Minute
+
+// ErrCannotImpersonate the target is the admin itself, is not an active user, can impersonate or has permissions
+// the admin doesn't have
+var ErrCannotImpersonate = errors.New("user cannot be impersonated")
+
+// Impersonate Issues a short lived access token of the user for the admin calling, with the admin on the act claim,
+// recording it on the audit log. There is no refresh token, and admin-only routes refuse these tokens.
+func (s *Services) Impersonate(logContext *u.LoggerContext, caller *repo.AuditCaller, user *repo.User) (map[string]interface{}, error) {
+	logger := zerolog.Ctx(*logContext)
+	adminID := caller.ActorID
+	if user.ID == adminID {
+		return nil, ErrCannotImpersonate
+	}
+	account, err := s.users.GetUserByID(logContext, user)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Impersonate] Error retrieving user %d: %s", user.ID, err)
+		return nil, err
+	}
+	if account.Status != repo.UserActive {
+		logger.Error().Msgf("[Impersonate] User %d is %s", account.ID, account.Status)
+		return nil, ErrCannotImpersonate
+	}
+	claims, err := s.accessClaims(logContext, account, impersonationDuration)
+	if err != nil {
+		return nil, err
+	}
+	admin := &repo.User{ID: adminID}
+	granted, err := s.rbac.GetEffectivePermissions(logContext, admin)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Impersonate] Error listing permissions of admin %d: %s", adminID, err)
+		return nil, err
+	}
+	for _, permission := range claims.Permissions { //The token can't grant more than the admin already has
+		if permission == "users:impersonate" || !slices.Contains(granted, permission) {
+			logger.Error().Msgf("[Impersonate] Admin %d cannot impersonate user %d, holding %s", adminID, account.ID, permission)
+			return nil, ErrCannotImpersonate
+		}
+	}
+	claims.Act = &repo.Actor{Sub: strconv.Itoa(adminID), UserID: adminID}
+	tokenString, err := s.keys.Sign(claims)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Impersonate] Error signing token: %s", err)
+		return nil, err
+	}
+	tx, err := s.transactor.BeginTenant(logContext, user.TenantID)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Impersonate] Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer tx.Rollback(logContext)
+	issued := map[string]any{"jti": claims.ID, "expires": claims.ExpiresAt.Unix()}
+	if err := s.audit(logContext, tx, caller, "user.impersonate", "user:"+strconv.Itoa(account.ID), nil, issued); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	logger.Warn().Int("impersonator", adminID).Int("user", account.ID).Str("jti", claims.ID).
+		Msgf("[Impersonate] Admin %d impersonating user %d", adminID, account.ID)
+
+	resp := map[string]interface{}{
+		"token":      tokenString,
+		"expires_in": int(impersonationDuration.Seconds()),
+		"account":    account,
+	}
+	return resp, nil
+}
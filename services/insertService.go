@@ -1,46 +1,56 @@
 package services
 
 import (
-	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	"context"
+	"strconv"
+
 	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
 	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
-	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 )
 
-// ListInserts Lists all inserts for one batch by id
-func ListInserts(logContext *u.LoggerContext, insert *repo.Insert) (*repo.Insert, error) {
-	return insert.ListInserts(logContext)
+// ListInserts Lists all inserts for one batch by id, inside the tenant of insert
+func (s *Services) ListInserts(logContext *u.LoggerContext, insert *repo.Insert) (*repo.Insert, error) {
+	return s.batches.ListInserts(logContext, insert)
 }
 
-// ClearInserts Clears tables
-func ClearInserts(logContext *u.LoggerContext, insert *repo.Insert) error {
+// ClearInserts Clears the batches of the tenant of insert, recording how many batches were removed on the audit log
+func (s *Services) ClearInserts(logContext *u.LoggerContext, caller *repo.AuditCaller, insert *repo.Insert) error {
 	logger := zerolog.Ctx(*logContext)
-	tx, txContext, err := db.GetTransaction()
+	tx, err := s.transactor.BeginTenant(logContext, insert.TenantID)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[ClearInserts] Error starting transaction: %s", err)
 		return err
 	}
-	defer db.Rollback(logContext, txContext, tx)
-	if err2 := insert.ClearBatches(logContext, txContext, tx); err2 != nil {
+	defer tx.Rollback(logContext)
+	count, err := s.batches.CountBatches(logContext, tx, insert)
+	if err != nil {
+		return err
+	}
+	if err2 := s.batches.ClearBatches(logContext, tx, insert); err2 != nil {
 		logger.Error().Err(err).Msgf("[ClearInserts] Error cleaning batches: %s", err2)
 		return err2
 	}
-	db.Commit(logContext, txContext, tx)
+	if err := s.audit(logContext, tx, caller, "batches.clear", "ins_id", map[string]int{"batches": count}, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
 	return nil
 }
 
 // InsertBatchSync Inserts a batch of given quantity synchronous
-func InsertBatchSync(logContext *u.LoggerContext, insert *repo.Insert) (*repo.Insert, error) {
+func (s *Services) InsertBatchSync(logContext *u.LoggerContext, caller *repo.AuditCaller, insert *repo.Insert) (*repo.Insert, error) {
 	logger := zerolog.Ctx(*logContext)
-	tx, txContext, err := db.GetTransaction()
+	tx, err := s.transactor.BeginTenant(logContext, insert.TenantID)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[InsertBatchSync] Error starting transaction: %s", err)
 		return nil, err
 	}
-	defer db.Rollback(logContext, txContext, tx)
+	defer tx.Rollback(logContext)
 	insert.Type = "sync"
-	ins, err := insert.InsertID(logContext, txContext, tx)
+	ins, err := s.batches.InsertID(logContext, tx, insert)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[InsertBatchSync] Error inserting a batch: %s", err)
 		return nil, err
@@ -50,81 +60,97 @@ func InsertBatchSync(logContext *u.LoggerContext, insert *repo.Insert) (*repo.In
 		insertBatch := &repo.InsertBatch{}
 		insertBatch.ID_Ins_ID = ins.ID
 		insertBatch.Pos = i
-		if err := insertBatch.InsertOneBatch(logContext, txContext, tx); err != nil {
+		if err := s.batches.InsertOneBatch(logContext, tx, insertBatch); err != nil {
 			logger.Error().Err(err).Msgf("[InsertBatchSync] Error inserting one item: %s", err)
 		}
 	}
 	ins.Status = "Finished"
-	_, err = ins.UpdateInsertID(logContext, txContext, tx)
+	_, err = s.batches.UpdateInsertID(logContext, tx, ins)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[InsertBatchSync] Error updating insert id: %s", err)
 		return nil, err
 	}
-	db.Commit(logContext, txContext, tx)
-	return ins.ListInserts(logContext)
+	if err := s.audit(logContext, tx, caller, "batch.insert", "ins_id:"+strconv.Itoa(ins.ID), nil, ins); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	return s.batches.ListInserts(logContext, ins)
 }
 
-// InsertBatchASync Inserts a batch of given quantity asynchronous
-func InsertBatchASync(logContext *u.LoggerContext, insert *repo.Insert) (*repo.Insert, error) {
+// InsertBatchASync Inserts a batch of given quantity asynchronous, the audit log records the batch when it is created
+func (s *Services) InsertBatchASync(logContext *u.LoggerContext, caller *repo.AuditCaller, insert *repo.Insert) (*repo.Insert, error) {
 	logger := zerolog.Ctx(*logContext)
-	tx, txContext, err := db.GetTransaction()
+	tx, err := s.transactor.BeginTenant(logContext, insert.TenantID)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[InsertBatchASync] Error starting transaction: %s", err)
 		return nil, err
 	}
-	defer db.Rollback(logContext, txContext, tx)
+	defer tx.Rollback(logContext)
 	insert.Type = "async"
-	ins, err := insert.InsertID(logContext, txContext, tx)
-	ins.Quantity = insert.Quantity
+	ins, err := s.batches.InsertID(logContext, tx, insert)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[InsertBatchASync] Error inserting a batch: %s", err)
 		return nil, err
 	}
-	db.Commit(logContext, txContext, tx)
-	go insertBatch(logContext, ins)
+	ins.Quantity = insert.Quantity
+	if err := s.audit(logContext, tx, caller, "batch.insert", "ins_id:"+strconv.Itoa(ins.ID), nil, ins); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	s.goBackground(func(logContext *u.LoggerContext) { s.insertBatch(logContext, ins) })
 	return ins, nil
 }
 
-func insertBatch(logContext *u.LoggerContext, insert *repo.Insert) {
+func (s *Services) insertBatch(logContext *u.LoggerContext, insert *repo.Insert) {
 	logger := zerolog.Ctx(*logContext)
-	tx, txContext, err := db.GetTransaction()
+	tx, err := s.transactor.BeginTenant(logContext, insert.TenantID)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[insertBatch] Error starting transaction: %s", err)
 		return
 	}
-	defer db.Rollback(logContext, txContext, tx)
+	defer tx.Rollback(logContext)
 	for i := 1; i <= insert.Quantity; i++ {
 		insertBatch := &repo.InsertBatch{}
 		insertBatch.ID_Ins_ID = insert.ID
 		insertBatch.Pos = i
-		if err := insertBatch.InsertOneBatch(logContext, txContext, tx); err != nil {
+		if err := s.batches.InsertOneBatch(logContext, tx, insertBatch); err != nil {
 			logger.Error().Err(err).Msgf("[insertBatch] Error inserting one item: %s", err)
-			onError(logContext, txContext, tx, insert)
+			s.onError(logContext, tx, insert)
+			return
 		}
 	}
 	insert.Status = "Finished"
-	_, err = insert.UpdateInsertID(logContext, txContext, tx)
-	if err != nil {
-		logger.Error().Err(err).Msgf("[InsertBatchSync] Error updating insert id: %s", err)
-	} else {
-		db.Commit(logContext, txContext, tx)
+	if _, err := s.batches.UpdateInsertID(logContext, tx, insert); err != nil {
+		logger.Error().Err(err).Msgf("[insertBatch] Error updating insert id: %s", err)
+		s.onError(logContext, tx, insert)
+		return
+	}
+	if err := tx.Commit(logContext); err != nil {
+		s.onError(logContext, tx, insert)
 	}
 }
 
-func onError(logContext *u.LoggerContext, txContext *db.DatabaseContext, tx *pgx.Tx, insert *repo.Insert) {
+// onError Rolls the batch back and marks it as Error, even when it failed because the application is stopping
+func (s *Services) onError(logContext *u.LoggerContext, tx repo.Transaction, insert *repo.Insert) {
 	logger := zerolog.Ctx(*logContext)
-	db.Rollback(logContext, txContext, tx)
-	newTx, newContext, err := db.GetTransaction()
+	tx.Rollback(logContext)
+	logContext = u.GetLoggerContext(context.WithoutCancel(*logContext))
+	newTx, err := s.transactor.BeginTenant(logContext, insert.TenantID)
 	if err != nil {
 		logger.Error().Msgf("[onError] Error starting transaction: %s", err)
 		return
 	}
-	defer db.Rollback(logContext, newContext, newTx)
+	defer newTx.Rollback(logContext)
 	insert.Status = "Error"
-	_, err = insert.UpdateInsertID(logContext, newContext, newTx)
-	if err != nil {
+	if _, err := s.batches.UpdateInsertID(logContext, newTx, insert); err != nil {
 		logger.Error().Err(err).Msgf("[onError] Error updating insert id: %s", err)
-	} else {
-		db.Commit(logContext, newContext, newTx)
+		return
+	}
+	if err := newTx.Commit(logContext); err != nil {
+		logger.Error().Err(err).Msgf("[onError] Error marking batch %d as Error: %s", insert.ID, err)
 	}
 }
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+// throttlePolicy after maxFailures failures inside window, the key is locked for baseLock,
+// doubling on every new failure up to maxLock
+type throttlePolicy struct {
+	prefix      string
+	maxFailures int
+	window      time.Duration
+	baseLock    time.Duration
+	maxLock     time.Duration
+}
+
+var accountPolicy = throttlePolicy{prefix: "email:", maxFailures: 5, window: 15 * time.Minute, baseLock: time.Minute, maxLock: time.Hour}
+var ipPolicy = throttlePolicy{prefix: "ip:", maxFailures: 20, window: 15 * time.Minute, baseLock: time.Minute, maxLock: time.Hour}
+
+// Password reset requests count every request, sent or not, on keys of their own
+var forgotAccountPolicy = throttlePolicy{prefix: "forgot:email:", maxFailures: 3, window: time.Hour, baseLock: 15 * time.Minute, maxLock: 24 * time.Hour}
+var forgotIPPolicy = throttlePolicy{prefix: "forgot:ip:", maxFailures: 10, window: time.Hour, baseLock: 15 * time.Minute, maxLock: 24 * time.Hour}
+
+// Registrations count every request too, each one sends an email
+var registerAccountPolicy = throttlePolicy{prefix: "register:email:", maxFailures: 3, window: time.Hour, baseLock: 15 * time.Minute, maxLock: 24 * time.Hour}
+var registerIPPolicy = throttlePolicy{prefix: "register:ip:", maxFailures: 10, window: time.Hour, baseLock: 15 * time.Minute, maxLock: 24 * time.Hour}
+
+// ThrottledError the email or client ip is locked by failed attempts, Wait is how long until it can retry
+type ThrottledError struct {
+	Wait time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return "too many failed attempts, retry after " + e.Wait.String()
+}
+
+// CheckLoginThrottle Returns how long the email or the client ip must wait before trying to login again, zero if allowed
+func (s *Services) CheckLoginThrottle(logContext *u.LoggerContext, email string, ip string) (time.Duration, error) {
+	logger := zerolog.Ctx(*logContext)
+	wait, err := s.throttleWait(logContext, accountPolicy.key(email), ipPolicy.key(ip))
+	if err != nil {
+		logger.Error().Err(err).Msgf("[CheckLoginThrottle] Error reading login attempts: %s", err)
+		return 0, err
+	}
+	if wait > 0 {
+		logger.Warn().Msgf("[CheckLoginThrottle] Login throttled for %s from %s, retry after %s", email, ip, wait)
+	}
+	return wait, nil
+}
+
+// throttleWait Returns how long until the longest lock of the keys ends, zero if none is locked
+func (s *Services) throttleWait(logContext *u.LoggerContext, keys ...string) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		attempt, err := s.loginAttempts.GetAttempt(logContext, key)
+		if err != nil {
+			return 0, err
+		}
+		if attempt == nil {
+			continue
+		}
+		if locked := time.Unix(attempt.Tstamplocked, 0).Sub(now); locked > wait {
+			wait = locked
+		}
+	}
+	return wait, nil
+}
+
+// UnlockAccount Removes the failed attempts and lock of an account
+func (s *Services) UnlockAccount(logContext *u.LoggerContext, user *repo.User) error {
+	logger := zerolog.Ctx(*logContext)
+	account, err := s.users.GetUserByID(logContext, user)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UnlockAccount] Error retrieving user: %s", err)
+		return err
+	}
+	if err := s.loginAttempts.ResetAttempts(logContext, accountPolicy.key(account.Email)); err != nil {
+		logger.Error().Err(err).Msgf("[UnlockAccount] Error unlocking user %d: %s", account.ID, err)
+		return err
+	}
+	logger.Info().Msgf("[UnlockAccount] User %d unlocked", account.ID)
+	return nil
+}
+
+// registerLoginFailure Counts a failed login for the email and the client ip, locking them if needed
+func (s *Services) registerLoginFailure(logContext *u.LoggerContext, email string, ip string) {
+	accountPolicy.registerFailure(logContext, s.loginAttempts, email)
+	ipPolicy.registerFailure(logContext, s.loginAttempts, ip)
+}
+
+// registerLoginSuccess Clears the failures of the email, the ip keeps its count
+func (s *Services) registerLoginSuccess(logContext *u.LoggerContext, email string) {
+	logger := zerolog.Ctx(*logContext)
+	if err := s.loginAttempts.ResetAttempts(logContext, accountPolicy.key(email)); err != nil {
+		logger.Error().Err(err).Msgf("[registerLoginSuccess] Error resetting login attempts: %s", err)
+	}
+}
+
+func (policy throttlePolicy) key(value string) string {
+	return policy.prefix + strings.ToLower(strings.TrimSpace(value))
+}
+
+func (policy throttlePolicy) registerFailure(logContext *u.LoggerContext, store repo.LoginAttemptStore, value string) {
+	logger := zerolog.Ctx(*logContext)
+	now := time.Now()
+	key := policy.key(value)
+	attempt, err := store.AddFailure(logContext, key, now.Unix(), now.Add(-policy.window).Unix())
+	if err != nil {
+		logger.Error().Err(err).Msgf("[registerFailure] Error counting login failure: %s", err)
+		return
+	}
+	if attempt.Failures < policy.maxFailures {
+		return
+	}
+	lock := policy.maxLock
+	if exceeded := attempt.Failures - policy.maxFailures; exceeded < 16 {
+		lock = min(policy.baseLock<<exceeded, policy.maxLock)
+	}
+	if err := store.LockUntil(logContext, key, now.Add(lock).Unix()); err != nil {
+		logger.Error().Err(err).Msgf("[registerFailure] Error locking login: %s", err)
+		return
+	}
+	logger.Warn().Msgf("[registerFailure] %s locked for %s after %d failures", key, lock, attempt.Failures)
+}
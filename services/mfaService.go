@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+)
+
+const totpIssuer = "go-ws-db-auth-v2"
+const mfaChallengeDuration = 5 * time.Minute
+const recoveryCodeCount = 10
+
+// ErrTotpAlreadyEnrolled the user already has an active TOTP enrollment
+var ErrTotpAlreadyEnrolled = errors.New("TOTP already enrolled")
+
+// ErrInvalidMfaCode the TOTP or recovery code does not match
+var ErrInvalidMfaCode = errors.New("invalid MFA code")
+
+// EnrollTotp Creates a pending TOTP secret and new recovery codes for the user, activated by ConfirmTotp.
+// The enrollment is recorded on the audit log, without the secret or the codes
+func (s *Services) EnrollTotp(logContext *u.LoggerContext, caller *repo.AuditCaller, user *repo.User) (*repo.TotpEnrollment, error) {
+	logger := zerolog.Ctx(*logContext)
+	account, err := s.users.GetUserByID(logContext, user)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[EnrollTotp] Error retrieving user: %s", err)
+		return nil, err
+	}
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[EnrollTotp] Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer tx.Rollback(logContext)
+
+	current, err := s.mfa.GetUserTotp(logContext, tx, &repo.UserTotp{UserID: account.ID})
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.Status == repo.TotpActive {
+		return nil, ErrTotpAlreadyEnrolled
+	}
+	secret, err := u.GenerateTOTPSecret()
+	if err != nil {
+		logger.Error().Err(err).Msgf("[EnrollTotp] Error generating secret: %s", err)
+		return nil, err
+	}
+	totp := &repo.UserTotp{UserID: account.ID, Secret: secret, Status: repo.TotpPending, Tstampinit: time.Now().Unix()}
+	if err := s.mfa.UpsertUserTotp(logContext, tx, totp); err != nil {
+		return nil, err
+	}
+	codes, err := s.replaceRecoveryCodes(logContext, tx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(logContext, tx, caller, "mfa.totp.enroll", "user:"+strconv.Itoa(account.ID), nil, totpSnapshot(totp)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	logger.Info().Msgf("[EnrollTotp] TOTP enrollment started for user %d", account.ID)
+	return &repo.TotpEnrollment{URI: u.TOTPURI(totpIssuer, account.Email, secret), Secret: secret, RecoveryCodes: codes}, nil
+}
+
+// ConfirmTotp Activates a pending TOTP enrollment with a code from the authenticator app, recording it on the audit log.
+// Wrong codes count as login failures of the user and ip, returning ThrottledError once the login is throttled
+func (s *Services) ConfirmTotp(logContext *u.LoggerContext, caller *repo.AuditCaller, userID int, code string, ip string) error {
+	logger := zerolog.Ctx(*logContext)
+	account, err := s.checkMfaThrottle(logContext, userID, ip)
+	if err != nil {
+		return err
+	}
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ConfirmTotp] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+	totp, err := s.mfa.GetUserTotp(logContext, tx, &repo.UserTotp{UserID: userID})
+	if err != nil {
+		return err
+	}
+	if totp == nil || totp.Status != repo.TotpPending {
+		return ErrInvalidMfaCode
+	}
+	step, ok := u.VerifyTOTP(totp.Secret, code, time.Now(), totp.LastStep)
+	if !ok {
+		logger.Error().Msgf("[ConfirmTotp] Invalid MFA code for user %d", userID)
+		s.registerLoginFailure(logContext, account.Email, ip)
+		return ErrInvalidMfaCode
+	}
+	before := totpSnapshot(totp)
+	totp.Status = repo.TotpActive
+	totp.LastStep = step
+	if err := s.mfa.UpdateUserTotp(logContext, tx, totp); err != nil {
+		return err
+	}
+	if err := s.audit(logContext, tx, caller, "mfa.totp.enable", "user:"+strconv.Itoa(userID), before, totpSnapshot(totp)); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+	s.registerLoginSuccess(logContext, account.Email)
+	logger.Info().Msgf("[ConfirmTotp] TOTP enabled for user %d", userID)
+	return nil
+}
+
+// DisableTotp Removes the TOTP enrollment of the user, given a valid TOTP or recovery code, recording it on the audit log.
+// Wrong codes count as login failures of the user and ip, returning ThrottledError once the login is throttled
+func (s *Services) DisableTotp(logContext *u.LoggerContext, caller *repo.AuditCaller, userID int, code string, ip string) error {
+	logger := zerolog.Ctx(*logContext)
+	account, err := s.checkMfaThrottle(logContext, userID, ip)
+	if err != nil {
+		return err
+	}
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[DisableTotp] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+	if err := s.verifyMfaCode(logContext, tx, userID, code); err != nil {
+		if errors.Is(err, ErrInvalidMfaCode) {
+			logger.Error().Msgf("[DisableTotp] Invalid MFA code for user %d", userID)
+			s.registerLoginFailure(logContext, account.Email, ip)
+		}
+		return err
+	}
+	if err := s.mfa.DeleteUserTotp(logContext, tx, &repo.UserTotp{UserID: userID}); err != nil {
+		return err
+	}
+	before := totpSnapshot(&repo.UserTotp{Status: repo.TotpActive})
+	if err := s.audit(logContext, tx, caller, "mfa.totp.disable", "user:"+strconv.Itoa(userID), before, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+	s.registerLoginSuccess(logContext, account.Email)
+	logger.Info().Msgf("[DisableTotp] TOTP disabled for user %d", userID)
+	return nil
+}
+
+// ParseMfaChallenge Validates an MFA challenge token returned by Login
+func (s *Services) ParseMfaChallenge(challenge string) (*repo.MfaChallenge, error) {
+	token, err := jwt.ParseWithClaims(challenge, &repo.MfaChallenge{}, s.keys.Keyfunc,
+		jwt.WithValidMethods(s.keys.Algorithms()), jwt.WithAudience(repo.MfaAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	return token.Claims.(*repo.MfaChallenge), nil
+}
+
+// LoginMfa Second login step: exchanges an MFA challenge and a TOTP or recovery code for the access and refresh tokens
+func (s *Services) LoginMfa(logContext *u.LoggerContext, challenge *repo.MfaChallenge, code string, ip string) map[string]interface{} {
+	logger := zerolog.Ctx(*logContext)
+	account, err := s.users.GetUserByID(logContext, &repo.User{ID: challenge.UserID})
+	if err != nil {
+		logger.Error().Err(err).Msgf("[LoginMfa] Error retrieving user %d: %s", challenge.UserID, err)
+		return u.Message(false, "Invalid MFA token")
+	}
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[LoginMfa] Error starting transaction: %s", err)
+		return u.Message(false, "Connection error. Please retry")
+	}
+	defer tx.Rollback(logContext)
+	if err := s.verifyMfaCode(logContext, tx, account.ID, code); err != nil {
+		if errors.Is(err, ErrInvalidMfaCode) {
+			logger.Error().Msgf("[LoginMfa] Invalid MFA code for user %d", account.ID)
+			s.registerLoginFailure(logContext, account.Email, ip)
+			return u.Message(false, "Invalid MFA code. Please try again")
+		}
+		return u.Message(false, "Connection error. Please retry")
+	}
+	s.registerLoginSuccess(logContext, account.Email)
+	return s.finishLogin(logContext, tx, account)
+}
+
+// checkMfaThrottle Gets the logged user checking a TOTP code, ThrottledError while its login is throttled
+func (s *Services) checkMfaThrottle(logContext *u.LoggerContext, userID int, ip string) (*repo.User, error) {
+	account, err := s.users.GetUserByID(logContext, &repo.User{ID: userID})
+	if err != nil {
+		zerolog.Ctx(*logContext).Error().Err(err).Msgf("[checkMfaThrottle] Error retrieving user %d: %s", userID, err)
+		return nil, err
+	}
+	wait, err := s.CheckLoginThrottle(logContext, account.Email, ip)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		return nil, &ThrottledError{Wait: wait}
+	}
+	return account, nil
+}
+
+// verifyMfaCode Checks a TOTP code, or else a recovery code, of an user with active TOTP, consuming it
+func (s *Services) verifyMfaCode(logContext *u.LoggerContext, tx repo.Transaction, userID int, code string) error {
+	totp, err := s.mfa.GetUserTotp(logContext, tx, &repo.UserTotp{UserID: userID})
+	if err != nil {
+		return err
+	}
+	if totp == nil || totp.Status != repo.TotpActive {
+		return ErrInvalidMfaCode
+	}
+	code = strings.TrimSpace(code)
+	if step, ok := u.VerifyTOTP(totp.Secret, code, time.Now(), totp.LastStep); ok {
+		totp.LastStep = step
+		return s.mfa.UpdateUserTotp(logContext, tx, totp)
+	}
+	search := &repo.RecoveryCode{UserID: userID, CodeHash: hashToken(normalizeRecoveryCode(code))}
+	recovery, err := s.mfa.GetRecoveryCode(logContext, tx, search)
+	if err != nil {
+		return err
+	}
+	if recovery == nil {
+		return ErrInvalidMfaCode
+	}
+	zerolog.Ctx(*logContext).Warn().Msgf("[verifyMfaCode] Recovery code used by user %d", userID)
+	return s.mfa.UseRecoveryCode(logContext, tx, recovery)
+}
+
+// totpSnapshot What the audit log keeps of a TOTP enrollment, never the secret
+func totpSnapshot(totp *repo.UserTotp) map[string]string {
+	return map[string]string{"status": totp.Status}
+}
+
+// generateMfaChallenge Signs a short lived token proving the password step was done, only accepted by LoginMfa
+func (s *Services) generateMfaChallenge(account *repo.User) (string, error) {
+	now := time.Now()
+	claims := &repo.MfaChallenge{
+		UserID: account.ID,
+		Email:  account.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{repo.MfaAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaChallengeDuration)),
+		},
+	}
+	return s.keys.Sign(claims)
+}
+
+// replaceRecoveryCodes Drops the recovery codes of the user and creates new ones, returning them in clear text
+func (s *Services) replaceRecoveryCodes(logContext *u.LoggerContext, tx repo.Transaction, userID int) ([]string, error) {
+	if err := s.mfa.DeleteRecoveryCodes(logContext, tx, &repo.RecoveryCode{UserID: userID}); err != nil {
+		return nil, err
+	}
+	var codes []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		random, err := u.GenerateTOTPSecret()
+		if err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(random[0:5] + "-" + random[5:10])
+		recovery := &repo.RecoveryCode{UserID: userID, CodeHash: hashToken(normalizeRecoveryCode(code)), Status: repo.RecoveryCodeActive}
+		if err := s.mfa.InsertRecoveryCode(logContext, tx, recovery); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
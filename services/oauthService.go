@@ -0,0 +1,231 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+)
+
+const clientTokenDuration = time.Hour
+
+// OAuthError error of the OAuth endpoints, Code is one of the error codes of RFC 6749 section 5.2
+type OAuthError struct {
+	Code        string
+	Description string
+	Status      int
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+var errInvalidClient = &OAuthError{Code: "invalid_client", Description: "Client authentication failed", Status: http.StatusUnauthorized}
+
+// CreateOAuthClient Registers an OAuth client of the tenant limited to the given scopes, which must be permissions of the caller,
+// recording it on the audit log. The secret is returned only here, only its hash is stored.
+func (s *Services) CreateOAuthClient(logContext *u.LoggerContext, caller *repo.AuditCaller, tenantID int, permissions []string, request *repo.OAuthClientRequest) (*repo.OAuthClient, error) {
+	logger := zerolog.Ctx(*logContext)
+	for _, scope := range request.Scopes {
+		if !slices.Contains(permissions, scope) {
+			logger.Error().Msgf("[CreateOAuthClient] Scope %s not granted to the caller", scope)
+			return nil, ErrScopeNotGranted
+		}
+	}
+	clientID, err := randomToken()
+	if err != nil {
+		logger.Error().Err(err).Msgf("[CreateOAuthClient] Error generating client id: %s", err)
+		return nil, err
+	}
+	secret, err := randomToken()
+	if err != nil {
+		logger.Error().Err(err).Msgf("[CreateOAuthClient] Error generating client secret: %s", err)
+		return nil, err
+	}
+	client := &repo.OAuthClient{
+		ClientID:   clientID[:22],
+		Name:       request.Name,
+		SecretHash: hashToken(secret),
+		Scopes:     strings.Join(request.Scopes, " "),
+		Tstampinit: time.Now().Unix(),
+		TenantID:   repo.TenantOrDefault(tenantID),
+	}
+
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[CreateOAuthClient] Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer tx.Rollback(logContext)
+	val, err := s.oauthClients.InsertOAuthClient(logContext, tx, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(logContext, tx, caller, "oauth_client.create", "oauth_client:"+strconv.Itoa(val.ID), nil, val); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	logger.Info().Msgf("[CreateOAuthClient] OAuth client %s registered", val.ClientID)
+	val.Secret = secret
+	return val, nil
+}
+
+// ListOAuthClients Lists all OAuth clients of the tenant
+func (s *Services) ListOAuthClients(logContext *u.LoggerContext, tenantID int) ([]repo.OAuthClient, error) {
+	return s.oauthClients.ListOAuthClients(logContext, &repo.OAuthClient{TenantID: repo.TenantOrDefault(tenantID)})
+}
+
+// DeleteOAuthClient Deletes an OAuth client of the tenant of client, recording it on the audit log. Its tokens stop
+// working on the next request
+func (s *Services) DeleteOAuthClient(logContext *u.LoggerContext, caller *repo.AuditCaller, client *repo.OAuthClient) error {
+	logger := zerolog.Ctx(*logContext)
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[DeleteOAuthClient] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+	current, err := s.oauthClients.GetOAuthClientForUpdate(logContext, tx, client)
+	if err != nil {
+		return err
+	}
+	if current == nil { //Not a client of the tenant, nothing to delete
+		return nil
+	}
+	if err := s.oauthClients.DeleteOAuthClient(logContext, tx, client); err != nil {
+		return err
+	}
+	if err := s.audit(logContext, tx, caller, "oauth_client.delete", "oauth_client:"+strconv.Itoa(client.ID), current, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+	logger.Info().Msgf("[DeleteOAuthClient] OAuth client %d deleted", client.ID)
+	return nil
+}
+
+// ClientCredentials Authenticates an OAuth client and issues an access token for the requested scopes,
+// every scope of the client when scope is empty. Answers with the access token response of RFC 6749 section 5.1
+func (s *Services) ClientCredentials(logContext *u.LoggerContext, clientID string, secret string, scope string) (map[string]interface{}, error) {
+	logger := zerolog.Ctx(*logContext)
+	if clientID == "" || secret == "" {
+		return nil, errInvalidClient
+	}
+	client, err := s.oauthClients.GetOAuthClientByClientID(logContext, &repo.OAuthClient{ClientID: clientID})
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(hashToken(secret))) != 1 {
+		logger.Error().Msgf("[ClientCredentials] Invalid credentials for client %s", clientID)
+		return nil, errInvalidClient
+	}
+
+	granted := strings.Fields(client.Scopes)
+	scopes := granted
+	if scope != "" {
+		scopes = strings.Fields(scope)
+		for _, v := range scopes {
+			if !slices.Contains(granted, v) {
+				logger.Error().Msgf("[ClientCredentials] Scope %s not granted to client %s", v, clientID)
+				return nil, &OAuthError{Code: "invalid_scope", Description: "Scope not granted to the client: " + v, Status: http.StatusBadRequest}
+			}
+		}
+	}
+
+	jti, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	claims := &repo.Token{
+		TenantID:    client.TenantID,
+		Role:        repo.ClientRole,
+		Permissions: scopes,
+		ClientID:    client.ClientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   client.ClientID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(clientTokenDuration)),
+		},
+	}
+	tokenString, err := s.keys.Sign(claims)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ClientCredentials] Error signing token: %s", err)
+		return nil, err
+	}
+	logger.Info().Msgf("[ClientCredentials] Token issued to client %s", clientID)
+	return map[string]interface{}{
+		"access_token": tokenString,
+		"token_type":   "Bearer",
+		"expires_in":   int(clientTokenDuration.Seconds()),
+		"scope":        strings.Join(scopes, " "),
+	}, nil
+}
+
+// IsClientActive Checks if the OAuth client of a token is still registered
+func (s *Services) IsClientActive(logContext *u.LoggerContext, clientID string) bool {
+	client, err := s.oauthClients.GetOAuthClientByClientID(logContext, &repo.OAuthClient{ClientID: clientID})
+	return err == nil && client != nil
+}
+
+// IntrospectToken Answers if an access token is active, as defined on RFC 7662.
+// Expired, revoked, MFA challenge tokens and tokens of deleted clients are all just inactive.
+func (s *Services) IntrospectToken(logContext *u.LoggerContext, tokenString string) map[string]interface{} {
+	logger := zerolog.Ctx(*logContext)
+	inactive := map[string]interface{}{"active": false}
+	token, err := jwt.ParseWithClaims(tokenString, &repo.Token{}, s.keys.Keyfunc, jwt.WithValidMethods(s.keys.Algorithms()))
+	if err != nil || !token.Valid {
+		return inactive
+	}
+	claims := token.Claims.(*repo.Token)
+	if slices.Contains(claims.Audience, repo.MfaAudience) || s.IsTokenRevoked(logContext, claims) {
+		return inactive
+	}
+	if claims.ClientID != "" && !s.IsClientActive(logContext, claims.ClientID) {
+		logger.Info().Msgf("[IntrospectToken] Client %s of the token no longer exists", claims.ClientID)
+		return inactive
+	}
+
+	resp := map[string]interface{}{
+		"active":     true,
+		"token_type": "Bearer",
+		"scope":      strings.Join(claims.Permissions, " "),
+		"jti":        claims.ID,
+		"tenant_id":  repo.TenantOrDefault(claims.TenantID),
+	}
+	if claims.ClientID != "" {
+		resp["client_id"] = claims.ClientID
+		resp["sub"] = claims.Subject
+	} else {
+		resp["sub"] = strconv.Itoa(claims.UserID)
+		resp["role"] = claims.Role
+	}
+	if claims.ExpiresAt != nil {
+		resp["exp"] = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp["iat"] = claims.IssuedAt.Unix()
+	}
+	return resp
+}
+
+// AsOAuthError Converts any error of the OAuth endpoints to an OAuthError
+func AsOAuthError(err error) *OAuthError {
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		return oauthErr
+	}
+	return &OAuthError{Code: "server_error", Description: "Connection error. Please retry", Status: http.StatusInternalServerError}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/mail"
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+const passwordResetDuration = time.Hour
+
+// ErrInvalidResetToken the reset token does not exist, was already used or expired
+var ErrInvalidResetToken = errors.New("invalid password reset token")
+
+// ForgotPassword Sends a single use password reset token to the email of an user of the tenant, replacing older ones.
+// Unknown emails and failures sending the email are only logged, so callers can't find out which emails have an account.
+// Requests are throttled by email and by client ip, returning ThrottledError. Tokens sent are recorded on the audit log
+func (s *Services) ForgotPassword(logContext *u.LoggerContext, caller *repo.AuditCaller, tenantID int, email string, ip string) error {
+	logger := zerolog.Ctx(*logContext)
+	wait, err := s.throttleWait(logContext, forgotAccountPolicy.key(email), forgotIPPolicy.key(ip))
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ForgotPassword] Error reading reset attempts: %s", err)
+		return err
+	}
+	if wait > 0 {
+		logger.Warn().Msgf("[ForgotPassword] Password reset throttled for %s from %s, retry after %s", email, ip, wait)
+		return &ThrottledError{Wait: wait}
+	}
+	forgotAccountPolicy.registerFailure(logContext, s.loginAttempts, email)
+	forgotIPPolicy.registerFailure(logContext, s.loginAttempts, ip)
+
+	account, err := s.users.GetUserByEmail(logContext, &repo.User{Email: email, TenantID: tenantID}, false)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Info().Msg("[ForgotPassword] Email not found, nothing sent")
+			return nil
+		}
+		return err
+	}
+	token, err := randomToken()
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ForgotPassword] Error generating token: %s", err)
+		return err
+	}
+
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ForgotPassword] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+	now := time.Now()
+	reset := &repo.PasswordReset{
+		UserID:     account.ID,
+		TokenHash:  hashToken(token),
+		Status:     repo.PasswordResetActive,
+		Tstampinit: now.Unix(),
+		Tstampexp:  now.Add(passwordResetDuration).Unix(),
+	}
+	if err := s.passwordResets.UseUserPasswordResets(logContext, tx, reset); err != nil {
+		return err
+	}
+	if err := s.passwordResets.InsertPasswordReset(logContext, tx, reset); err != nil {
+		return err
+	}
+	if err := s.audit(logContext, tx, onAccount(caller, account), "user.password.forgot", "user:"+strconv.Itoa(account.ID), nil, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+
+	msg := mail.Message{
+		To:      account.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Someone asked to reset the password of this account.\n\n"+
+			"Use this token on POST /api/password/reset within %d minutes:\n\n%s\n\n"+
+			"If it wasn't you, ignore this email.\n", int(passwordResetDuration.Minutes()), token),
+	}
+	if err := s.mailer.Send(logContext, msg); err != nil { //Answered as a success, as for unknown emails
+		logger.Error().Err(err).Msgf("[ForgotPassword] Error sending password reset to user %d: %s", account.ID, err)
+		return nil
+	}
+	logger.Info().Msgf("[ForgotPassword] Password reset sent to user %d", account.ID)
+	return nil
+}
+
+// ResetPassword Sets a new password with a reset token, recording it on the audit log without the password. Every
+// session of the user is revoked and the account unlocked
+func (s *Services) ResetPassword(logContext *u.LoggerContext, caller *repo.AuditCaller, token string, password string) error {
+	logger := zerolog.Ctx(*logContext)
+	if err := u.ValidatePassword(password); err != nil {
+		return err
+	}
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ResetPassword] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+
+	reset, err := s.passwordResets.GetPasswordResetByHash(logContext, tx, &repo.PasswordReset{TokenHash: hashToken(token)})
+	if err != nil {
+		return err
+	}
+	if reset == nil || reset.Status != repo.PasswordResetActive || reset.Tstampexp < time.Now().Unix() {
+		logger.Error().Msg("[ResetPassword] Invalid, used or expired reset token")
+		return ErrInvalidResetToken
+	}
+	account, err := s.users.GetUserForUpdate(logContext, tx, &repo.User{ID: reset.UserID})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	if err := s.users.UpdatePassword(logContext, tx, account, password); err != nil {
+		return err
+	}
+	if err := s.passwordResets.UseUserPasswordResets(logContext, tx, reset); err != nil {
+		return err
+	}
+	revoked, err := s.revokeUserTokens(logContext, tx, account.ID)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ResetPassword] Error revoking user tokens: %s", err)
+		return err
+	}
+	if err := s.audit(logContext, tx, onBehalf(caller, account), "user.password.reset", "user:"+strconv.Itoa(account.ID), nil, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+	s.commitUserRevocation(revoked)
+	s.registerLoginSuccess(logContext, account.Email)
+	logger.Info().Msgf("[ResetPassword] Password reset for user %d", account.ID)
+	return nil
+}
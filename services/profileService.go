@@ -0,0 +1,254 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/mail"
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+const emailChangeDuration = 24 * time.Hour
+
+// ErrInvalidPassword the current password sent to confirm a change is wrong
+var ErrInvalidPassword = errors.New("invalid password")
+
+// ErrEmailInUse another user already has the email
+var ErrEmailInUse = errors.New("email already in use")
+
+// ErrInvalidEmailToken the email change token does not exist, was already used or expired
+var ErrInvalidEmailToken = errors.New("invalid email change token")
+
+// UpdateProfile Updates the fields an user may change on its own account, only the name for now, recording the
+// change on the audit log
+func (s *Services) UpdateProfile(logContext *u.LoggerContext, caller *repo.AuditCaller, userID int, name string) (*repo.User, error) {
+	logger := zerolog.Ctx(*logContext)
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UpdateProfile] Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer tx.Rollback(logContext)
+	user := &repo.User{ID: userID, Name: name}
+	current, err := s.users.GetUserForUpdate(logContext, tx, user)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.users.Upsert(logContext, tx, user); err != nil {
+		return nil, err
+	}
+	if err := s.audit(logContext, tx, caller, "user.profile", "user:"+strconv.Itoa(userID), current, mergeUser(*current, user)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	return s.users.GetUserByID(logContext, user)
+}
+
+// ChangePassword Changes the password of the user after checking the current one, recording it on the audit log
+// without the password. Every token of the user is revoked, so the user must log in again.
+func (s *Services) ChangePassword(logContext *u.LoggerContext, caller *repo.AuditCaller, userID int, request *repo.PasswordChangeRequest, ip string) error {
+	logger := zerolog.Ctx(*logContext)
+	if err := u.ValidatePassword(request.Password); err != nil {
+		return err
+	}
+	account, err := s.checkCurrentPassword(logContext, userID, request.CurrentPassword, ip)
+	if err != nil {
+		return err
+	}
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ChangePassword] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+	if err := s.users.UpdatePassword(logContext, tx, account, request.Password); err != nil {
+		return err
+	}
+	revoked, err := s.revokeUserTokens(logContext, tx, account.ID)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[ChangePassword] Error revoking user tokens: %s", err)
+		return err
+	}
+	if err := s.audit(logContext, tx, caller, "user.password", "user:"+strconv.Itoa(account.ID), nil, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+	s.commitUserRevocation(revoked)
+	logger.Info().Msgf("[ChangePassword] Password changed by user %d", account.ID)
+	return nil
+}
+
+// RequestEmailChange Sends a verification token to the new email, the email only changes after VerifyEmailChange.
+// The request is recorded on the audit log
+func (s *Services) RequestEmailChange(logContext *u.LoggerContext, caller *repo.AuditCaller, userID int, request *repo.EmailChangeRequest, ip string) error {
+	logger := zerolog.Ctx(*logContext)
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+	account, err := s.checkCurrentPassword(logContext, userID, request.Password, ip)
+	if err != nil {
+		return err
+	}
+	if inUse, err := s.emailInUse(logContext, account.TenantID, email); err != nil || inUse {
+		if err == nil {
+			err = ErrEmailInUse
+		}
+		return err
+	}
+	token, err := randomToken()
+	if err != nil {
+		logger.Error().Err(err).Msgf("[RequestEmailChange] Error generating token: %s", err)
+		return err
+	}
+
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[RequestEmailChange] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+	now := time.Now()
+	change := &repo.EmailChange{
+		UserID:     account.ID,
+		Email:      email,
+		TokenHash:  hashToken(token),
+		Status:     repo.EmailChangeActive,
+		Tstampinit: now.Unix(),
+		Tstampexp:  now.Add(emailChangeDuration).Unix(),
+	}
+	if err := s.emailChanges.UseUserEmailChanges(logContext, tx, change); err != nil {
+		return err
+	}
+	if err := s.emailChanges.InsertEmailChange(logContext, tx, change); err != nil {
+		return err
+	}
+	if err := s.audit(logContext, tx, caller, "user.email.request", "user:"+strconv.Itoa(account.ID), nil, map[string]string{"email": email}); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+
+	msg := mail.Message{
+		To:      email,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf("Use this token on POST /api/me/email/verify within %d hours to confirm this email:\n\n%s\n\n"+
+			"If you didn't ask for it, ignore this email.\n", int(emailChangeDuration.Hours()), token),
+	}
+	if err := s.mailer.Send(logContext, msg); err != nil {
+		return err
+	}
+	logger.Info().Msgf("[RequestEmailChange] Email change requested by user %d", account.ID)
+	return nil
+}
+
+// VerifyEmailChange Changes the email of the user that asked for the token, telling the old email about it and
+// recording the change on the audit log
+func (s *Services) VerifyEmailChange(logContext *u.LoggerContext, caller *repo.AuditCaller, token string) (*repo.User, error) {
+	logger := zerolog.Ctx(*logContext)
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[VerifyEmailChange] Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer tx.Rollback(logContext)
+
+	change, err := s.emailChanges.GetEmailChangeByHash(logContext, tx, &repo.EmailChange{TokenHash: hashToken(token)})
+	if err != nil {
+		return nil, err
+	}
+	if change == nil || change.Status != repo.EmailChangeActive || change.Tstampexp < time.Now().Unix() {
+		logger.Error().Msg("[VerifyEmailChange] Invalid, used or expired email change token")
+		return nil, ErrInvalidEmailToken
+	}
+	account, err := s.users.GetUserForUpdate(logContext, tx, &repo.User{ID: change.UserID})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidEmailToken
+		}
+		return nil, err
+	}
+	if inUse, err := s.emailInUse(logContext, account.TenantID, change.Email); err != nil || inUse { //Taken since the request
+		if err == nil {
+			err = ErrEmailInUse
+		}
+		return nil, err
+	}
+	current := *account
+	oldEmail := account.Email
+	account.Email = change.Email
+	if err := s.users.UpdateEmail(logContext, tx, account); err != nil {
+		return nil, err
+	}
+	if err := s.emailChanges.UseUserEmailChanges(logContext, tx, change); err != nil {
+		return nil, err
+	}
+	if err := s.audit(logContext, tx, onBehalf(caller, account), "user.email", "user:"+strconv.Itoa(account.ID), &current, account); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	logger.Info().Msgf("[VerifyEmailChange] Email changed by user %d", account.ID)
+
+	msg := mail.Message{
+		To:      oldEmail,
+		Subject: "Your email was changed",
+		Body:    fmt.Sprintf("The email of your account was changed to %s.\n\nIf it wasn't you, reset your password.\n", change.Email),
+	}
+	if err := s.mailer.Send(logContext, msg); err != nil {
+		logger.Error().Err(err).Msgf("[VerifyEmailChange] Error notifying old email of user %d: %s", account.ID, err)
+	}
+	return account, nil
+}
+
+// checkCurrentPassword Verifies the password of the logged user, counting failures like a login
+func (s *Services) checkCurrentPassword(logContext *u.LoggerContext, userID int, password string, ip string) (*repo.User, error) {
+	logger := zerolog.Ctx(*logContext)
+	account, err := s.users.GetUserByID(logContext, &repo.User{ID: userID})
+	if err != nil {
+		return nil, err
+	}
+	wait, err := s.CheckLoginThrottle(logContext, account.Email, ip)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		return nil, &ThrottledError{Wait: wait}
+	}
+	account, err = s.users.GetUserByEmail(logContext, account, true)
+	if err != nil {
+		return nil, err
+	}
+	match, _, err := u.VerifyPassword(account.Password, password)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[checkCurrentPassword] Cannot verify password of user %d: %s", account.ID, err)
+	}
+	if !match {
+		logger.Error().Msgf("[checkCurrentPassword] Invalid password for user %d", account.ID)
+		s.registerLoginFailure(logContext, account.Email, ip)
+		return nil, ErrInvalidPassword
+	}
+	account.Password = ""
+	return account, nil
+}
+
+// emailInUse Checks if an user of the tenant already has the email
+func (s *Services) emailInUse(logContext *u.LoggerContext, tenantID int, email string) (bool, error) {
+	_, err := s.users.GetUserByEmail(logContext, &repo.User{Email: email, TenantID: tenantID}, false)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
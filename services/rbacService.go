@@ -0,0 +1,223 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+// ErrPermissionNotGranted a role or user would get a permission the caller doesn't have
+var ErrPermissionNotGranted = errors.New("permission not granted to the caller")
+
+// ListRoles Lists all roles with their permissions
+func (s *Services) ListRoles(logContext *u.LoggerContext, role *repo.Role) ([]repo.Role, error) {
+	return s.rbac.ListRoles(logContext)
+}
+
+// ListPermissions Lists all permissions
+func (s *Services) ListPermissions(logContext *u.LoggerContext, permission *repo.Permission) ([]repo.Permission, error) {
+	return s.rbac.ListPermissions(logContext)
+}
+
+// UpsertPermission Inserts or updates a permission, recording the change on the audit log
+func (s *Services) UpsertPermission(logContext *u.LoggerContext, caller *repo.AuditCaller, permission *repo.Permission) (*repo.Permission, error) {
+	logger := zerolog.Ctx(*logContext)
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UpsertPermission] Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer tx.Rollback(logContext)
+	var current *repo.Permission
+	if permission.ID != 0 {
+		if current, err = s.rbac.GetPermissionByID(logContext, tx, permission); err != nil {
+			return nil, err
+		}
+	}
+	val, err := s.rbac.UpsertPermission(logContext, tx, permission)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		err = s.audit(logContext, tx, caller, "permission.create", "permission:"+strconv.Itoa(val.ID), nil, val)
+	} else {
+		err = s.audit(logContext, tx, caller, "permission.update", "permission:"+strconv.Itoa(val.ID), current, val)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// UpsertRole Inserts or updates a role and its permissions, revoking the tokens of its users and recording the change
+// on the audit log. Permissions added to the role must be permissions of the caller
+func (s *Services) UpsertRole(logContext *u.LoggerContext, caller *repo.AuditCaller, permissions []string, role *repo.Role) (*repo.Role, error) {
+	logger := zerolog.Ctx(*logContext)
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UpsertRole] Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer tx.Rollback(logContext)
+	slices.Sort(role.Permissions)
+	role.Permissions = slices.Compact(role.Permissions)
+	var revoked []*repo.RevokedUser
+	var current *repo.Role
+	if role.ID != 0 { //Before the update, users may hold the role by its current name
+		if current, err = s.rbac.GetRoleByID(logContext, tx, role); err != nil {
+			return nil, err
+		}
+		if revoked, err = s.revokeRoleUsers(logContext, tx, role); err != nil {
+			return nil, err
+		}
+	}
+	for _, permission := range role.Permissions { //The caller can't grant more than it already has
+		if (current == nil || !slices.Contains(current.Permissions, permission)) && !slices.Contains(permissions, permission) {
+			logger.Error().Msgf("[UpsertRole] Permission %s not granted to the caller", permission)
+			return nil, ErrPermissionNotGranted
+		}
+	}
+	val, err := s.rbac.UpsertRole(logContext, tx, role)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		err = s.audit(logContext, tx, caller, "role.create", "role:"+strconv.Itoa(val.ID), nil, val)
+	} else {
+		err = s.audit(logContext, tx, caller, "role.update", "role:"+strconv.Itoa(val.ID), current, val)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	for _, v := range revoked {
+		s.commitUserRevocation(v)
+	}
+	return val, nil
+}
+
+// DeleteRole Deletes a role, revoking the tokens of its users and recording it on the audit log
+func (s *Services) DeleteRole(logContext *u.LoggerContext, caller *repo.AuditCaller, role *repo.Role) error {
+	logger := zerolog.Ctx(*logContext)
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[DeleteRole] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+	current, err := s.rbac.GetRoleByID(logContext, tx, role)
+	if err != nil || current == nil { //Nothing to delete
+		return err
+	}
+	revoked, err := s.revokeRoleUsers(logContext, tx, role)
+	if err != nil {
+		return err
+	}
+	if err := s.rbac.DeleteRole(logContext, tx, role); err != nil {
+		return err
+	}
+	if err := s.audit(logContext, tx, caller, "role.delete", "role:"+strconv.Itoa(role.ID), current, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+	for _, v := range revoked {
+		s.commitUserRevocation(v)
+	}
+	return nil
+}
+
+// GetUserRoles Lists the roles granted to the user besides the role on user_db
+func (s *Services) GetUserRoles(logContext *u.LoggerContext, user *repo.User) (*repo.UserRoles, error) {
+	logger := zerolog.Ctx(*logContext)
+	if _, err := s.users.GetUserByID(logContext, user); err != nil { //Only users of the tenant of user
+		logger.Error().Err(err).Msgf("[GetUserRoles] Error retrieving user: %s", err)
+		return nil, err
+	}
+	roles, err := s.rbac.ListUserRoles(logContext, nil, user)
+	if err != nil {
+		return nil, err
+	}
+	return &repo.UserRoles{UserID: user.ID, Roles: roles}, nil
+}
+
+// SetUserRoles Replaces the roles granted to the user, revoking its tokens and recording the change on the audit log.
+// Roles added to the user must have only permissions of the caller
+func (s *Services) SetUserRoles(logContext *u.LoggerContext, caller *repo.AuditCaller, permissions []string, user *repo.User, roles []string) (*repo.UserRoles, error) {
+	logger := zerolog.Ctx(*logContext)
+	if _, err := s.users.GetUserByID(logContext, user); err != nil {
+		logger.Error().Err(err).Msgf("[SetUserRoles] Error retrieving user: %s", err)
+		return nil, err
+	}
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[SetUserRoles] Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer tx.Rollback(logContext)
+	slices.Sort(roles)
+	roles = slices.Compact(roles)
+	current, err := s.rbac.ListUserRoles(logContext, tx, user)
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.rbac.ListRoles(logContext)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range all { //The caller can't grant more than it already has, unknown roles are refused by the store
+		if !slices.Contains(roles, v.Name) || slices.Contains(current, v.Name) {
+			continue
+		}
+		for _, permission := range v.Permissions {
+			if !slices.Contains(permissions, permission) {
+				logger.Error().Msgf("[SetUserRoles] Role %s has permission %s, not granted to the caller", v.Name, permission)
+				return nil, ErrPermissionNotGranted
+			}
+		}
+	}
+	if err := s.rbac.SetUserRoles(logContext, tx, user, roles); err != nil {
+		return nil, err
+	}
+	revoked, err := s.revokeUserTokens(logContext, tx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := &repo.UserRoles{UserID: user.ID, Roles: roles}
+	before := &repo.UserRoles{UserID: user.ID, Roles: current}
+	if err := s.audit(logContext, tx, caller, "user.roles", "user:"+strconv.Itoa(user.ID), before, result); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	s.commitUserRevocation(revoked)
+	return result, nil
+}
+
+// revokeRoleUsers Revokes the tokens of every user holding the role, their embedded permissions are outdated
+func (s *Services) revokeRoleUsers(logContext *u.LoggerContext, tx repo.Transaction, role *repo.Role) ([]*repo.RevokedUser, error) {
+	users, err := s.rbac.ListRoleUsers(logContext, tx, role)
+	if err != nil {
+		return nil, err
+	}
+	var revoked []*repo.RevokedUser
+	for _, userID := range users {
+		v, err := s.revokeUserTokens(logContext, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		revoked = append(revoked, v)
+	}
+	return revoked, nil
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	mailer "github.com/elnerribeiro/go-ws-db-auth-v2/mail"
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+const activationDuration = 48 * time.Hour
+
+// ErrInvalidEmail the email is not a valid address
+var ErrInvalidEmail = errors.New("invalid email")
+
+// ErrDomainNotAllowed the email domain is not on register.allowed.domains
+var ErrDomainNotAllowed = errors.New("email domain not allowed")
+
+// ErrInvalidActivationToken the activation token does not exist, was already used or expired
+var ErrInvalidActivationToken = errors.New("invalid activation token")
+
+// Register Creates a pending user and sends it an activation token. Registering a pending email again replaces the
+// pending user, with its password, name and tokens, so whoever registered it first can't keep the account. Registering
+// an email that already has an account only warns its owner, so callers can't find out which emails exist.
+// New users are recorded on the audit log, with the pending user they replaced. Requests are throttled by email and by
+// client ip, returning ThrottledError
+func (s *Services) Register(logContext *u.LoggerContext, caller *repo.AuditCaller, request *repo.RegisterRequest, ip string) error {
+	logger := zerolog.Ctx(*logContext)
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+	if len(s.allowedDomains) > 0 && !slices.Contains(s.allowedDomains, email[strings.LastIndex(email, "@")+1:]) {
+		logger.Error().Msgf("[Register] Domain of %s not allowed", email)
+		return ErrDomainNotAllowed
+	}
+	if err := u.ValidatePassword(request.Password); err != nil {
+		return err
+	}
+	if err := s.checkTenant(logContext, request.TenantID); err != nil {
+		return err
+	}
+	wait, err := s.throttleWait(logContext, registerAccountPolicy.key(email), registerIPPolicy.key(ip))
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Register] Error reading register attempts: %s", err)
+		return err
+	}
+	if wait > 0 {
+		logger.Warn().Msgf("[Register] Registration throttled for %s from %s, retry after %s", email, ip, wait)
+		return &ThrottledError{Wait: wait}
+	}
+	registerAccountPolicy.registerFailure(logContext, s.loginAttempts, email)
+	registerIPPolicy.registerFailure(logContext, s.loginAttempts, ip)
+
+	account, err := s.users.GetUserByEmail(logContext, &repo.User{Email: email, TenantID: request.TenantID}, false)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if account != nil && account.Status != repo.UserPending {
+		return s.warnRegistered(logContext, email, account.ID)
+	}
+
+	tx, err := s.transactor.BeginTenant(logContext, repo.TenantOrDefault(request.TenantID))
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Register] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+	var replaced *repo.User
+	if account != nil {
+		pending, err := s.users.GetUserForUpdate(logContext, tx, &repo.User{ID: account.ID})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if pending != nil && pending.Status != repo.UserPending { //Activated since read
+			return s.warnRegistered(logContext, email, pending.ID)
+		}
+		if pending != nil { //Deleting cascades to its activation tokens
+			if err := s.users.Delete(logContext, tx, pending); err != nil {
+				return err
+			}
+			logger.Info().Msgf("[Register] Pending user %d replaced", pending.ID)
+			replaced = pending
+		}
+	}
+	user := &repo.User{Email: email, Name: request.Name, Password: request.Password, Role: s.registerRole, Status: repo.UserPending}
+	if account, err = s.users.Upsert(logContext, tx, user); err != nil {
+		return err
+	}
+	token, err := s.issueActivation(logContext, tx, account.ID)
+	if err != nil {
+		return err
+	}
+	if replaced == nil {
+		err = s.audit(logContext, tx, onAccount(caller, account), "user.register", "user:"+strconv.Itoa(account.ID), nil, account)
+	} else {
+		err = s.audit(logContext, tx, onAccount(caller, account), "user.register", "user:"+strconv.Itoa(account.ID), replaced, account)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Activate your account",
+		Body: fmt.Sprintf("Use this token on POST /api/register/activate within %d hours to activate your account:\n\n%s\n",
+			int(activationDuration.Hours()), token),
+	}
+	if err := s.mailer.Send(logContext, msg); err != nil {
+		return err
+	}
+	logger.Info().Msgf("[Register] Activation sent to user %d", account.ID)
+	return nil
+}
+
+// warnRegistered Warns the owner of an account that someone tried to register its email again
+func (s *Services) warnRegistered(logContext *u.LoggerContext, email string, userID int) error {
+	logger := zerolog.Ctx(*logContext)
+	logger.Info().Msgf("[Register] User %d already registered", userID)
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Account already registered",
+		Body:    "Someone tried to register a new account with this email, but it already has one.\n\nIf it was you, log in or reset your password.\n",
+	}
+	return s.mailer.Send(logContext, msg)
+}
+
+// Activate Activates a pending user with the token sent by Register, recording it on the audit log
+func (s *Services) Activate(logContext *u.LoggerContext, caller *repo.AuditCaller, token string) error {
+	logger := zerolog.Ctx(*logContext)
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Activate] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+
+	activation, err := s.activations.GetActivationByHash(logContext, tx, &repo.AccountActivation{TokenHash: hashToken(token)})
+	if err != nil {
+		return err
+	}
+	if activation == nil || activation.Status != repo.ActivationActive || activation.Tstampexp < time.Now().Unix() {
+		logger.Error().Msg("[Activate] Invalid, used or expired activation token")
+		return ErrInvalidActivationToken
+	}
+	account, err := s.users.GetUserForUpdate(logContext, tx, &repo.User{ID: activation.UserID})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidActivationToken
+		}
+		return err
+	}
+	if account.Status != repo.UserPending { //Disabled accounts are only enabled by admins
+		return ErrInvalidActivationToken
+	}
+	activated := &repo.User{ID: account.ID, Status: repo.UserActive}
+	if _, err := s.users.Upsert(logContext, tx, activated); err != nil {
+		return err
+	}
+	if err := s.activations.UseUserActivations(logContext, tx, activation); err != nil {
+		return err
+	}
+	if err := s.audit(logContext, tx, onBehalf(caller, account), "user.activate", "user:"+strconv.Itoa(account.ID), account, mergeUser(*account, activated)); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+	logger.Info().Msgf("[Activate] User %d activated", account.ID)
+	return nil
+}
+
+// SetUserStatus Enables or disables an user, disabling revokes every token of the user
+func (s *Services) SetUserStatus(logContext *u.LoggerContext, caller *repo.AuditCaller, user *repo.User) error {
+	_, err := s.Upsert(logContext, caller, &repo.User{ID: user.ID, Status: user.Status, TenantID: user.TenantID})
+	return err
+}
+
+// issueActivation Creates a new activation token for the user, replacing older ones
+func (s *Services) issueActivation(logContext *u.LoggerContext, tx repo.Transaction, userID int) (string, error) {
+	logger := zerolog.Ctx(*logContext)
+	token, err := randomToken()
+	if err != nil {
+		logger.Error().Err(err).Msgf("[issueActivation] Error generating token: %s", err)
+		return "", err
+	}
+	now := time.Now()
+	activation := &repo.AccountActivation{
+		UserID:     userID,
+		TokenHash:  hashToken(token),
+		Status:     repo.ActivationActive,
+		Tstampinit: now.Unix(),
+		Tstampexp:  now.Add(activationDuration).Unix(),
+	}
+	if err := s.activations.UseUserActivations(logContext, tx, activation); err != nil {
+		return "", err
+	}
+	if err := s.activations.InsertActivation(logContext, tx, activation); err != nil {
+		return "", err
+	}
+	return token, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+const revocationCacheRefresh = 30 * time.Second
+
+// revocationCache keeps revoked tokens in memory, reloading them from database periodically
+// so revocations made by other instances are seen without querying on every request
+type revocationCache struct {
+	sync.RWMutex
+	tokens   map[string]int64 //jti -> expiration
+	users    map[int]int64    //user id -> tokens issued up to this timestamp are revoked
+	loadedAt time.Time
+}
+
+// newRevocationCache creates an empty revocationCache, loaded on its first use
+func newRevocationCache() *revocationCache {
+	return &revocationCache{tokens: map[string]int64{}, users: map[int]int64{}}
+}
+
+// IsTokenRevoked Checks if an access token was revoked, by itself, by its user or by the admin impersonating the user
+func (s *Services) IsTokenRevoked(logContext *u.LoggerContext, token *repo.Token) bool {
+	s.revocations.reloadIfStale(logContext, s.tokens)
+	s.revocations.RLock()
+	defer s.revocations.RUnlock()
+	if _, ok := s.revocations.tokens[token.ID]; ok && token.ID != "" {
+		return true
+	}
+	if revokedAt, ok := s.revocations.users[token.UserID]; ok && (token.IssuedAt == nil || token.IssuedAt.Unix() <= revokedAt) {
+		return true
+	}
+	if token.Act != nil { //Impersonation tokens also die with the tokens of the admin behind them
+		if revokedAt, ok := s.revocations.users[token.Act.UserID]; ok && (token.IssuedAt == nil || token.IssuedAt.Unix() <= revokedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// Logout Revokes the current access token and, if given, the refresh token family. Called with an API key, which
+// has no session to end, the key itself is revoked
+func (s *Services) Logout(logContext *u.LoggerContext, caller *repo.AuditCaller, token *repo.Token, refreshToken string) error {
+	logger := zerolog.Ctx(*logContext)
+	if keyID, ok := apiKeyOf(token); ok {
+		return s.RevokeApiKey(logContext, caller, &repo.ApiKey{ID: keyID, UserID: token.UserID})
+	}
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Logout] Error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback(logContext)
+
+	revoked := &repo.RevokedToken{Jti: token.ID, UserID: token.UserID}
+	if token.ExpiresAt != nil {
+		revoked.Tstampexp = token.ExpiresAt.Unix()
+	}
+	if err := s.tokens.InsertRevokedToken(logContext, tx, revoked); err != nil {
+		return err
+	}
+	if refreshToken != "" {
+		search := &repo.RefreshToken{TokenHash: hashToken(refreshToken)}
+		current, err := s.tokens.GetRefreshTokenByHash(logContext, tx, search)
+		if err != nil {
+			return err
+		}
+		if current != nil && current.UserID == token.UserID {
+			if err := s.tokens.RevokeFamily(logContext, tx, current); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+
+	s.revocations.Lock()
+	s.revocations.tokens[revoked.Jti] = revoked.Tstampexp
+	s.revocations.Unlock()
+	logger.Info().Msgf("[Logout] User logged out: %d", token.UserID)
+	return nil
+}
+
+// revokeUserTokens Revokes every access and refresh token issued to the user up to now, and every API key of the user,
+// which outlive the revocations kept on the cache. The cache is only updated by commitUserRevocation, after the
+// transaction is committed.
+func (s *Services) revokeUserTokens(logContext *u.LoggerContext, tx repo.Transaction, userID int) (*repo.RevokedUser, error) {
+	revoked := &repo.RevokedUser{UserID: userID, Tstamprevoked: time.Now().Unix()}
+	if err := s.tokens.UpsertRevokedUser(logContext, tx, revoked); err != nil {
+		return nil, err
+	}
+	refresh := &repo.RefreshToken{UserID: userID}
+	if err := s.tokens.RevokeUserRefreshTokens(logContext, tx, refresh); err != nil {
+		return nil, err
+	}
+	if err := s.apiKeys.RevokeUserApiKeys(logContext, tx, &repo.ApiKey{UserID: userID}); err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
+
+// commitUserRevocation Applies a committed user revocation to the cache
+func (s *Services) commitUserRevocation(revoked *repo.RevokedUser) {
+	s.revocations.Lock()
+	defer s.revocations.Unlock()
+	if revoked.Tstamprevoked > s.revocations.users[revoked.UserID] {
+		s.revocations.users[revoked.UserID] = revoked.Tstamprevoked
+	}
+}
+
+// reloadIfStale Merges revocations from the token store into the cache and drops expired entries.
+// Entries are never removed before they expire, so a failed reload keeps the current state.
+func (cache *revocationCache) reloadIfStale(logContext *u.LoggerContext, tokens repo.TokenStore) {
+	cache.RLock()
+	stale := time.Since(cache.loadedAt) > revocationCacheRefresh
+	cache.RUnlock()
+	if !stale {
+		return
+	}
+	logger := zerolog.Ctx(*logContext)
+	now := time.Now()
+	revokedTokens, err := tokens.ListRevokedTokens(logContext, now.Unix())
+	if err != nil {
+		logger.Error().Err(err).Msgf("[reloadIfStale] Error loading revoked tokens: %s", err)
+		return
+	}
+	oldest := now.Add(-accessTokenDuration).Unix()
+	users, err := tokens.ListRevokedUsers(logContext, oldest)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[reloadIfStale] Error loading revoked users: %s", err)
+		return
+	}
+
+	cache.Lock()
+	defer cache.Unlock()
+	for jti, exp := range cache.tokens {
+		if exp < now.Unix() {
+			delete(cache.tokens, jti)
+		}
+	}
+	for userID, revokedAt := range cache.users {
+		if revokedAt < oldest {
+			delete(cache.users, userID)
+		}
+	}
+	for _, v := range revokedTokens {
+		cache.tokens[v.Jti] = v.Tstampexp
+	}
+	for _, v := range users {
+		if v.Tstamprevoked > cache.users[v.UserID] {
+			cache.users[v.UserID] = v.Tstamprevoked
+		}
+	}
+	cache.loadedAt = now
+}
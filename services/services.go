@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"github.com/elnerribeiro/go-ws-db-auth-v2/config"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/keys"
+	"github.com/elnerribeiro/go-ws-db-auth-v2/mail"
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+)
+
+// Services the business rules of the application, with the stores, keys and mailer they use
+type Services struct {
+	keys           *keys.KeyRing
+	mailer         mail.Mailer
+	transactor     repo.Transactor
+	users          repo.UserStore
+	batches        repo.BatchStore
+	tokens         repo.TokenStore
+	rbac           repo.RBACStore
+	auditLog       repo.AuditStore
+	mfa            repo.MfaStore
+	loginAttempts  repo.LoginAttemptStore
+	apiKeys        repo.ApiKeyStore
+	oauthClients   repo.OAuthClientStore
+	tenants        repo.TenantStore
+	activations    repo.ActivationStore
+	passwordResets repo.PasswordResetStore
+	emailChanges   repo.EmailChangeStore
+	allowedDomains []string
+	registerRole   string
+	revocations    *revocationCache
+	background     context.Context //parent of the work outliving its request, as async batches, cancelled by Stop
+	stop           context.CancelFunc
+	running        sync.WaitGroup
+}
+
+// New Creates the services on the stores, with the registration rules of the settings
+func New(ring *keys.KeyRing, mailer mail.Mailer, stores repo.Stores, cfg *config.Config) *Services {
+	s := &Services{
+		keys:           ring,
+		mailer:         mailer,
+		transactor:     stores.Transactor,
+		users:          stores.Users,
+		batches:        stores.Batches,
+		tokens:         stores.Tokens,
+		rbac:           stores.RBAC,
+		auditLog:       stores.Audit,
+		mfa:            stores.Mfa,
+		loginAttempts:  stores.LoginAttempts,
+		apiKeys:        stores.ApiKeys,
+		oauthClients:   stores.OAuthClients,
+		tenants:        stores.Tenants,
+		activations:    stores.Activations,
+		passwordResets: stores.PasswordResets,
+		emailChanges:   stores.EmailChanges,
+		allowedDomains: cfg.Register.AllowedDomains,
+		registerRole:   cfg.Register.Role,
+		revocations:    newRevocationCache(),
+	}
+	s.background, s.stop = context.WithCancel(context.Background())
+	return s
+}
+
+// Stop Cancels the work running in background, as async batches, and waits for it to finish
+func (s *Services) Stop() {
+	s.stop()
+	s.running.Wait()
+}
+
+// goBackground Runs work in background with a context cancelled by Stop, not by the end of the request
+func (s *Services) goBackground(work func(logContext *u.LoggerContext)) {
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		work(u.GetLoggerContext(s.background))
+	}()
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/rs/zerolog"
+)
+
+// ErrUnknownTenant the tenant does not exist on table tenants
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// ListTenants Lists all tenants
+func (s *Services) ListTenants(logContext *u.LoggerContext) ([]repo.Tenant, error) {
+	return s.tenants.ListTenants(logContext)
+}
+
+// UpsertTenant Creates or renames a tenant, recording the change on the audit log
+func (s *Services) UpsertTenant(logContext *u.LoggerContext, caller *repo.AuditCaller, tenant *repo.Tenant) (*repo.Tenant, error) {
+	logger := zerolog.Ctx(*logContext)
+	var current *repo.Tenant
+	if tenant.ID != 0 {
+		var err error
+		if current, err = s.tenants.GetTenantByID(logContext, tenant); err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, ErrUnknownTenant
+		}
+		tenant.Tstampinit = current.Tstampinit
+	} else {
+		tenant.Tstampinit = time.Now().Unix()
+	}
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[UpsertTenant] Error starting transaction: %s", err)
+		return nil, err
+	}
+	defer tx.Rollback(logContext)
+	val, err := s.tenants.UpsertTenant(logContext, tx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		err = s.audit(logContext, tx, caller, "tenant.create", "tenant:"+strconv.Itoa(val.ID), nil, val)
+	} else {
+		err = s.audit(logContext, tx, caller, "tenant.update", "tenant:"+strconv.Itoa(val.ID), current, val)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// checkTenant Returns ErrUnknownTenant when the tenant, 0 meaning the default one, does not exist
+func (s *Services) checkTenant(logContext *u.LoggerContext, tenantID int) error {
+	tenant, err := s.tenants.GetTenantByID(logContext, &repo.Tenant{ID: repo.TenantOrDefault(tenantID)})
+	if err != nil {
+		return err
+	}
+	if tenant == nil {
+		return ErrUnknownTenant
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
+	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+)
+
+const accessTokenDuration = 12 * time.Hour
+const refreshTokenDuration = 30 * 24 * time.Hour
+
+// RefreshToken Rotates a refresh token and issues a new access token
+func (s *Services) RefreshToken(logContext *u.LoggerContext, refreshToken string) map[string]interface{} {
+	logger := zerolog.Ctx(*logContext)
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[RefreshToken] Error starting transaction: %s", err)
+		return u.Message(false, "Connection error. Please retry")
+	}
+	defer tx.Rollback(logContext)
+
+	search := &repo.RefreshToken{TokenHash: hashToken(refreshToken)}
+	current, err := s.tokens.GetRefreshTokenByHash(logContext, tx, search)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[RefreshToken] Connection error. Please retry: %s", err)
+		return u.Message(false, "Connection error. Please retry")
+	}
+	if current == nil {
+		logger.Error().Msg("[RefreshToken] Refresh token not found.")
+		return u.Message(false, "Invalid refresh token")
+	}
+
+	switch current.Status {
+	case repo.RefreshTokenRotated:
+		//An already rotated token is being used again, someone else may hold the family
+		logger.Warn().Msgf("[RefreshToken] Reuse detected for user %d, revoking token family", current.UserID)
+		if err := s.tokens.RevokeFamily(logContext, tx, current); err != nil {
+			return u.Message(false, "Connection error. Please retry")
+		}
+		if err := tx.Commit(logContext); err != nil {
+			return u.Message(false, "Connection error. Please retry")
+		}
+		return u.Message(false, "Invalid refresh token")
+	case repo.RefreshTokenRevoked:
+		logger.Error().Msgf("[RefreshToken] Revoked refresh token used by user %d", current.UserID)
+		return u.Message(false, "Invalid refresh token")
+	}
+	if current.Tstampexp < time.Now().Unix() {
+		logger.Error().Msgf("[RefreshToken] Expired refresh token used by user %d", current.UserID)
+		return u.Message(false, "Refresh token expired")
+	}
+
+	user := &repo.User{ID: current.UserID}
+	account, err := s.users.GetUserByID(logContext, user)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error().Msgf("[RefreshToken] User %d not found, revoking token family", current.UserID)
+			if err := s.tokens.RevokeFamily(logContext, tx, current); err == nil {
+				if err := tx.Commit(logContext); err != nil {
+					return u.Message(false, "Connection error. Please retry")
+				}
+			}
+			return u.Message(false, "Invalid refresh token")
+		}
+		return u.Message(false, "Connection error. Please retry")
+	}
+	if account.Status != repo.UserActive {
+		logger.Error().Msgf("[RefreshToken] User %d is %s, revoking token family", account.ID, account.Status)
+		if err := s.tokens.RevokeFamily(logContext, tx, current); err == nil {
+			if err := tx.Commit(logContext); err != nil {
+				return u.Message(false, "Connection error. Please retry")
+			}
+		}
+		return u.Message(false, "Invalid refresh token")
+	}
+
+	current.Status = repo.RefreshTokenRotated
+	if err := s.tokens.UpdateRefreshTokenStatus(logContext, tx, current); err != nil {
+		return u.Message(false, "Connection error. Please retry")
+	}
+	newRefreshToken, err := s.issueRefreshToken(logContext, tx, account.ID, current.Family)
+	if err != nil {
+		return u.Message(false, "Connection error. Please retry")
+	}
+	tokenString, err := s.generateAccessToken(logContext, account)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[RefreshToken] Error signing token: %s", err)
+		return u.Message(false, "Connection error. Please retry")
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return u.Message(false, "Connection error. Please retry")
+	}
+
+	account.Token = tokenString
+	account.RefreshToken = newRefreshToken
+	logger.Info().Msgf("[RefreshToken] Token refreshed for user: %d", account.ID)
+
+	resp := u.Message(true, "Token refreshed")
+	resp["account"] = account
+	return resp
+}
+
+// generateAccessToken Creates and signs a JWT access token for the account, embedding its roles and permissions
+func (s *Services) generateAccessToken(logContext *u.LoggerContext, account *repo.User) (string, error) {
+	claims, err := s.accessClaims(logContext, account, accessTokenDuration)
+	if err != nil {
+		return "", err
+	}
+
+	// Sign with the current key, its kid goes on the header
+	return s.keys.Sign(claims)
+}
+
+// accessClaims Builds the claims of an access token for the account, valid for duration
+func (s *Services) accessClaims(logContext *u.LoggerContext, account *repo.User, duration time.Duration) (*repo.Token, error) {
+	jti, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.rbac.GetEffectiveRoles(logContext, account)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := s.rbac.GetEffectivePermissions(logContext, account)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	// Create the JWT claims, which includes the user id, tenant, roles, permissions, token id and expiry time
+	claims := &repo.Token{
+		UserID:      account.ID,
+		TenantID:    account.TenantID,
+		Role:        account.Role,
+		Roles:       roles,
+		Permissions: permissions,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+	}
+	return claims, nil
+}
+
+// issueRefreshToken Creates a new opaque refresh token and stores its hash
+func (s *Services) issueRefreshToken(logContext *u.LoggerContext, tx repo.Transaction, userID int, family string) (string, error) {
+	logger := zerolog.Ctx(*logContext)
+	tokenString, err := randomToken()
+	if err != nil {
+		logger.Error().Err(err).Msgf("[issueRefreshToken] Error generating refresh token: %s", err)
+		return "", err
+	}
+	if family == "" {
+		if family, err = randomToken(); err != nil {
+			logger.Error().Err(err).Msgf("[issueRefreshToken] Error generating token family: %s", err)
+			return "", err
+		}
+	}
+	now := time.Now()
+	refresh := &repo.RefreshToken{
+		UserID:     userID,
+		Family:     family,
+		TokenHash:  hashToken(tokenString),
+		Status:     repo.RefreshTokenActive,
+		Tstampinit: now.Unix(),
+		Tstampexp:  now.Add(refreshTokenDuration).Unix(),
+	}
+	if err := s.tokens.InsertRefreshToken(logContext, tx, refresh); err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// randomToken Generates a random url safe string
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken Returns the hex encoded SHA-256 of a token, the only form stored on database
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,56 +3,105 @@ package services
 import (
 	"database/sql"
 	"errors"
-	"github.com/elnerribeiro/go-ws-db-auth-v2/db"
+	"strconv"
+
 	repo "github.com/elnerribeiro/go-ws-db-auth-v2/repositories"
 	u "github.com/elnerribeiro/go-ws-db-auth-v2/utils"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/rs/zerolog"
-	"time"
 )
 
-// ListUsers Lists all users
-func ListUsers(logContext *u.LoggerContext, user *repo.User) ([]repo.User, error) {
-	return user.ListUsers(logContext)
+// ListUsers Lists all users of the tenant of user
+func (s *Services) ListUsers(logContext *u.LoggerContext, user *repo.User) ([]repo.User, error) {
+	return s.users.ListUsers(logContext, user)
 }
 
-// Login Authenticates an user
-func Login(logContext *u.LoggerContext, user *repo.User, password string) map[string]interface{} {
+// Login Authenticates an user, counting failures for the email and the client ip
+func (s *Services) Login(logContext *u.LoggerContext, user *repo.User, password string, ip string) map[string]interface{} {
 	logger := zerolog.Ctx(*logContext)
-	account, err := user.GetUserByEmail(logContext, true)
+	account, err := s.users.GetUserByEmail(logContext, user, true)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			logger.Error().Err(err).Msgf("[Login] Email not found.")
+			s.registerLoginFailure(logContext, user.Email, ip)
 			return u.Message(false, "Email address not found")
 		} else {
 			logger.Error().Err(err).Msgf("[Login] Connection error. Please retry: %s", err)
 			return u.Message(false, "Connection error. Please retry")
 		}
 	}
-	if account.Password != password { //Password does not match!
-		logger.Error().Msgf("[Login] Invalid login credentials. Please try again: %s != %s", account.Password, password)
+	match, needsRehash, err := u.VerifyPassword(account.Password, password)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Login] Cannot verify password of user %d: %s", account.ID, err)
+	}
+	if !match { //Password does not match!
+		logger.Error().Msgf("[Login] Invalid login credentials for user %d", account.ID)
+		s.registerLoginFailure(logContext, user.Email, ip)
 		return u.Message(false, "Invalid login credentials. Please try again")
 	}
-	//Worked! Logged In
+	//Password is right
 	account.Password = ""
+	if resp := s.inactiveAccount(logContext, account); resp != nil {
+		return resp
+	}
 
-	//Create JWT token
-	expirationTime := time.Now().Add(12 * time.Hour)
-	numericDate := jwt.NumericDate{expirationTime}
-	// Create the JWT claims, which includes the username and expiry time
-	claims := &repo.Token{
-		UserID: account.ID,
-		Role:   account.Role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			// In JWT, the expiry time is expressed as unix milliseconds
-			ExpiresAt: &numericDate,
-		},
+	tx, err := s.transactor.Begin(logContext)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Login] Error starting transaction: %s", err)
+		return u.Message(false, "Connection error. Please retry")
+	}
+	defer tx.Rollback(logContext)
+	if needsRehash { //Legacy or outdated hash, upgrade it now that we know the password
+		if err := s.users.UpdatePassword(logContext, tx, account, password); err != nil {
+			return u.Message(false, "Connection error. Please retry")
+		}
+		logger.Info().Msgf("[Login] Password hash upgraded for user %d", account.ID)
+	}
+
+	totp, err := s.mfa.GetUserTotp(logContext, tx, &repo.UserTotp{UserID: account.ID})
+	if err != nil {
+		return u.Message(false, "Connection error. Please retry")
+	}
+	if totp != nil && totp.Status == repo.TotpActive { //Second step needed, see LoginMfa
+		challenge, err := s.generateMfaChallenge(account)
+		if err != nil {
+			logger.Error().Err(err).Msgf("[Login] Error signing MFA challenge: %s", err)
+			return u.Message(false, "Connection error. Please retry")
+		}
+		if err := tx.Commit(logContext); err != nil {
+			return u.Message(false, "Connection error. Please retry")
+		}
+		logger.Info().Msgf("[Login] MFA required for user: %d", account.ID)
+		resp := u.Message(true, "MFA required")
+		resp["mfa_required"] = true
+		resp["mfa_token"] = challenge
+		return resp
 	}
 
-	// Declare the token with the algorithm used for signing, and the claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, _ := token.SignedString([]byte("JWTpassword123@"))
+	//Worked! Logged In
+	s.registerLoginSuccess(logContext, user.Email)
+	return s.finishLogin(logContext, tx, account)
+}
+
+// finishLogin Issues the access and refresh tokens of an authenticated account and commits the transaction
+func (s *Services) finishLogin(logContext *u.LoggerContext, tx repo.Transaction, account *repo.User) map[string]interface{} {
+	logger := zerolog.Ctx(*logContext)
+	if resp := s.inactiveAccount(logContext, account); resp != nil { //Disabled after the MFA challenge was issued
+		return resp
+	}
+	refreshToken, err := s.issueRefreshToken(logContext, tx, account.ID, "")
+	if err != nil {
+		return u.Message(false, "Connection error. Please retry")
+	}
+	tokenString, err := s.generateAccessToken(logContext, account)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[finishLogin] Error signing token: %s", err)
+		return u.Message(false, "Connection error. Please retry")
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return u.Message(false, "Connection error. Please retry")
+	}
 	account.Token = tokenString //Store the token in the response
+	account.RefreshToken = refreshToken
 
 	logger.Info().Msgf("[Login] User Logged In: %d", account.ID)
 
@@ -61,10 +110,24 @@ func Login(logContext *u.LoggerContext, user *repo.User, password string) map[st
 	return resp
 }
 
+// inactiveAccount Returns the login error of pending and disabled accounts, nil for active ones
+func (s *Services) inactiveAccount(logContext *u.LoggerContext, account *repo.User) map[string]interface{} {
+	logger := zerolog.Ctx(*logContext)
+	switch account.Status {
+	case repo.UserPending:
+		logger.Error().Msgf("[Login] User %d not activated", account.ID)
+		return u.Message(false, "Account not activated. Check your email")
+	case repo.UserDisabled:
+		logger.Error().Msgf("[Login] User %d disabled", account.ID)
+		return u.Message(false, "Account disabled")
+	}
+	return nil
+}
+
 // GetUserByID Gets an user by ID
-func GetUserByID(logContext *u.LoggerContext, user *repo.User) (*repo.User, error) {
+func (s *Services) GetUserByID(logContext *u.LoggerContext, user *repo.User) (*repo.User, error) {
 	logger := zerolog.Ctx(*logContext)
-	val, err := user.GetUserByID(logContext)
+	val, err := s.users.GetUserByID(logContext, user)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[GetUserByID] Error : %s", err)
 		return nil, err
@@ -72,37 +135,103 @@ func GetUserByID(logContext *u.LoggerContext, user *repo.User) (*repo.User, erro
 	return val, nil
 }
 
-// Upsert Inserts or updates an user
-func Upsert(logContext *u.LoggerContext, user *repo.User) (*repo.User, error) {
+// Upsert Inserts or updates an user of the tenant of user, recording the change on the audit log
+func (s *Services) Upsert(logContext *u.LoggerContext, caller *repo.AuditCaller, user *repo.User) (*repo.User, error) {
 	logger := zerolog.Ctx(*logContext)
-	tx, txContext, err := db.GetTransaction()
+	tx, err := s.transactor.BeginTenant(logContext, user.TenantID)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[Upsert] Error starting transaction: %s", err)
 		return nil, err
 	}
-	defer db.Rollback(logContext, txContext, tx)
-	val, err := user.Upsert(logContext, txContext, tx)
+	defer tx.Rollback(logContext)
+	var revoked *repo.RevokedUser
+	var current *repo.User
+	if user.ID != 0 {
+		if current, err = s.users.GetUserForUpdate(logContext, tx, user); err != nil { //The row being changed
+			logger.Error().Err(err).Msgf("[Upsert] Error retrieving user: %s", err)
+			return nil, err
+		}
+		roleChanged := user.Role != "" && current.Role != user.Role
+		deactivated := user.Status != "" && user.Status != repo.UserActive && current.Status == repo.UserActive
+		if roleChanged || deactivated { //Tokens issued with the old role or to a now disabled user must stop working
+			if revoked, err = s.revokeUserTokens(logContext, tx, user.ID); err != nil {
+				logger.Error().Err(err).Msgf("[Upsert] Error revoking user tokens: %s", err)
+				return nil, err
+			}
+		}
+	}
+	val, err := s.users.Upsert(logContext, tx, user)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[Upsert] Error executing upsert: %s", err)
 		return nil, err
 	}
-	db.Commit(logContext, txContext, tx)
+	if current == nil {
+		err = s.audit(logContext, tx, caller, "user.create", "user:"+strconv.Itoa(val.ID), nil, val)
+	} else {
+		err = s.audit(logContext, tx, caller, "user.update", "user:"+strconv.Itoa(val.ID), current, mergeUser(*current, val))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return nil, err
+	}
+	if revoked != nil {
+		s.commitUserRevocation(revoked)
+	}
 	return val, nil
 }
 
-// Delete Deletes an user
-func Delete(logContext *u.LoggerContext, user *repo.User) error {
+// Delete Deletes an user of the tenant of user, recording it on the audit log
+func (s *Services) Delete(logContext *u.LoggerContext, caller *repo.AuditCaller, user *repo.User) error {
 	logger := zerolog.Ctx(*logContext)
-	tx, txContext, err := db.GetTransaction()
+	tx, err := s.transactor.BeginTenant(logContext, user.TenantID)
 	if err != nil {
 		logger.Error().Err(err).Msgf("[Delete] Error starting transaction: %s", err)
 		return err
 	}
-	defer db.Rollback(logContext, txContext, tx)
-	if err2 := user.Delete(logContext, txContext, tx); err2 != nil {
-		logger.Error().Err(err2).Msgf("[Delete] Error executing delete: %s", err)
+	defer tx.Rollback(logContext)
+	current, err := s.users.GetUserForUpdate(logContext, tx, user)
+	if errors.Is(err, sql.ErrNoRows) { //Nothing to delete, and the tokens of users of other tenants are not ours to revoke
+		return nil
+	}
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Delete] Error retrieving user: %s", err)
+		return err
+	}
+	revoked, err := s.revokeUserTokens(logContext, tx, user.ID)
+	if err != nil {
+		logger.Error().Err(err).Msgf("[Delete] Error revoking user tokens: %s", err)
+		return err
+	}
+	if err2 := s.users.Delete(logContext, tx, user); err2 != nil {
+		logger.Error().Err(err2).Msgf("[Delete] Error executing delete: %s", err2)
 		return err2
 	}
-	db.Commit(logContext, txContext, tx)
+	if err := s.audit(logContext, tx, caller, "user.delete", "user:"+strconv.Itoa(user.ID), current, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(logContext); err != nil {
+		return err
+	}
+	s.commitUserRevocation(revoked)
 	return nil
 }
+
+// mergeUser Applies the fields sent on an update to the current user, the result of the update
+func mergeUser(current repo.User, changes *repo.User) *repo.User {
+	if changes.Email != "" {
+		current.Email = changes.Email
+	}
+	if changes.Name != "" {
+		current.Name = changes.Name
+	}
+	if changes.Role != "" {
+		current.Role = changes.Role
+	}
+	if changes.Status != "" {
+		current.Status = changes.Status
+	}
+	current.Password = ""
+	return &current
+}
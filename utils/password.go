@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argon2id parameters used on new hashes, older hashes with other parameters are rehashed on login
+const (
+	argonMemory  = 64 * 1024
+	argonTime    = 3
+	argonThreads = 2
+	argonSaltLen = 16
+	argonKeyLen  = 32
+)
+
+// ErrUnknownHash the stored password is not in a known format
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// MinPasswordLength minimum length of new passwords
+const MinPasswordLength = 8
+
+// ErrPasswordTooShort the new password has less than MinPasswordLength characters
+var ErrPasswordTooShort = fmt.Errorf("password must have at least %d characters", MinPasswordLength)
+
+// ValidatePassword checks a new password before it is hashed
+func ValidatePassword(password string) error {
+	if len([]rune(password)) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+	return nil
+}
+
+// HashPassword hashes a password with argon2id, returning a PHC string:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword compares a password with a stored hash in constant time.
+// Accepts argon2id and bcrypt hashes, and the legacy uppercase SHA-512 hex computed by the client.
+// needsRehash is true when the password matches but the stored hash should be replaced by HashPassword.
+func VerifyPassword(stored string, password string) (match bool, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(stored, "$argon2id$"):
+		return verifyArgon2id(stored, password)
+	case strings.HasPrefix(stored, "$2a$"), strings.HasPrefix(stored, "$2b$"), strings.HasPrefix(stored, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(stored), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		return err == nil, true, err
+	case isLegacyHash(stored):
+		//Legacy rows store the SHA-512 the client sends, so the stored value works as the password itself
+		match := subtle.ConstantTimeCompare([]byte(stored), []byte(strings.ToUpper(password))) == 1
+		return match, true, nil
+	}
+	return false, false, ErrUnknownHash
+}
+
+func verifyArgon2id(stored string, password string) (bool, bool, error) {
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 {
+		return false, false, ErrUnknownHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, ErrUnknownHash
+	}
+	var memory uint32
+	var time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false, ErrUnknownHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrUnknownHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false, ErrUnknownHash
+	}
+	computed := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, computed) != 1 {
+		return false, false, nil
+	}
+	needsRehash := memory != argonMemory || time != argonTime || threads != argonThreads || len(key) != argonKeyLen
+	return true, needsRehash, nil
+}
+
+func isLegacyHash(stored string) bool {
+	if len(stored) != 128 {
+		return false
+	}
+	for _, c := range stored {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/rs/zerolog"
+	"net"
 	"net/http"
 	"os"
 )
@@ -29,10 +30,30 @@ func Respond(logContext *LoggerContext, w http.ResponseWriter, data map[string]i
 	}
 }
 
+// ClientIP returns the ip address of the caller, without the port
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func GetLoggerAndContext() (*zerolog.Logger, *LoggerContext) {
 	return logger, logContext
 }
 
+// GetLoggerContext Returns ctx carrying the logger, so services and queries using it are cancelled with ctx
+func GetLoggerContext(ctx context.Context) *LoggerContext {
+	loggerContext := logger.WithContext(ctx)
+	return (*LoggerContext)(&loggerContext)
+}
+
+// GetRequestContext Returns the context of the request carrying the logger: its queries stop when the client goes away
+func GetRequestContext(r *http.Request) *LoggerContext {
+	return GetLoggerContext(r.Context())
+}
+
 func init() {
 	initLogger := zerolog.New(os.Stdout).With().
 		Timestamp().